@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func CreateToken(svc *auth.Service) echo.HandlerFunc {
+	type request struct {
+		Name  string `json:"name" form:"name"`
+		Scope string `json:"scope" form:"scope"`
+	}
+	return func(c echo.Context) error {
+		req := request{}
+		if err := c.Bind(&req); err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
+		}
+		created, err := svc.CreateToken(c.Request().Context(), req.Name, req.Scope)
+		if err != nil {
+			return util.JSONError(c, errcode.Parse(err))
+		}
+		return util.JSONSuccess(c, created)
+	}
+}
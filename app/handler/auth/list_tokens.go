@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func ListTokens(svc *auth.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tokens, err := svc.ListTokens(c.Request().Context())
+		if err != nil {
+			return util.JSONError(c, errcode.Parse(err))
+		}
+		return util.JSONSuccess(c, map[string]any{"tokens": tokens, "scopes": auth.Scopes()})
+	}
+}
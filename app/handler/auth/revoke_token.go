@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func RevokeToken(svc *auth.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tokenID, err := strconv.ParseInt(c.Param("tokenId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "token_id"))
+		}
+		if err := svc.RevokeToken(c.Request().Context(), tokenID); err != nil {
+			return util.JSONError(c, errcode.Parse(err))
+		}
+		return util.JSONSuccess(c, map[string]any{"ok": true})
+	}
+}
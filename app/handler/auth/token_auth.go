@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// TokenContextKey holds the *entity.APIToken of an authenticated request.
+const TokenContextKey = "api_token"
+
+// TokenAuth checks the "Authorization: Bearer" token of /api requests against
+// the scope the route needs. Unless requireToken is set, requests carrying no
+// token are let through with the read scope, so the bundled web page can
+// browse before tokens are issued; anything more, token management and admin
+// routes included, needs a token. A token that is sent is always checked.
+func TokenAuth(svc *auth.Service, requireToken bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			required := RequiredScope(c.Request().Method, c.Path())
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			if header == "" {
+				if !requireToken && required == auth.ScopeRead {
+					return next(c)
+				}
+				return unauthorized(c, errcode.New(errcode.TokenMissing))
+			}
+			scheme, secret, _ := strings.Cut(header, " ")
+			if !strings.EqualFold(scheme, "Bearer") {
+				return unauthorized(c, errcode.New(errcode.TokenInvalid))
+			}
+			token, err := svc.Authenticate(c.Request().Context(), strings.TrimSpace(secret))
+			if err != nil {
+				parsed := errcode.Parse(err)
+				if parsed.Code.Status == http.StatusUnauthorized {
+					return unauthorized(c, parsed)
+				}
+				return util.JSONError(c, parsed)
+			}
+			if !auth.Allows(token.Scope, required) {
+				return util.JSONError(c, errcode.New(errcode.TokenScopeDenied, required))
+			}
+			c.Set(TokenContextKey, token)
+			return next(c)
+		}
+	}
+}
+
+// RequiredScope is the scope a request to the route path needs.
+func RequiredScope(method, path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/auth/"), strings.HasPrefix(path, "/api/recite/admin/"):
+		return auth.ScopeAdmin
+	case method == http.MethodGet || method == http.MethodHead:
+		return auth.ScopeRead
+	case path == "/api/recite/words/query", path == "/api/recite/words/query/batch":
+		// lookups; caching the fetched entries is a side effect
+		return auth.ScopeRead
+	case strings.HasPrefix(path, "/api/recite/quizzes/"):
+		return auth.ScopeQuizWrite
+	default:
+		return auth.ScopeWrite
+	}
+}
+
+func unauthorized(c echo.Context, err *errcode.Error) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="moss"`)
+	return util.JSONError(c, err)
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/wutianfang/moss/app/service/auth"
+)
+
+func TestRequiredScope(t *testing.T) {
+	cases := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/api/recite/units", auth.ScopeRead},
+		{http.MethodHead, "/api/recite/words/:wordId", auth.ScopeRead},
+		{http.MethodPost, "/api/recite/words/query", auth.ScopeRead},
+		{http.MethodPost, "/api/recite/words/query/batch", auth.ScopeRead},
+		{http.MethodPost, "/api/recite/quizzes/:quizId/words/:seq/submit", auth.ScopeQuizWrite},
+		{http.MethodPost, "/api/recite/units", auth.ScopeWrite},
+		{http.MethodPut, "/api/recite/words/:wordId", auth.ScopeWrite},
+		{http.MethodDelete, "/api/recite/notes/:noteId", auth.ScopeWrite},
+		{http.MethodGet, "/api/auth/tokens", auth.ScopeAdmin},
+		{http.MethodPost, "/api/auth/tokens", auth.ScopeAdmin},
+		{http.MethodGet, "/api/recite/admin/audio/check", auth.ScopeAdmin},
+		{http.MethodPost, "/api/recite/admin/audio/repair", auth.ScopeAdmin},
+		{http.MethodGet, "/api/recite/admin/audio/repair", auth.ScopeAdmin},
+	}
+	for _, tc := range cases {
+		if got := RequiredScope(tc.method, tc.path); got != tc.want {
+			t.Errorf("RequiredScope(%s %s) = %q, want %q", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	commonservice "github.com/wutianfang/moss/app/service/common"
+	"github.com/wutianfang/moss/infra/storage"
+)
+
+// readyTimeout bounds one readiness probe so a hanging MySQL fails it
+// instead of stalling the caller.
+const readyTimeout = 3 * time.Second
+
+// Ready answers 200 when MySQL and the audio store are usable and 503
+// otherwise. Unlike Health it touches the dependencies, so load balancers
+// should use it to decide whether to route traffic here.
+func Ready(db *sql.DB, audioStore storage.Store) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), readyTimeout)
+		defer cancel()
+		checks, ready := commonservice.CheckReady(ctx, db, audioStore)
+		if !ready {
+			return c.JSON(http.StatusServiceUnavailable, map[string]any{"status": "unavailable", "checks": checks})
+		}
+		return c.JSON(http.StatusOK, map[string]any{"status": "ok", "checks": checks})
+	}
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/infra/storage"
+	"github.com/wutianfang/moss/util"
+)
+
+// WordAudio serves /word_mp3/* from the audio store. When presignExpiry is
+// positive and the store can presign, clients are redirected to a temporary
+// direct URL instead of having the audio proxied.
+func WordAudio(store storage.Store, presignExpiry time.Duration) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		key, err = storage.CleanKey(key)
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		ctx := c.Request().Context()
+
+		if presigner, ok := store.(storage.Presigner); ok && presignExpiry > 0 {
+			target, err := presigner.PresignGet(ctx, key, presignExpiry)
+			if err != nil {
+				util.ErrorfWithRequest(ctx, "handler.common.word_audio.presign_failed", "key=%s err=%v", key, err)
+				return echo.NewHTTPError(http.StatusBadGateway)
+			}
+			return c.Redirect(http.StatusFound, target)
+		}
+
+		reader, info, err := store.Open(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return echo.ErrNotFound
+		}
+		if err != nil {
+			util.ErrorfWithRequest(ctx, "handler.common.word_audio.open_failed", "key=%s err=%v", key, err)
+			return echo.NewHTTPError(http.StatusBadGateway)
+		}
+		defer reader.Close()
+		c.Response().Header().Set(echo.HeaderContentType, "audio/mpeg")
+		http.ServeContent(c.Response(), c.Request(), path.Base(key), info.ModTime, reader)
+		return nil
+	}
+}
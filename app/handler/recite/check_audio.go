@@ -0,0 +1,17 @@
+package recite
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+)
+
+func CheckAudio(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		report, err := svc.CheckAudio(c.Request().Context(), false)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, audioReportData{Report: *report})
+	}
+}
@@ -4,24 +4,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func CreateUnit(svc *recite.Service) echo.HandlerFunc {
-	type request struct {
-		Name       string `json:"name" form:"name"`
-		ReciteDate string `json:"recite_date" form:"recite_date"`
-	}
-
 	return func(c echo.Context) error {
-		req := request{}
+		req := unitBody{}
 		if err := c.Bind(&req); err != nil {
-			return util.JSONError(c, 1001, "请求参数错误")
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
 		}
 		unit, err := svc.CreateUnit(c.Request().Context(), req.Name, req.ReciteDate)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"unit": unit})
+		return util.JSONSuccess(c, unitData{Unit: *unit})
 	}
 }
@@ -0,0 +1,24 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// DeleteNote moves a note to the trash.
+func DeleteNote(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		noteID, err := strconv.ParseInt(c.Param("noteId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "note_id"))
+		}
+		if err := svc.DeleteNote(c.Request().Context(), noteID); err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, okData{OK: true})
+	}
+}
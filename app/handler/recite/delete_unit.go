@@ -6,18 +6,18 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func DeleteUnit(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		unitID, err := strconv.ParseInt(c.Param("unitId"), 10, 64)
 		if err != nil {
-			return util.JSONError(c, 1001, "unit_id 非法")
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "unit_id"))
 		}
 		if err := svc.DeleteUnit(c.Request().Context(), unitID); err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"ok": true})
+		return util.JSONSuccess(c, okData{OK: true})
 	}
 }
@@ -6,19 +6,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func FinishQuiz(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		quizID, err := strconv.ParseInt(c.Param("quizId"), 10, 64)
 		if err != nil {
-			return util.JSONError(c, 1001, "quiz_id 非法")
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "quiz_id"))
 		}
 		detail, err := svc.FinishQuiz(c.Request().Context(), quizID)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"quiz": detail})
+		return util.JSONSuccess(c, quizData{Quiz: *detail})
 	}
 }
@@ -9,6 +9,6 @@ import (
 func GetClientConfig(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		cfg := svc.GetClientConfig()
-		return util.JSONSuccess(c, map[string]any{"config": cfg})
+		return util.JSONSuccess(c, configData{Config: cfg})
 	}
 }
@@ -6,22 +6,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func GetDictation(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		unitID, err := strconv.ParseInt(c.Param("unitId"), 10, 64)
 		if err != nil {
-			return util.JSONError(c, 1001, "unit_id 非法")
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "unit_id"))
 		}
 		items, err := svc.GetDictationWords(c.Request().Context(), unitID)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{
-			"words": items,
-			"total": len(items),
-		})
+		return util.JSONSuccess(c, wordListData{Words: items, Total: len(items)})
 	}
 }
@@ -6,19 +6,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func GetNote(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		noteID, err := strconv.ParseInt(c.Param("noteId"), 10, 64)
 		if err != nil {
-			return util.JSONError(c, 1001, "note_id 非法")
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "note_id"))
 		}
 		detail, err := svc.GetNoteDetail(c.Request().Context(), noteID)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"note": detail})
+		return util.JSONSuccess(c, noteData{Note: *detail})
 	}
 }
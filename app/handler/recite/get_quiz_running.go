@@ -10,9 +10,8 @@ func GetQuizRunning(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		hasRunning, err := svc.HasRunningQuiz(c.Request().Context())
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"has_running": hasRunning})
+		return util.JSONSuccess(c, quizRunningData{HasRunning: hasRunning})
 	}
 }
@@ -0,0 +1,45 @@
+package recite
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// dotMediaType is the media type of Graphviz DOT documents.
+const dotMediaType = "text/vnd.graphviz"
+
+func GetUnitGraph(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		unitID, err := strconv.ParseInt(c.Param("unitId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "unit_id"))
+		}
+		graph, err := svc.GetUnitGraph(c.Request().Context(), unitID)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, unitGraphData{Graph: *graph})
+	}
+}
+
+// GetUnitGraphDOT serves the unit graph as a Graphviz DOT file; errors are
+// still JSON.
+func GetUnitGraphDOT(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		unitID, err := strconv.ParseInt(c.Param("unitId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "unit_id"))
+		}
+		graph, err := svc.GetUnitGraph(c.Request().Context(), unitID)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		c.Response().Header().Set(echo.HeaderContentDisposition, `inline; filename="unit-`+strconv.FormatInt(unitID, 10)+`.dot"`)
+		return c.Blob(http.StatusOK, dotMediaType+"; charset=utf-8", []byte(graph.DOT()))
+	}
+}
@@ -0,0 +1,32 @@
+package recite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func GetWordGraph(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		wordID, err := strconv.ParseInt(c.Param("wordId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_id"))
+		}
+		depth := 0
+		if raw := strings.TrimSpace(c.QueryParam("depth")); raw != "" {
+			depth, err = strconv.Atoi(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "depth"))
+			}
+		}
+		graph, err := svc.GetWordGraph(c.Request().Context(), wordID, depth)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, wordGraphData{Graph: *graph})
+	}
+}
@@ -10,12 +10,8 @@ func ListForgottenWords(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		items, err := svc.ListForgottenWords(c.Request().Context())
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{
-			"words": items,
-			"total": len(items),
-		})
+		return util.JSONSuccess(c, wordListData{Words: items, Total: len(items)})
 	}
 }
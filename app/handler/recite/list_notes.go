@@ -7,6 +7,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func ListNotes(svc *recite.Service) echo.HandlerFunc {
@@ -15,7 +16,7 @@ func ListNotes(svc *recite.Service) echo.HandlerFunc {
 		if raw := strings.TrimSpace(c.QueryParam("page")); raw != "" {
 			parsed, err := strconv.Atoi(raw)
 			if err != nil {
-				return util.JSONError(c, 1001, "page 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page"))
 			}
 			page = parsed
 		}
@@ -23,20 +24,14 @@ func ListNotes(svc *recite.Service) echo.HandlerFunc {
 		if raw := strings.TrimSpace(c.QueryParam("page_size")); raw != "" {
 			parsed, err := strconv.Atoi(raw)
 			if err != nil {
-				return util.JSONError(c, 1001, "page_size 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page_size"))
 			}
 			pageSize = parsed
 		}
 		items, total, err := svc.ListNotes(c.Request().Context(), page, pageSize)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{
-			"items":     items,
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-		})
+		return util.JSONSuccess(c, noteListData{Items: items, Total: total, Page: page, PageSize: pageSize})
 	}
 }
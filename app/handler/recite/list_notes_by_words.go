@@ -7,13 +7,14 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func ListNotesByWords(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		raw := strings.TrimSpace(c.QueryParam("word_ids"))
 		if raw == "" {
-			return util.JSONSuccess(c, map[string]any{"word_notes": map[string]any{}})
+			return util.JSONSuccess(c, wordNotesData{WordNotes: map[int64][]recite.NoteTag{}})
 		}
 		parts := strings.Split(raw, ",")
 		wordIDs := make([]int64, 0, len(parts))
@@ -24,15 +25,14 @@ func ListNotesByWords(svc *recite.Service) echo.HandlerFunc {
 			}
 			id, err := strconv.ParseInt(text, 10, 64)
 			if err != nil {
-				return util.JSONError(c, 1001, "word_ids 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_ids"))
 			}
 			wordIDs = append(wordIDs, id)
 		}
 		rows, err := svc.ListNotesByWordIDs(c.Request().Context(), wordIDs)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"word_notes": rows})
+		return util.JSONSuccess(c, wordNotesData{WordNotes: rows})
 	}
 }
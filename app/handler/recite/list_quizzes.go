@@ -7,6 +7,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func ListQuizzes(svc *recite.Service) echo.HandlerFunc {
@@ -15,7 +16,7 @@ func ListQuizzes(svc *recite.Service) echo.HandlerFunc {
 		if raw := strings.TrimSpace(c.QueryParam("page")); raw != "" {
 			parsed, err := strconv.Atoi(raw)
 			if err != nil {
-				return util.JSONError(c, 1001, "page 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page"))
 			}
 			page = parsed
 		}
@@ -23,21 +24,20 @@ func ListQuizzes(svc *recite.Service) echo.HandlerFunc {
 		if raw := strings.TrimSpace(c.QueryParam("page_size")); raw != "" {
 			parsed, err := strconv.Atoi(raw)
 			if err != nil {
-				return util.JSONError(c, 1001, "page_size 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page_size"))
 			}
 			pageSize = parsed
 		}
 		items, total, hasRunning, err := svc.ListQuizzes(c.Request().Context(), page, pageSize)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{
-			"items":       items,
-			"total":       total,
-			"page":        page,
-			"page_size":   pageSize,
-			"has_running": hasRunning,
+		return util.JSONSuccess(c, quizListData{
+			Items:      items,
+			Total:      total,
+			Page:       page,
+			PageSize:   pageSize,
+			HasRunning: hasRunning,
 		})
 	}
 }
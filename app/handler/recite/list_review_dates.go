@@ -6,6 +6,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func ListReviewDates(svc *recite.Service) echo.HandlerFunc {
@@ -14,11 +15,11 @@ func ListReviewDates(svc *recite.Service) echo.HandlerFunc {
 		if raw := c.QueryParam("recent_days"); raw != "" {
 			v, err := strconv.Atoi(raw)
 			if err != nil {
-				return util.JSONError(c, 1001, "recent_days 非法")
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "recent_days"))
 			}
 			recentDays = v
 		}
 		dates := svc.ListReviewDateOptions(recentDays)
-		return util.JSONSuccess(c, map[string]any{"dates": dates})
+		return util.JSONSuccess(c, reviewDatesData{Dates: dates})
 	}
 }
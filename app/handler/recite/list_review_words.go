@@ -10,12 +10,8 @@ func ListReviewWords(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		items, units, err := svc.ListReviewWordsByDate(c.Request().Context(), c.QueryParam("date"))
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{
-			"words": items,
-			"units": units,
-		})
+		return util.JSONSuccess(c, reviewWordsData{Words: items, Units: units})
 	}
 }
@@ -0,0 +1,37 @@
+package recite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func ListTrashedNotes(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		page := 1
+		if raw := strings.TrimSpace(c.QueryParam("page")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page"))
+			}
+			page = parsed
+		}
+		pageSize := 20
+		if raw := strings.TrimSpace(c.QueryParam("page_size")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "page_size"))
+			}
+			pageSize = parsed
+		}
+		items, total, err := svc.ListTrashedNotes(c.Request().Context(), page, pageSize)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, noteListData{Items: items, Total: total, Page: page, PageSize: pageSize})
+	}
+}
@@ -10,9 +10,8 @@ func ListUnits(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		rows, err := svc.ListUnits(c.Request().Context())
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"units": rows})
+		return util.JSONSuccess(c, unitsData{Units: rows})
 	}
 }
@@ -0,0 +1,211 @@
+package recite
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util/openapi"
+)
+
+// APIPrefix is where the recite routes are mounted.
+const APIPrefix = "/api/recite"
+
+// apiRoute documents one recite route. body and data are zero values of the
+// types the handler binds and answers with, see types.go. Routes answering
+// with a document other than JSON set its mediaType instead of data.
+type apiRoute struct {
+	method    string
+	path      string
+	summary   string
+	query     []apiQuery
+	body      any
+	data      any
+	mediaType string
+}
+
+type apiQuery struct {
+	name        string
+	description string
+	schema      *openapi.Schema
+}
+
+var (
+	stringParam  = &openapi.Schema{Type: "string"}
+	integerParam = &openapi.Schema{Type: "integer"}
+	boolParam    = &openapi.Schema{Type: "boolean"}
+	dateQuery    = apiQuery{name: "date", description: "yyyy-mm-dd, default today", schema: stringParam}
+	pageQueries  = []apiQuery{
+		{name: "page", description: "1 based, default 1", schema: integerParam},
+		{name: "page_size", description: "default 20", schema: integerParam},
+	}
+)
+
+// apiRoutes lists every route registered under APIPrefix. A route missing
+// here fails TestOpenAPICoversRoutes.
+var apiRoutes = []apiRoute{
+	{method: http.MethodGet, path: "/config", summary: "Client settings", data: configData{}},
+	{method: http.MethodGet, path: "/units", summary: "List units", data: unitsData{}},
+	{method: http.MethodPost, path: "/units", summary: "Create a unit", body: unitBody{}, data: unitData{}},
+	{method: http.MethodPut, path: "/units/:unitId/name", summary: "Rename a unit and set its recite date", body: unitBody{}, data: okData{}},
+	{method: http.MethodDelete, path: "/units/:unitId", summary: "Delete a unit", data: okData{}},
+	{method: http.MethodPut, path: "/units/order", summary: "Reorder all units", body: unitOrderBody{}, data: okData{}},
+	{method: http.MethodPost, path: "/words/query", summary: "Look a word up, fetching it from the dictionary when not cached",
+		body: wordQueryBody{}, data: wordData{}},
+	{method: http.MethodPost, path: "/words/query/batch", body: wordBatchBody{}, data: wordBatchData{},
+		summary: "Look up to 300 words at once; each item reports cached, fetched, invalid or failed"},
+	{method: http.MethodGet, path: "/words/search", summary: "Search cached words by prefix, substring or Chinese meaning",
+		query: []apiQuery{
+			{name: "q", description: "search text", schema: stringParam},
+			{name: "mode", description: "prefix, substring or meaning; default meaning for Chinese text, prefix otherwise", schema: stringParam},
+			{name: "in_unit", description: "only words in some unit", schema: boolParam},
+			{name: "in_forgotten", description: "only words in the forgotten list", schema: boolParam},
+			{name: "has_notes", description: "only words linked to a note", schema: boolParam},
+			{name: "limit", description: "default 20, at most 100", schema: integerParam},
+		},
+		data: wordSearchData{}},
+	{method: http.MethodGet, path: "/words/:wordId", summary: "Get a word", data: wordData{}},
+	{method: http.MethodPut, path: "/words/:wordId", summary: "Edit a word", body: recite.UpdateWordRequest{}, data: wordData{}},
+	{method: http.MethodPost, path: "/words/:wordId/revert", summary: "Revert a word to the fetched entry", data: wordData{}},
+	{method: http.MethodGet, path: "/words/:wordId/graph", summary: "Neighbours of a word through notes, grouped by note type",
+		query: []apiQuery{{name: "depth", description: "steps to walk, 1 to 3, default 1", schema: integerParam}},
+		data:  wordGraphData{}},
+	{method: http.MethodPost, path: "/words/:wordId/refresh", summary: "Fetch a word again from the dictionary", data: wordRefreshData{}},
+	{method: http.MethodPost, path: "/units/:unitId/words", summary: "Add a word to a unit", body: wordQueryBody{}, data: okData{}},
+	{method: http.MethodGet, path: "/units/:unitId/words", summary: "List the words of a unit", data: unitWordsData{}},
+	{method: http.MethodGet, path: "/units/:unitId/dictation", summary: "Dictation words of a unit", data: wordListData{}},
+	{method: http.MethodGet, path: "/units/:unitId/graph", summary: "Graph of the unit words and their note neighbours", data: unitGraphData{}},
+	{method: http.MethodGet, path: "/units/:unitId/graph.dot", summary: "Graph of the unit words as Graphviz DOT", mediaType: dotMediaType},
+	{method: http.MethodGet, path: "/review/dates", summary: "Recent dates to review",
+		query: []apiQuery{{name: "recent_days", description: "default 7", schema: integerParam}},
+		data:  reviewDatesData{}},
+	{method: http.MethodGet, path: "/review/words", summary: "Words due for review on a date", query: []apiQuery{dateQuery}, data: reviewWordsData{}},
+	{method: http.MethodGet, path: "/review/dictation", summary: "Dictation words due for review on a date",
+		query: []apiQuery{dateQuery}, data: wordListData{}},
+	{method: http.MethodPost, path: "/forgotten/words", summary: "Add a word to the forgotten list", body: wordBody{}, data: okData{}},
+	{method: http.MethodGet, path: "/forgotten/words", summary: "List forgotten words", data: wordListData{}},
+	{method: http.MethodPost, path: "/forgotten/words/remember", summary: "Remove a word from the forgotten list", body: wordBody{}, data: okData{}},
+	{method: http.MethodGet, path: "/forgotten/dictation", summary: "Dictation words of the forgotten list", data: wordListData{}},
+	{method: http.MethodPost, path: "/quizzes/start", summary: "Start a quiz", body: recite.StartQuizRequest{}, data: quizData{}},
+	{method: http.MethodGet, path: "/quizzes", summary: "List quizzes", query: pageQueries, data: quizListData{}},
+	{method: http.MethodGet, path: "/quizzes/running", summary: "Whether a quiz is running", data: quizRunningData{}},
+	{method: http.MethodGet, path: "/quizzes/:quizId", summary: "Get a quiz", data: quizData{}},
+	{method: http.MethodPost, path: "/quizzes/:quizId/words/:seq/submit", summary: "Submit the answer of one quiz word",
+		body: quizAnswerBody{}, data: quizAnswerData{}},
+	{method: http.MethodPost, path: "/quizzes/:quizId/finish", summary: "Finish a quiz", data: quizData{}},
+	{method: http.MethodPost, path: "/notes", summary: "Create a note", body: noteBody{}, data: noteData{}},
+	{method: http.MethodPut, path: "/notes/:noteId", summary: "Update a note", body: noteBody{}, data: noteData{}},
+	{method: http.MethodGet, path: "/notes", summary: "List notes", query: pageQueries, data: noteListData{}},
+	{method: http.MethodGet, path: "/notes/by-words", summary: "Notes linked to words, keyed by word id",
+		query: []apiQuery{{name: "word_ids", description: "comma separated word ids", schema: stringParam}},
+		data:  wordNotesData{}},
+	{method: http.MethodGet, path: "/notes/search", summary: "Search notes with highlighted snippets",
+		query: append([]apiQuery{
+			{name: "q", description: "space separated terms, all must occur", schema: stringParam},
+			{name: "note_type", description: "only notes of this type", schema: stringParam},
+			{name: "word_id", description: "only notes linked to this word", schema: integerParam},
+			{name: "sort", description: "relevance or updated; default relevance with q, updated without", schema: stringParam},
+		}, pageQueries...),
+		data: noteSearchData{}},
+	{method: http.MethodGet, path: "/notes/trash", summary: "List trashed notes", query: pageQueries, data: noteListData{}},
+	{method: http.MethodDelete, path: "/notes/trash/:noteId", summary: "Delete a trashed note for good", data: okData{}},
+	{method: http.MethodGet, path: "/notes/:noteId", summary: "Get a note", data: noteData{}},
+	{method: http.MethodDelete, path: "/notes/:noteId", summary: "Move a note to the trash", data: okData{}},
+	{method: http.MethodPost, path: "/notes/:noteId/restore", summary: "Restore a note from the trash", data: noteData{}},
+	{method: http.MethodDelete, path: "/notes/:noteId/words/:wordId", summary: "Unlink a word from a note", data: noteData{}},
+	{method: http.MethodGet, path: "/admin/audio/check", summary: "Report broken and orphan audio files", data: audioReportData{}},
+	{method: http.MethodPost, path: "/admin/audio/repair", summary: "Start repairing broken audio files and removing orphans in the background", data: audioRepairData{}},
+	{method: http.MethodGet, path: "/admin/audio/repair", summary: "Report the state of the current or last audio repair", data: audioRepairData{}},
+}
+
+// apiError is the body of every failed request.
+type apiError = struct {
+	Errno int    `json:"errno"`
+	Key   string `json:"key"`
+	Error string `json:"error"`
+}
+
+// OpenAPIDocument builds the OpenAPI document of the recite routes.
+func OpenAPIDocument() *openapi.Document {
+	doc := openapi.New("moss recite API", "1.0.0")
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearer": {Type: "http", Scheme: "bearer", Description: "API token; required for writes, and for reads too when auth.require_token is set"},
+	}
+	doc.Security = []openapi.SecurityRequirement{{}, {"bearer": {}}}
+	errorResponse := &openapi.Response{
+		Description: "error; the HTTP status and key tell the kind",
+		Content:     openapi.JSON(doc.SchemaOf(apiError{})),
+	}
+	for _, route := range apiRoutes {
+		path := APIPrefix + route.path
+		success := &openapi.Response{Description: "success"}
+		if route.mediaType != "" {
+			success.Content = map[string]*openapi.MediaType{route.mediaType: {Schema: &openapi.Schema{Type: "string"}}}
+		} else {
+			success.Content = openapi.JSON(&openapi.Schema{
+				Type:     "object",
+				Required: []string{"errno", "data"},
+				Properties: map[string]*openapi.Schema{
+					"errno": {Type: "integer", Enum: []any{0}},
+					"data":  doc.SchemaOf(route.data),
+				},
+			})
+		}
+		op := &openapi.Operation{
+			OperationID: operationID(route.method, route.path),
+			Summary:     route.summary,
+			Tags:        []string{operationTag(route.path)},
+			Responses: map[string]*openapi.Response{
+				"200":     success,
+				"default": errorResponse,
+			},
+		}
+		for _, part := range strings.Split(route.path, "/") {
+			if name, ok := strings.CutPrefix(part, ":"); ok {
+				op.Parameters = append(op.Parameters, &openapi.Parameter{Name: name, In: "path", Required: true, Schema: integerParam})
+			}
+		}
+		for _, query := range route.query {
+			op.Parameters = append(op.Parameters, &openapi.Parameter{Name: query.name, In: "query", Description: query.description, Schema: query.schema})
+		}
+		if route.body != nil {
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: openapi.JSON(doc.SchemaOf(route.body))}
+		}
+		doc.Add(route.method, openapi.EchoPath(path), op)
+	}
+	return doc
+}
+
+// OpenAPI serves the OpenAPI document of the recite routes.
+func OpenAPI() echo.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	return func(c echo.Context) error {
+		once.Do(func() {
+			doc = OpenAPIDocument()
+		})
+		return c.JSON(http.StatusOK, doc)
+	}
+}
+
+// operationID names an operation after its method and path:
+// "GET /units/:unitId/words" is "getUnitsUnitIdWords".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func operationTag(path string) string {
+	tag, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return tag
+}
@@ -0,0 +1,24 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// PurgeNote deletes a trashed note for good.
+func PurgeNote(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		noteID, err := strconv.ParseInt(c.Param("noteId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "note_id"))
+		}
+		if err := svc.PurgeNote(c.Request().Context(), noteID); err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, okData{OK: true})
+	}
+}
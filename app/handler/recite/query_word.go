@@ -4,23 +4,23 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func QueryWord(svc *recite.Service) echo.HandlerFunc {
-	type request struct {
-		Word string `json:"word" form:"word" query:"word"`
-	}
-
 	return func(c echo.Context) error {
-		req := request{}
+		req := wordQueryBody{}
 		if err := c.Bind(&req); err != nil {
-			return util.JSONError(c, 1001, "请求参数错误")
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
+		}
+		query := svc.QueryWord
+		if req.UseLemma {
+			query = svc.QueryWordLemma
 		}
-		wordInfo, err := svc.QueryWord(c.Request().Context(), req.Word)
+		wordInfo, err := query(c.Request().Context(), req.Word)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"word": wordInfo})
+		return util.JSONSuccess(c, wordData{Word: *wordInfo})
 	}
 }
@@ -0,0 +1,34 @@
+package recite
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func QueryWords(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := wordBatchBody{}
+		if err := c.Bind(&req); err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
+		}
+		items, err := svc.QueryWords(c.Request().Context(), req.Words, req.UseLemma)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		lang := errcode.Lang(c.Request().Header.Get("Accept-Language"))
+		for i := range items {
+			if items[i].Err == nil {
+				continue
+			}
+			itemErr := recite.ParseError(items[i].Err)
+			if itemErr.Code.Status >= 500 {
+				util.ErrorfWithRequest(c.Request().Context(), "api.query_words.item_failed", "word=%q err=%v", items[i].Query, itemErr)
+			}
+			items[i].Key = itemErr.Code.Key
+			items[i].Error = itemErr.Message(lang)
+		}
+		return util.JSONSuccess(c, wordBatchData{Items: items})
+	}
+}
@@ -0,0 +1,24 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func RefreshWord(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		wordID, err := strconv.ParseInt(c.Param("wordId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_id"))
+		}
+		wordInfo, result, err := svc.RefreshWord(c.Request().Context(), wordID)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, wordRefreshData{Word: *wordInfo, Refresh: *result})
+	}
+}
@@ -6,27 +6,22 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func RenameUnit(svc *recite.Service) echo.HandlerFunc {
-	type request struct {
-		Name       string `json:"name" form:"name"`
-		ReciteDate string `json:"recite_date" form:"recite_date"`
-	}
-
 	return func(c echo.Context) error {
 		unitID, err := strconv.ParseInt(c.Param("unitId"), 10, 64)
 		if err != nil {
-			return util.JSONError(c, 1001, "unit_id 非法")
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "unit_id"))
 		}
-		req := request{}
+		req := unitBody{}
 		if err := c.Bind(&req); err != nil {
-			return util.JSONError(c, 1001, "请求参数错误")
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
 		}
 		if err := svc.RenameUnit(c.Request().Context(), unitID, req.Name, req.ReciteDate); err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"ok": true})
+		return util.JSONSuccess(c, okData{OK: true})
 	}
 }
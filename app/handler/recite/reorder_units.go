@@ -4,22 +4,18 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func ReorderUnits(svc *recite.Service) echo.HandlerFunc {
-	type request struct {
-		UnitIDs []int64 `json:"unit_ids" form:"unit_ids"`
-	}
-
 	return func(c echo.Context) error {
-		req := request{}
+		req := unitOrderBody{}
 		if err := c.Bind(&req); err != nil {
-			return util.JSONError(c, 1001, "请求参数错误")
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
 		}
 		if err := svc.ReorderUnits(c.Request().Context(), req.UnitIDs); err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"ok": true})
+		return util.JSONSuccess(c, okData{OK: true})
 	}
 }
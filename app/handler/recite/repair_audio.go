@@ -0,0 +1,25 @@
+package recite
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+)
+
+// RepairAudio starts the audio repair in the background; poll
+// AudioRepairStatus for the report.
+func RepairAudio(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		job, err := svc.StartAudioRepair(c.Request().Context())
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, audioRepairData{Job: job})
+	}
+}
+
+func AudioRepairStatus(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return util.JSONSuccess(c, audioRepairData{Job: svc.AudioRepairStatus()})
+	}
+}
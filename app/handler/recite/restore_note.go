@@ -0,0 +1,24 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func RestoreNote(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		noteID, err := strconv.ParseInt(c.Param("noteId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "note_id"))
+		}
+		detail, err := svc.RestoreNote(c.Request().Context(), noteID)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, noteData{Note: *detail})
+	}
+}
@@ -0,0 +1,53 @@
+package recite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func SearchNotes(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := recite.NoteSearchRequest{
+			Query:    c.QueryParam("q"),
+			NoteType: c.QueryParam("note_type"),
+			Sort:     c.QueryParam("sort"),
+			Page:     1,
+			PageSize: 20,
+		}
+		ints := []struct {
+			name  string
+			value *int
+		}{
+			{"page", &req.Page},
+			{"page_size", &req.PageSize},
+		}
+		for _, param := range ints {
+			raw := strings.TrimSpace(c.QueryParam(param.name))
+			if raw == "" {
+				continue
+			}
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, param.name))
+			}
+			*param.value = parsed
+		}
+		if raw := strings.TrimSpace(c.QueryParam("word_id")); raw != "" {
+			wordID, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_id"))
+			}
+			req.WordID = wordID
+		}
+		items, total, err := svc.SearchNotes(c.Request().Context(), req)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, noteSearchData{Items: items, Total: total, Page: req.Page, PageSize: req.PageSize})
+	}
+}
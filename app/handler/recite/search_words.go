@@ -0,0 +1,51 @@
+package recite
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func SearchWords(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := recite.WordSearchRequest{
+			Query: c.QueryParam("q"),
+			Mode:  c.QueryParam("mode"),
+		}
+		flags := []struct {
+			name  string
+			value *bool
+		}{
+			{"in_unit", &req.InUnit},
+			{"in_forgotten", &req.InForgotten},
+			{"has_notes", &req.HasNotes},
+		}
+		for _, flag := range flags {
+			raw := strings.TrimSpace(c.QueryParam(flag.name))
+			if raw == "" {
+				continue
+			}
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, flag.name))
+			}
+			*flag.value = parsed
+		}
+		if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				return util.JSONError(c, errcode.New(errcode.InvalidParam, "limit"))
+			}
+			req.Limit = parsed
+		}
+		words, err := svc.SearchWords(c.Request().Context(), req)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, wordSearchData{Words: words})
+	}
+}
@@ -4,19 +4,19 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
 )
 
 func StartQuiz(svc *recite.Service) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		req := recite.StartQuizRequest{}
 		if err := c.Bind(&req); err != nil {
-			return util.JSONError(c, 1001, "请求参数错误")
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
 		}
 		detail, err := svc.StartQuiz(c.Request().Context(), req)
 		if err != nil {
-			code, msg := recite.ParseError(err)
-			return util.JSONError(c, code, msg)
+			return util.JSONError(c, recite.ParseError(err))
 		}
-		return util.JSONSuccess(c, map[string]any{"quiz": detail})
+		return util.JSONSuccess(c, quizData{Quiz: *detail})
 	}
 }
@@ -0,0 +1,152 @@
+package recite
+
+import "github.com/wutianfang/moss/app/service/recite"
+
+// The request bodies the handlers bind and the "data" they answer with. The
+// OpenAPI document is built from the same types, so it cannot drift from what
+// the handlers do. They are aliases of anonymous structs so the document
+// inlines them instead of adding components.
+
+type wordBody = struct {
+	Word string `json:"word" form:"word"`
+}
+
+type wordQueryBody = struct {
+	Word     string `json:"word" form:"word" query:"word"`
+	UseLemma bool   `json:"use_lemma,omitempty" form:"use_lemma" query:"use_lemma"`
+}
+
+type wordBatchBody = struct {
+	Words    []string `json:"words" form:"words"`
+	UseLemma bool     `json:"use_lemma,omitempty" form:"use_lemma"`
+}
+
+type unitBody = struct {
+	Name       string `json:"name" form:"name"`
+	ReciteDate string `json:"recite_date,omitempty" form:"recite_date"`
+}
+
+type unitOrderBody = struct {
+	UnitIDs []int64 `json:"unit_ids" form:"unit_ids"`
+}
+
+type quizAnswerBody = struct {
+	InputAnswer string `json:"input_answer" form:"input_answer"`
+	Result      string `json:"result" form:"result"`
+}
+
+type noteBody = struct {
+	NoteType string  `json:"note_type" form:"note_type"`
+	Content  string  `json:"content" form:"content"`
+	WordIDs  []int64 `json:"word_ids" form:"word_ids"`
+}
+
+type okData = struct {
+	OK bool `json:"ok"`
+}
+
+type configData = struct {
+	Config recite.ClientConfig `json:"config"`
+}
+
+type unitsData = struct {
+	Units []recite.UnitInfo `json:"units"`
+}
+
+type unitData = struct {
+	Unit recite.UnitInfo `json:"unit"`
+}
+
+type wordData = struct {
+	Word recite.WordInfo `json:"word"`
+}
+
+type wordBatchData = struct {
+	Items []recite.WordBatchItem `json:"items"`
+}
+
+type wordSearchData = struct {
+	Words []recite.WordSearchItem `json:"words"`
+}
+
+type wordGraphData = struct {
+	Graph recite.WordGraph `json:"graph"`
+}
+
+type wordRefreshData = struct {
+	Word    recite.WordInfo          `json:"word"`
+	Refresh recite.WordRefreshResult `json:"refresh"`
+}
+
+type unitWordsData = struct {
+	Words []recite.UnitWordItem `json:"words"`
+}
+
+type wordListData = struct {
+	Words []recite.UnitWordItem `json:"words"`
+	Total int                   `json:"total"`
+}
+
+type unitGraphData = struct {
+	Graph recite.UnitGraph `json:"graph"`
+}
+
+type reviewDatesData = struct {
+	Dates []string `json:"dates"`
+}
+
+type reviewWordsData = struct {
+	Words []recite.UnitWordItem      `json:"words"`
+	Units []recite.ReviewUnitSummary `json:"units"`
+}
+
+type quizData = struct {
+	Quiz recite.QuizDetail `json:"quiz"`
+}
+
+type quizListData = struct {
+	Items      []recite.QuizListItem `json:"items"`
+	Total      int64                 `json:"total"`
+	Page       int                   `json:"page"`
+	PageSize   int                   `json:"page_size"`
+	HasRunning bool                  `json:"has_running"`
+}
+
+type quizRunningData = struct {
+	HasRunning bool `json:"has_running"`
+}
+
+type quizAnswerData = struct {
+	OK     bool   `json:"ok"`
+	Result string `json:"result"`
+}
+
+type noteData = struct {
+	Note recite.NoteDetail `json:"note"`
+}
+
+type noteListData = struct {
+	Items    []recite.NoteListItem `json:"items"`
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"page_size"`
+}
+
+type wordNotesData = struct {
+	WordNotes map[int64][]recite.NoteTag `json:"word_notes"`
+}
+
+type noteSearchData = struct {
+	Items    []recite.NoteSearchItem `json:"items"`
+	Total    int64                   `json:"total"`
+	Page     int                     `json:"page"`
+	PageSize int                     `json:"page_size"`
+}
+
+type audioReportData = struct {
+	Report recite.AudioCheckReport `json:"report"`
+}
+
+type audioRepairData = struct {
+	Job recite.AudioRepairJob `json:"job"`
+}
@@ -0,0 +1,28 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func UnlinkNoteWord(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		noteID, err := strconv.ParseInt(c.Param("noteId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "note_id"))
+		}
+		wordID, err := strconv.ParseInt(c.Param("wordId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_id"))
+		}
+		detail, err := svc.UnlinkNoteWord(c.Request().Context(), noteID, wordID)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, noteData{Note: *detail})
+	}
+}
@@ -0,0 +1,28 @@
+package recite
+
+import (
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func UpdateWord(svc *recite.Service) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		wordID, err := strconv.ParseInt(c.Param("wordId"), 10, 64)
+		if err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidParam, "word_id"))
+		}
+		req := recite.UpdateWordRequest{}
+		if err := c.Bind(&req); err != nil {
+			return util.JSONError(c, errcode.New(errcode.InvalidRequest))
+		}
+		wordInfo, err := svc.UpdateWord(c.Request().Context(), wordID, req)
+		if err != nil {
+			return util.JSONError(c, recite.ParseError(err))
+		}
+		return util.JSONSuccess(c, wordData{Word: *wordInfo})
+	}
+}
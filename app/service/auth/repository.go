@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/wutianfang/moss/infra/auth/entity"
+)
+
+// TokenRepository is the storage Service depends on; the MySQL implementation
+// lives in infra/auth/repository.
+type TokenRepository interface {
+	Create(ctx context.Context, token *entity.APIToken) error
+	List(ctx context.Context) ([]entity.APIToken, error)
+	GetByID(ctx context.Context, id int64) (*entity.APIToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*entity.APIToken, error)
+	Revoke(ctx context.Context, id int64, at time.Time) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
@@ -0,0 +1,144 @@
+// Package auth manages the API tokens accepted by the /api routes.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/infra/auth/entity"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// Scopes, from the narrowest to the widest. A token is granted one scope and
+// every narrower one: quiz-write can read, write can take quizzes, admin can
+// also manage tokens and repair audio.
+const (
+	ScopeRead      = "read"
+	ScopeQuizWrite = "quiz-write"
+	ScopeWrite     = "write"
+	ScopeAdmin     = "admin"
+)
+
+var scopeRanks = map[string]int{
+	ScopeRead:      1,
+	ScopeQuizWrite: 2,
+	ScopeWrite:     3,
+	ScopeAdmin:     4,
+}
+
+const (
+	tokenPrefix    = "moss_"
+	tokenBytes     = 32
+	tokenShownChar = 12
+	// lastUsedResolution throttles last_used_at writes so busy clients do not
+	// update the row on every request.
+	lastUsedResolution = time.Minute
+)
+
+type Service struct {
+	tokenRepo TokenRepository
+}
+
+func NewService(tokenRepo TokenRepository) *Service {
+	return &Service{tokenRepo: tokenRepo}
+}
+
+// CreatedToken is a new token together with its secret, which is shown once
+// and never stored.
+type CreatedToken struct {
+	Token  entity.APIToken `json:"token"`
+	Secret string          `json:"secret"`
+}
+
+func (s *Service) CreateToken(ctx context.Context, name, scope string) (*CreatedToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errcode.New(errcode.TokenNameEmpty)
+	}
+	scope = strings.TrimSpace(scope)
+	if _, ok := scopeRanks[scope]; !ok {
+		return nil, errcode.New(errcode.TokenScopeInvalid)
+	}
+
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	token := entity.APIToken{
+		Name:        name,
+		TokenHash:   hashToken(secret),
+		TokenPrefix: secret[:tokenShownChar],
+		Scope:       scope,
+	}
+	if err := s.tokenRepo.Create(ctx, &token); err != nil {
+		return nil, err
+	}
+	return &CreatedToken{Token: token, Secret: secret}, nil
+}
+
+func (s *Service) ListTokens(ctx context.Context) ([]entity.APIToken, error) {
+	return s.tokenRepo.List(ctx)
+}
+
+func (s *Service) RevokeToken(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return errcode.New(errcode.InvalidParam, "token_id")
+	}
+	token, err := s.tokenRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return errcode.New(errcode.TokenNotFound)
+	}
+	return s.tokenRepo.Revoke(ctx, id, time.Now())
+}
+
+// Authenticate returns the live token whose secret is given and records its
+// use. Unknown and revoked tokens are rejected alike.
+func (s *Service) Authenticate(ctx context.Context, secret string) (*entity.APIToken, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, errcode.New(errcode.TokenInvalid)
+	}
+	token, err := s.tokenRepo.GetByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return nil, errcode.New(errcode.TokenInvalid)
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.tokenRepo.TouchLastUsed(ctx, token.ID, now); err != nil {
+			// auditing must not lock clients out
+			util.Warnf("touch api token last_used_at failed: token_id=%d err=%v", token.ID, err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
+
+// Allows reports whether a token granted scope may do what required needs.
+func Allows(scope, required string) bool {
+	rank, ok := scopeRanks[scope]
+	return ok && rank >= scopeRanks[required]
+}
+
+// Scopes lists the valid scopes from the narrowest to the widest.
+func Scopes() []string {
+	return []string{ScopeRead, ScopeQuizWrite, ScopeWrite, ScopeAdmin}
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wutianfang/moss/infra/auth/repository/memory"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+func errKey(err error) string {
+	var apiErr *errcode.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code.Key
+	}
+	return ""
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTokenRepository()
+	svc := NewService(repo)
+
+	created, err := svc.CreateToken(ctx, "cli", ScopeWrite)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	token, err := svc.Authenticate(ctx, created.Secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if token.ID != created.Token.ID || token.Scope != ScopeWrite || token.LastUsedAt == nil {
+		t.Fatalf("Authenticate = %+v", token)
+	}
+
+	for _, secret := range []string{"", "not-a-moss-token", created.Secret + "x", "moss_unknown"} {
+		if _, err := svc.Authenticate(ctx, secret); errKey(err) != errcode.TokenInvalid.Key {
+			t.Errorf("Authenticate(%q) err = %v, want token_invalid", secret, err)
+		}
+	}
+
+	if err := svc.RevokeToken(ctx, created.Token.ID); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, created.Secret); errKey(err) != errcode.TokenInvalid.Key {
+		t.Fatalf("revoked token err = %v, want token_invalid", err)
+	}
+	if err := svc.RevokeToken(ctx, created.Token.ID+1); errKey(err) != errcode.TokenNotFound.Key {
+		t.Fatalf("RevokeToken(unknown) err = %v, want token_not_found", err)
+	}
+}
+
+func TestAuthenticateThrottlesLastUsed(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewTokenRepository()
+	svc := NewService(repo)
+	created, err := svc.CreateToken(ctx, "web", ScopeRead)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Authenticate(ctx, created.Secret); err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+	}
+	if repo.Touches != 1 {
+		t.Fatalf("touches after a burst = %d, want 1", repo.Touches)
+	}
+
+	repo.SetLastUsed(created.Token.ID, time.Now().Add(-lastUsedResolution))
+	token, err := svc.Authenticate(ctx, created.Secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if repo.Touches != 2 {
+		t.Fatalf("touches after %s = %d, want 2", lastUsedResolution, repo.Touches)
+	}
+	if time.Since(*token.LastUsedAt) > time.Second {
+		t.Fatalf("last_used_at = %v, want now", token.LastUsedAt)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		scope, required string
+		want            bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeQuizWrite, false},
+		{ScopeQuizWrite, ScopeRead, true},
+		{ScopeQuizWrite, ScopeWrite, false},
+		{ScopeWrite, ScopeQuizWrite, true},
+		{ScopeWrite, ScopeAdmin, false},
+		{ScopeAdmin, ScopeAdmin, true},
+		{"root", ScopeRead, false},
+	}
+	for _, tc := range cases {
+		if got := Allows(tc.scope, tc.required); got != tc.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tc.scope, tc.required, got, tc.want)
+		}
+	}
+}
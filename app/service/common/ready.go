@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/wutianfang/moss/infra/storage"
+)
+
+// readyProbeKey is written and removed again to prove the audio store is
+// writable.
+const readyProbeKey = ".readyz"
+
+// ReadyCheck is the outcome of one readiness check.
+type ReadyCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// CheckReady pings the database and writes a probe blob into the audio store.
+// It reports every check and whether all of them passed.
+func CheckReady(ctx context.Context, db *sql.DB, audioStore storage.Store) ([]ReadyCheck, bool) {
+	checks := []ReadyCheck{
+		newReadyCheck("mysql", db.PingContext(ctx)),
+		newReadyCheck("audio_store", checkWritable(ctx, audioStore)),
+	}
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.OK
+	}
+	return checks, ready
+}
+
+func checkWritable(ctx context.Context, store storage.Store) error {
+	if err := store.Put(ctx, readyProbeKey, []byte("ok")); err != nil {
+		return fmt.Errorf("%s not writable: %w", store.Name(), err)
+	}
+	if err := store.Delete(ctx, readyProbeKey); err != nil {
+		return fmt.Errorf("%s delete probe failed: %w", store.Name(), err)
+	}
+	return nil
+}
+
+func newReadyCheck(name string, err error) ReadyCheck {
+	if err != nil {
+		return ReadyCheck{Name: name, Error: err.Error()}
+	}
+	return ReadyCheck{Name: name, OK: true}
+}
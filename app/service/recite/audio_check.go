@@ -0,0 +1,231 @@
+package recite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	audioActionRedownloaded = "redownloaded"
+	audioActionSynthesized  = "synthesized"
+	audioActionRemoved      = "removed"
+	audioActionFailed       = "failed"
+	// audioActionUnavailable marks a missing file the dictionary has no audio
+	// for, which no repair can bring back.
+	audioActionUnavailable = "unavailable"
+)
+
+type AudioIssueItem struct {
+	Word    string `json:"word"`
+	Accent  string `json:"accent"`
+	Key     string `json:"key"`
+	Problem string `json:"problem"`
+	Action  string `json:"action,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type AudioCheckReport struct {
+	WordCount   int              `json:"word_count"`
+	Issues      []AudioIssueItem `json:"issues"`
+	Repaired    int              `json:"repaired"`
+	Removed     int              `json:"removed"`
+	Unavailable int              `json:"unavailable"`
+	Failed      int              `json:"failed"`
+}
+
+// CheckAudio scans the audio of every cached word and its example sentences.
+// With repair, broken or missing files are downloaded again and orphan files
+// are removed. Accents the dictionary has no audio for are not reported
+// missing unless an audio provider could synthesize them.
+func (s *Service) CheckAudio(ctx context.Context, repair bool) (*AudioCheckReport, error) {
+	if s.audioLibrary == nil {
+		return nil, errcode.New(errcode.AudioCheckMissing)
+	}
+	words, err := s.wordRepo.ListAudioWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(words))
+	sentences := make(map[string]string)
+	sentenceRefs := make(map[string]sentenceAudioRef)
+	absent := make(map[string]bool)
+	for _, word := range words {
+		names = append(names, word.Word)
+		for _, group := range word.SentenceGroups {
+			for _, sentence := range group.Sentences {
+				if sentence.ID <= 0 && sentence.EN == "" {
+					continue
+				}
+				name := fetcher.SentenceAudioName(sentence.ID, sentence.EN)
+				key := fetcher.SentenceAudioKey(name)
+				sentences[key] = word.Word
+				sentenceRefs[key] = sentenceAudioRef{name: name, source: sentence.TTSURL, text: sentence.EN}
+				// only audio the sentence already plays from the library is
+				// required; the rest is still to be downloaded or synthesized
+				if sentence.LocalTTSURL != fetcher.AudioURL(key) {
+					absent[key] = true
+				}
+			}
+		}
+		if s.audioProvider != nil {
+			continue
+		}
+		for _, accent := range word.NoAudioAccents {
+			absent[fetcher.WordAudioKey(accent, word.Word)] = true
+		}
+	}
+	issues, err := s.audioLibrary.Scan(ctx, names, sentences, absent)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AudioCheckReport{
+		WordCount: len(words),
+		Issues:    make([]AudioIssueItem, 0, len(issues)),
+	}
+	repairedWords := make(map[string]wordAudioRepair)
+	for _, issue := range issues {
+		item := AudioIssueItem{
+			Word:    issue.Word,
+			Accent:  issue.Accent,
+			Key:     issue.Key,
+			Problem: issue.Problem,
+		}
+		if repair {
+			s.repairAudioIssue(ctx, issue, &item, repairedWords, sentenceRefs)
+			switch item.Action {
+			case audioActionRedownloaded, audioActionSynthesized:
+				report.Repaired++
+			case audioActionRemoved:
+				report.Removed++
+			case audioActionUnavailable:
+				report.Unavailable++
+			case audioActionFailed:
+				report.Failed++
+			}
+		}
+		report.Issues = append(report.Issues, item)
+	}
+	util.InfofWithRequest(ctx, "recite.check_audio.finish", "word_count=%d issue_count=%d repair=%t repaired=%d removed=%d unavailable=%d failed=%d",
+		report.WordCount, len(report.Issues), repair, report.Repaired, report.Removed, report.Unavailable, report.Failed)
+	return report, nil
+}
+
+func (s *Service) repairAudioIssue(ctx context.Context, issue fetcher.AudioIssue, item *AudioIssueItem, repairedWords map[string]wordAudioRepair, sentenceRefs map[string]sentenceAudioRef) {
+	if issue.Problem == fetcher.AudioProblemOrphan {
+		if err := s.audioLibrary.Remove(ctx, issue.Key); err != nil {
+			item.Action = audioActionFailed
+			item.Error = err.Error()
+			return
+		}
+		item.Action = audioActionRemoved
+		return
+	}
+
+	if issue.Problem != fetcher.AudioProblemMissing {
+		if err := s.audioLibrary.Remove(ctx, issue.Key); err != nil {
+			item.Action = audioActionFailed
+			item.Error = err.Error()
+			return
+		}
+	}
+	if fetcher.IsSentenceAudioKey(issue.Key) {
+		s.repairSentenceAudio(ctx, sentenceRefs[issue.Key], item)
+		return
+	}
+	// both accents of a word are downloaded by one fetch
+	repaired, ok := repairedWords[issue.Word]
+	if !ok {
+		repaired = s.repairWordAudio(ctx, issue.Word)
+		repairedWords[issue.Word] = repaired
+	}
+	if problem := s.audioLibrary.Check(ctx, issue.Key); problem != "" {
+		item.Action = audioActionFailed
+		if containsString(repaired.noAudio, issue.Accent) {
+			item.Action = audioActionUnavailable
+		}
+		item.Error = problem
+		if repaired.err != nil {
+			item.Error = repaired.err.Error()
+		}
+		return
+	}
+	item.Action = audioActionRedownloaded
+	if repaired.synthesized {
+		item.Action = audioActionSynthesized
+	}
+}
+
+// sentenceAudioRef is an example sentence whose audio the check expects:
+// the audio name, the dictionary url it is downloaded from, if any, and the
+// text it is synthesized from otherwise.
+type sentenceAudioRef struct {
+	name   string
+	source string
+	text   string
+}
+
+// repairSentenceAudio downloads the audio of a sentence again, or synthesizes
+// it when the dictionary gave none.
+func (s *Service) repairSentenceAudio(ctx context.Context, ref sentenceAudioRef, item *AudioIssueItem) {
+	var err error
+	action := audioActionRedownloaded
+	switch {
+	case ref.source != "":
+		err = s.wordFetcher.DownloadSentenceAudio(ctx, ref.source, ref.name)
+	case s.audioProvider != nil:
+		action = audioActionSynthesized
+		err = s.synthesizeAudio(ctx, ref.text, s.defaultAccent, item.Key)
+	default:
+		err = errors.New("the sentence has no dictionary audio and no audio provider is set")
+	}
+	if problem := s.audioLibrary.Check(ctx, item.Key); problem != "" {
+		item.Action = audioActionFailed
+		item.Error = problem
+		if err != nil {
+			item.Error = err.Error()
+		}
+		return
+	}
+	item.Action = action
+}
+
+// wordAudioRepair is the outcome of repairing the audio of one word: err is
+// the download error, synthesized is set when the audio provider made up for
+// it, and noAudio lists the accents the dictionary has no audio for.
+type wordAudioRepair struct {
+	synthesized bool
+	noAudio     []string
+	err         error
+}
+
+func (s *Service) repairWordAudio(ctx context.Context, word string) wordAudioRepair {
+	fetchErr := s.wordFetcher.EnsureAudioFiles(ctx, word)
+	if fetchErr == nil {
+		return wordAudioRepair{}
+	}
+	var noAudio *fetcher.NoAudioError
+	unavailable := errors.As(fetchErr, &noAudio)
+	if !unavailable && s.audioProvider == nil {
+		return wordAudioRepair{err: fetchErr}
+	}
+	row, err := s.wordRepo.GetByWord(ctx, word)
+	if err != nil || row == nil {
+		return wordAudioRepair{err: fetchErr}
+	}
+	repaired := wordAudioRepair{err: fetchErr}
+	if unavailable {
+		repaired.noAudio = noAudio.Accents
+		// remember it, so the next check doesn't report the file missing
+		s.rememberNoAudio(ctx, row, noAudio.Accents)
+	}
+	if s.audioProvider != nil {
+		s.fallbackWordAudio(ctx, row, fetchErr, 0)
+		repaired.synthesized = len(row.SyntheticAccents) > 0
+	}
+	return repaired
+}
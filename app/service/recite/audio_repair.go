@@ -0,0 +1,58 @@
+package recite
+
+import (
+	"context"
+	"time"
+
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// AudioRepairJob is the state of the audio repair run in the background. Report
+// and Error describe the last finished run.
+type AudioRepairJob struct {
+	Running    bool              `json:"running"`
+	StartedAt  string            `json:"started_at,omitempty"`
+	FinishedAt string            `json:"finished_at,omitempty"`
+	Report     *AudioCheckReport `json:"report,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// StartAudioRepair runs CheckAudio with repair in the background, since
+// repairing a whole library takes far longer than a request may. Only one
+// repair runs at a time.
+func (s *Service) StartAudioRepair(ctx context.Context) (AudioRepairJob, error) {
+	if s.audioLibrary == nil {
+		return AudioRepairJob{}, errcode.New(errcode.AudioCheckMissing)
+	}
+	s.audioRepairMu.Lock()
+	defer s.audioRepairMu.Unlock()
+	if s.audioRepair.Running {
+		return s.audioRepair, errcode.New(errcode.AudioRepairRunning)
+	}
+	s.audioRepair = AudioRepairJob{
+		Running:   true,
+		StartedAt: time.Now().Format(datetimeLayout),
+	}
+	util.InfofWithRequest(ctx, "recite.audio_repair.start", "started_at=%s", s.audioRepair.StartedAt)
+	s.runBackground(func(ctx context.Context) {
+		report, err := s.CheckAudio(ctx, true)
+		s.audioRepairMu.Lock()
+		defer s.audioRepairMu.Unlock()
+		s.audioRepair.Running = false
+		s.audioRepair.FinishedAt = time.Now().Format(datetimeLayout)
+		s.audioRepair.Report = report
+		if err != nil {
+			s.audioRepair.Error = err.Error()
+			util.ErrorfWithRequest(ctx, "recite.audio_repair.failed", "err=%v", err)
+		}
+	})
+	return s.audioRepair, nil
+}
+
+// AudioRepairStatus returns the state of the current or last audio repair.
+func (s *Service) AudioRepairStatus() AudioRepairJob {
+	s.audioRepairMu.Lock()
+	defer s.audioRepairMu.Unlock()
+	return s.audioRepair
+}
@@ -0,0 +1,181 @@
+package recite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/util"
+)
+
+var wordAudioAccents = []string{"en", "am"}
+
+// querySentenceSynthesis caps the example sentences synthesized while a query
+// waits; the rest get their audio on later queries.
+const querySentenceSynthesis = 2
+
+// ensureWordAudio makes sure a cached word has playable audio. Dictionary
+// audio is tried first; accents it can't provide, and example sentences
+// without a TTS url, fall back to the audio provider. Once synthesized the
+// files are valid, so later queries no longer hit the dictionary site.
+// Sentence audio of the dictionary is copied into the local library.
+func (s *Service) ensureWordAudio(ctx context.Context, row *entity.Word) {
+	s.fallbackWordAudio(ctx, row, s.fetchWordAudio(ctx, row), querySentenceSynthesis)
+	s.cacheSentenceAudio(ctx, row)
+}
+
+// fetchWordAudio downloads the word audio missing from the library. Accents
+// the dictionary is known to lack are not asked for again: when only those
+// are missing the site isn't visited and a NoAudioError is returned.
+func (s *Service) fetchWordAudio(ctx context.Context, row *entity.Word) error {
+	if s.audioLibrary == nil || len(row.NoAudioAccents) == 0 {
+		return s.downloadWordAudio(ctx, row)
+	}
+	missing := make([]string, 0, len(wordAudioAccents))
+	for _, accent := range wordAudioAccents {
+		if !s.audioLibrary.Ready(ctx, fetcher.WordAudioKey(accent, row.Word)) {
+			missing = append(missing, accent)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	for _, accent := range missing {
+		if !containsString(row.NoAudioAccents, accent) {
+			return s.downloadWordAudio(ctx, row)
+		}
+	}
+	return &fetcher.NoAudioError{Accents: missing}
+}
+
+// downloadWordAudio asks the dictionary for the word audio and remembers the
+// accents it turns out to lack.
+func (s *Service) downloadWordAudio(ctx context.Context, row *entity.Word) error {
+	err := s.wordFetcher.EnsureAudioFiles(ctx, row.Word)
+	var noAudio *fetcher.NoAudioError
+	if errors.As(err, &noAudio) {
+		s.rememberNoAudio(ctx, row, noAudio.Accents)
+	}
+	return err
+}
+
+// rememberNoAudio stores the accents the dictionary has no audio for, so
+// later queries and checks don't ask for them again.
+func (s *Service) rememberNoAudio(ctx context.Context, row *entity.Word, accents []string) {
+	if row.ID <= 0 || sameJSON(row.NoAudioAccents, accents) {
+		return
+	}
+	row.NoAudioAccents = accents
+	if err := s.wordRepo.Update(ctx, row); err != nil {
+		util.ErrorfWithRequest(ctx, "service.recite.audio.save_no_audio_failed", "word=%s err=%v", row.Word, err)
+	}
+}
+
+// fallbackWordAudio synthesizes what the dictionary download left missing,
+// and at most sentenceLimit example sentences (no limit when 0).
+func (s *Service) fallbackWordAudio(ctx context.Context, row *entity.Word, fetchErr error, sentenceLimit int) {
+	if s.audioProvider == nil || s.audioLibrary == nil {
+		return
+	}
+
+	changed := false
+	if fetchErr != nil {
+		for _, accent := range wordAudioAccents {
+			key := fetcher.WordAudioKey(accent, row.Word)
+			if s.audioLibrary.Ready(ctx, key) {
+				continue
+			}
+			if err := s.synthesizeAudio(ctx, row.Word, accent, key); err != nil {
+				util.ErrorfWithRequest(ctx, "service.recite.audio.synthesize_word_failed", "provider=%s word=%s accent=%s err=%v", s.audioProvider.Name(), row.Word, accent, err)
+				continue
+			}
+			if s.audioProvider.Synthetic() && !containsString(row.SyntheticAccents, accent) {
+				row.SyntheticAccents = append(row.SyntheticAccents, accent)
+			}
+			changed = true
+		}
+	}
+	if s.synthesizeSentenceAudio(ctx, row, sentenceLimit) {
+		changed = true
+	}
+	if !changed || row.ID <= 0 {
+		return
+	}
+	if err := s.wordRepo.Update(ctx, row); err != nil {
+		util.ErrorfWithRequest(ctx, "service.recite.audio.save_synthetic_failed", "word=%s err=%v", row.Word, err)
+	}
+}
+
+// synthesizeSentenceAudio generates audio for example sentences that have none
+// and points their local TTS url at the generated file. At most limit
+// sentences are generated (no limit when 0); files already in the library
+// don't count.
+func (s *Service) synthesizeSentenceAudio(ctx context.Context, row *entity.Word, limit int) bool {
+	changed := false
+	generated := 0
+	for gi := range row.SentenceGroups {
+		sentences := row.SentenceGroups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			if sentence.TTSURL != "" || sentence.LocalTTSURL != "" || sentence.EN == "" {
+				continue
+			}
+			key := fetcher.SentenceAudioKey(fetcher.SentenceAudioName(sentence.ID, sentence.EN))
+			if !s.audioLibrary.Ready(ctx, key) {
+				if limit > 0 && generated >= limit {
+					continue
+				}
+				generated++
+				if err := s.synthesizeAudio(ctx, sentence.EN, s.defaultAccent, key); err != nil {
+					util.ErrorfWithRequest(ctx, "service.recite.audio.synthesize_sentence_failed", "provider=%s word=%s key=%s err=%v", s.audioProvider.Name(), row.Word, key, err)
+					continue
+				}
+			}
+			sentence.LocalTTSURL = fetcher.AudioURL(key)
+			sentence.Synthetic = s.audioProvider.Synthetic()
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (s *Service) synthesizeAudio(ctx context.Context, text, accent, key string) error {
+	data, err := s.audioProvider.Generate(ctx, text, accent)
+	if err != nil {
+		return err
+	}
+	return s.audioLibrary.Put(ctx, key, data)
+}
+
+// dropSyntheticWordAudio removes synthesized word audio before a refresh so the
+// dictionary download can replace it. It returns the accents removed.
+func (s *Service) dropSyntheticWordAudio(ctx context.Context, row *entity.Word) []string {
+	if s.audioProvider == nil || s.audioLibrary == nil || len(row.SyntheticAccents) == 0 {
+		return nil
+	}
+	removed := make([]string, 0, len(row.SyntheticAccents))
+	for _, accent := range row.SyntheticAccents {
+		if err := s.audioLibrary.Remove(ctx, fetcher.WordAudioKey(accent, row.Word)); err != nil {
+			continue
+		}
+		removed = append(removed, accent)
+	}
+	return removed
+}
+
+// settleSyntheticWordAudio clears the synthetic mark of accents whose audio was
+// replaced by a real download after dropSyntheticWordAudio.
+func (s *Service) settleSyntheticWordAudio(ctx context.Context, row *entity.Word, removed []string) {
+	if len(removed) == 0 {
+		return
+	}
+	kept := make([]string, 0, len(row.SyntheticAccents))
+	for _, accent := range row.SyntheticAccents {
+		if containsString(removed, accent) && s.audioLibrary.Ready(ctx, fetcher.WordAudioKey(accent, row.Word)) {
+			continue
+		}
+		kept = append(kept, accent)
+	}
+	row.SyntheticAccents = kept
+}
@@ -1,26 +1,24 @@
 package recite
 
-import "fmt"
+import (
+	"errors"
 
-type BizError struct {
-	Code int
-	Msg  string
-}
-
-func (e *BizError) Error() string {
-	return e.Msg
-}
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/util/errcode"
+)
 
-func NewBizError(code int, format string, args ...any) error {
-	return &BizError{Code: code, Msg: fmt.Sprintf(format, args...)}
+// ParseError classifies an error returned by the service for the API. Errors
+// of the catalogue are found through any wrapping; everything else is an
+// internal error.
+func ParseError(err error) *errcode.Error {
+	return errcode.Parse(err)
 }
 
-func ParseError(err error) (int, string) {
-	if err == nil {
-		return 0, ""
-	}
-	if biz, ok := err.(*BizError); ok {
-		return biz.Code, biz.Msg
+// fetchError reports a failed dictionary fetch: an unknown word is the
+// client's problem, anything else the dictionary's.
+func fetchError(err error) error {
+	if errors.Is(err, fetcher.ErrWordNotFound) {
+		return errcode.Wrap(errcode.WordUnknown, err)
 	}
-	return 1, err.Error()
+	return errcode.Wrap(errcode.WordFetchFailed, err)
 }
@@ -0,0 +1,87 @@
+package recite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+)
+
+// fixtureFetcher is a WordFetcher that answers from the iciba pages the
+// fetcher's parser tests replay, run through the same parser, instead of
+// calling iciba.
+type fixtureFetcher struct {
+	dir string
+	// noAudio lists, per word, the accents EnsureAudioFiles reports the
+	// dictionary has no audio for.
+	noAudio map[string][]string
+	// library, when set, receives test audio for every sentence download.
+	library *fetcher.AudioLibrary
+
+	mu      sync.Mutex
+	fetched []string
+	ensured []string
+}
+
+func newFixtureFetcher() *fixtureFetcher {
+	return &fixtureFetcher{dir: filepath.Join("..", "..", "..", "infra", "recite", "fetcher", "testdata", "iciba_sample")}
+}
+
+func (f *fixtureFetcher) FetchAndStore(ctx context.Context, word string) (*entity.Word, error) {
+	f.mu.Lock()
+	f.fetched = append(f.fetched, word)
+	f.mu.Unlock()
+
+	pagePath, _ := fetcher.IcibaFixturePaths(f.dir, word)
+	page, err := os.ReadFile(pagePath)
+	if os.IsNotExist(err) {
+		return nil, fetcher.ErrWordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry, err := fetcher.ParseIcibaPage(word, page)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Word(word), nil
+}
+
+func (f *fixtureFetcher) EnsureAudioFiles(ctx context.Context, word string) error {
+	f.mu.Lock()
+	f.ensured = append(f.ensured, word)
+	f.mu.Unlock()
+	if accents := f.noAudio[word]; len(accents) > 0 {
+		return &fetcher.NoAudioError{Accents: accents}
+	}
+	return nil
+}
+
+func (f *fixtureFetcher) DownloadSentenceAudio(ctx context.Context, source, name string) error {
+	if f.library == nil {
+		return nil
+	}
+	return f.library.Put(ctx, fetcher.SentenceAudioKey(name), testMP3())
+}
+
+// ensureCount returns how often the audio of word was checked.
+func (f *fixtureFetcher) ensureCount(word string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, item := range f.ensured {
+		if item == word {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *fixtureFetcher) fetchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.fetched)
+}
@@ -0,0 +1,155 @@
+package recite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/infra/recite/inflection"
+)
+
+// resolveLemma returns the lemma of an inflected word, or "" when the word does
+// not look inflected. Forms recorded earlier and lemmas already cached in the
+// words table are preferred over the first rule-based guess.
+func (s *Service) resolveLemma(ctx context.Context, word string) (string, error) {
+	lemmas, err := s.resolveLemmas(ctx, []string{word})
+	if err != nil {
+		return "", err
+	}
+	return lemmas[word], nil
+}
+
+// resolveLemmas is resolveLemma for many words at once, keyed by word; words
+// that do not look inflected are left out. It makes the same few queries
+// whatever the number of words.
+func (s *Service) resolveLemmas(ctx context.Context, words []string) (map[string]string, error) {
+	ret := make(map[string]string, len(words))
+	pending := words
+	if s.wordFormRepo != nil && len(words) > 0 {
+		formWordIDs, err := s.wordFormRepo.GetWordIDsByForms(ctx, words)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int64, 0, len(formWordIDs))
+		for _, id := range formWordIDs {
+			ids = append(ids, id)
+		}
+		wordMap, err := s.wordRepo.GetByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		pending = make([]string, 0, len(words))
+		for _, word := range words {
+			if row := wordMap[formWordIDs[word]]; row != nil && row.Word != word {
+				ret[word] = row.Word
+				continue
+			}
+			pending = append(pending, word)
+		}
+	}
+
+	guesses := make(map[string][]string)
+	lookups := make([]string, 0)
+	for _, word := range pending {
+		candidates := inflection.Candidates(word)
+		if len(candidates) == 0 {
+			continue
+		}
+		if _, ok := inflection.IrregularLemma(strings.Fields(word)[0]); ok || len(candidates) == 1 {
+			ret[word] = candidates[0]
+			continue
+		}
+		guesses[word] = candidates
+		lookups = append(lookups, candidates...)
+	}
+	if len(lookups) == 0 {
+		return ret, nil
+	}
+	cached, err := s.wordRepo.GetByWords(ctx, lookups)
+	if err != nil {
+		return nil, err
+	}
+	for word, candidates := range guesses {
+		ret[word] = candidates[0]
+		for _, candidate := range candidates {
+			if cached[candidate] != nil {
+				ret[word] = candidate
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// fillWordLemma suggests a lemma for an inflected word and lists the surface
+// forms seen for it.
+func (s *Service) fillWordLemma(ctx context.Context, info *WordInfo) error {
+	return s.fillWordLemmas(ctx, []*WordInfo{info})
+}
+
+// fillWordLemmas is fillWordLemma for many words at once.
+func (s *Service) fillWordLemmas(ctx context.Context, infos []*WordInfo) error {
+	if len(infos) == 0 {
+		return nil
+	}
+	words := make([]string, 0, len(infos))
+	ids := make([]int64, 0, len(infos))
+	for _, info := range infos {
+		words = append(words, info.Word)
+		ids = append(ids, info.ID)
+	}
+	lemmas, err := s.resolveLemmas(ctx, words)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if lemma := lemmas[info.Word]; lemma != info.Word {
+			info.Lemma = lemma
+		}
+	}
+	if s.wordFormRepo == nil {
+		return nil
+	}
+	forms, err := s.wordFormRepo.ListByWordIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		info.Forms = forms[info.ID]
+	}
+	return nil
+}
+
+// inflectedAnswerMatches accepts an inflected form of the word when that form
+// is the one used by one of the word's example sentences.
+func inflectedAnswerMatches(word *entity.Word, input string) bool {
+	form := fetcher.NormalizeWord(input)
+	if form == "" || form == word.Word || !inflection.IsFormOf(form, word.Word) {
+		return false
+	}
+	for _, group := range word.SentenceGroups {
+		for _, sentence := range group.Sentences {
+			if sentenceContains(sentence.EN, form) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sentenceContains(sentence, form string) bool {
+	tokens := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && r != '\'' && r != '-'
+	})
+	return strings.Contains(" "+strings.Join(tokens, " ")+" ", " "+form+" ")
+}
+
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
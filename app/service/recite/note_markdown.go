@@ -0,0 +1,62 @@
+package recite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/util/errcode"
+	"github.com/wutianfang/moss/util/markdown"
+)
+
+// noteWordLinks returns the words a note links to: wordIDs, chosen by hand,
+// and the words referenced as [[word]] in content. A word that is both stays
+// a hand-chosen link, so it survives removing its reference. Referenced
+// words have to be in the dictionary already; saving a note never fetches.
+func (s *Service) noteWordLinks(ctx context.Context, content string, wordIDs []int64) ([]int64, []int64, error) {
+	manual, err := s.normalizeWordIDs(ctx, wordIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	refWords := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, ref := range markdown.WordRefs(content) {
+		word := fetcher.NormalizeWord(ref)
+		if _, ok := seen[word]; ok || word == "" {
+			continue
+		}
+		seen[word] = struct{}{}
+		refWords = append(refWords, word)
+	}
+	refIDs := make([]int64, 0, len(refWords))
+	if len(refWords) > 0 {
+		wordMap, err := s.wordRepo.GetByWords(ctx, refWords)
+		if err != nil {
+			return nil, nil, err
+		}
+		chosen := make(map[int64]struct{}, len(manual))
+		for _, id := range manual {
+			chosen[id] = struct{}{}
+		}
+		missing := make([]string, 0)
+		for _, word := range refWords {
+			item := wordMap[word]
+			if item == nil {
+				missing = append(missing, word)
+				continue
+			}
+			if _, ok := chosen[item.ID]; ok {
+				continue
+			}
+			chosen[item.ID] = struct{}{}
+			refIDs = append(refIDs, item.ID)
+		}
+		if len(missing) > 0 {
+			return nil, nil, errcode.New(errcode.NoteWordRefUnknown, strings.Join(missing, ", "))
+		}
+	}
+	if len(manual)+len(refIDs) == 0 {
+		return nil, nil, errcode.New(errcode.NoteWordsEmpty)
+	}
+	return manual, refIDs, nil
+}
@@ -0,0 +1,207 @@
+package recite
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/wutianfang/moss/infra/recite/repository"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	maxNoteSearchTerms = 10
+	// noteSnippetRunes is the length of a search snippet and noteSnippetLead
+	// how much of it comes before the first match.
+	noteSnippetRunes = 120
+	noteSnippetLead  = 30
+)
+
+// NoteSearchRequest searches notes. Every word of Query must occur in the
+// content. Sort is "relevance" or "updated"; empty sorts by relevance when
+// there is a query and by update time otherwise.
+type NoteSearchRequest struct {
+	Query    string
+	NoteType string
+	WordID   int64
+	Sort     string
+	Page     int
+	PageSize int
+}
+
+// NoteSearchItem is a matching note. Snippet is HTML: an escaped excerpt of
+// the content around the first match with the matches wrapped in <mark>.
+type NoteSearchItem struct {
+	ID        int64    `json:"id"`
+	Type      string   `json:"type"`
+	Snippet   string   `json:"snippet"`
+	Words     []string `json:"words"`
+	Score     float64  `json:"score"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+func (s *Service) SearchNotes(ctx context.Context, req NoteSearchRequest) ([]NoteSearchItem, int64, error) {
+	if s.noteRepo == nil {
+		return nil, 0, errcode.New(errcode.NoteRepoMissing)
+	}
+	terms := searchTerms(req.Query)
+	if len(terms) > maxNoteSearchTerms {
+		return nil, 0, errcode.New(errcode.NoteSearchTooManyTerms, maxNoteSearchTerms)
+	}
+	filter := repository.NoteSearchFilter{Terms: terms, WordID: req.WordID}
+	if strings.TrimSpace(req.NoteType) != "" {
+		noteType, err := s.normalizeNoteTypeChoice(req.NoteType)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.NoteType = noteType
+	}
+	if req.WordID < 0 {
+		return nil, 0, errcode.New(errcode.InvalidParam, "word_id")
+	}
+	switch strings.TrimSpace(req.Sort) {
+	case "":
+		filter.Sort = repository.NoteSearchUpdated
+		if len(terms) > 0 {
+			filter.Sort = repository.NoteSearchRelevance
+		}
+	case repository.NoteSearchRelevance:
+		filter.Sort = repository.NoteSearchRelevance
+	case repository.NoteSearchUpdated:
+		filter.Sort = repository.NoteSearchUpdated
+	default:
+		return nil, 0, errcode.New(errcode.NoteSearchSortInvalid)
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+
+	rows, total, err := s.noteRepo.Search(ctx, filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	noteIDs := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		noteIDs = append(noteIDs, row.Note.ID)
+	}
+	wordTextsByNote, err := s.noteWordTexts(ctx, noteIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	ret := make([]NoteSearchItem, 0, len(rows))
+	for _, row := range rows {
+		ret = append(ret, NoteSearchItem{
+			ID:        row.Note.ID,
+			Type:      row.Note.NoteType,
+			Snippet:   noteSnippet(row.Note.Content, terms),
+			Words:     wordTextsByNote[row.Note.ID],
+			Score:     row.Score,
+			CreatedAt: row.Note.CreatedAt.Format(datetimeLayout),
+			UpdatedAt: row.Note.UpdatedAt.Format(datetimeLayout),
+		})
+	}
+	return ret, total, nil
+}
+
+// searchTerms splits a query on white space, dropping repeated terms.
+func searchTerms(query string) []string {
+	seen := make(map[string]bool)
+	ret := make([]string, 0)
+	for _, term := range strings.Fields(query) {
+		key := strings.ToLower(term)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, term)
+	}
+	return ret
+}
+
+// noteSnippet cuts about noteSnippetRunes of content around the first match
+// of terms, collapsing white space, and marks every match in it.
+func noteSnippet(content string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(content), " "))
+	lower := lowerRunes(runes)
+	lowerTerms := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		lowerTerms = append(lowerTerms, lowerRunes([]rune(term)))
+	}
+
+	type span struct{ start, end int }
+	spans := make([]span, 0)
+	for i := 0; i < len(lower); {
+		length := 0
+		for _, term := range lowerTerms {
+			if len(term) > length && hasRunePrefix(lower[i:], term) {
+				length = len(term)
+			}
+		}
+		if length == 0 {
+			i++
+			continue
+		}
+		spans = append(spans, span{i, i + length})
+		i += length
+	}
+
+	start := 0
+	if len(spans) > 0 && spans[0].start > noteSnippetLead {
+		start = spans[0].start - noteSnippetLead
+	}
+	end := min(len(runes), start+noteSnippetRunes)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, sp := range spans {
+		if sp.end <= start {
+			continue
+		}
+		if sp.start >= end {
+			break
+		}
+		markStart, markEnd := max(sp.start, pos), min(sp.end, end)
+		b.WriteString(html.EscapeString(string(runes[pos:markStart])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[markStart:markEnd])))
+		b.WriteString("</mark>")
+		pos = markEnd
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func lowerRunes(runes []rune) []rune {
+	ret := make([]rune, len(runes))
+	for i, r := range runes {
+		ret[i] = unicode.ToLower(r)
+	}
+	return ret
+}
+
+func hasRunePrefix(s, prefix []rune) bool {
+	if len(prefix) == 0 || len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
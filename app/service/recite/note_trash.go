@@ -0,0 +1,106 @@
+package recite
+
+import (
+	"context"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// getNote loads a note that must be in the trash when trashed is true and
+// must not be otherwise. A live note asked for from the trash is a conflict;
+// every other mismatch reads as not found.
+func (s *Service) getNote(ctx context.Context, noteID int64, trashed bool) (*entity.Note, error) {
+	if noteID <= 0 {
+		return nil, errcode.New(errcode.InvalidParam, "note_id")
+	}
+	note, err := s.noteRepo.GetByID(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, errcode.New(errcode.NoteNotFound)
+	}
+	if inTrash := note.DeletedAt != nil; inTrash != trashed {
+		if trashed {
+			return nil, errcode.New(errcode.NoteNotInTrash)
+		}
+		return nil, errcode.New(errcode.NoteNotFound)
+	}
+	return note, nil
+}
+
+// DeleteNote moves a note to the trash. Its word links are kept so that
+// RestoreNote brings it back unchanged.
+func (s *Service) DeleteNote(ctx context.Context, noteID int64) error {
+	if s.noteRepo == nil {
+		return errcode.New(errcode.NoteRepoMissing)
+	}
+	if _, err := s.getNote(ctx, noteID, false); err != nil {
+		return err
+	}
+	return s.noteRepo.SoftDelete(ctx, noteID, time.Now())
+}
+
+// RestoreNote takes a note out of the trash.
+func (s *Service) RestoreNote(ctx context.Context, noteID int64) (*NoteDetail, error) {
+	if s.noteRepo == nil {
+		return nil, errcode.New(errcode.NoteRepoMissing)
+	}
+	if _, err := s.getNote(ctx, noteID, true); err != nil {
+		return nil, err
+	}
+	if err := s.noteRepo.Restore(ctx, noteID); err != nil {
+		return nil, err
+	}
+	return s.GetNoteDetail(ctx, noteID)
+}
+
+// PurgeNote deletes a trashed note for good together with its word links.
+// Live notes have to be trashed first.
+func (s *Service) PurgeNote(ctx context.Context, noteID int64) error {
+	if s.noteRepo == nil {
+		return errcode.New(errcode.NoteRepoMissing)
+	}
+	if _, err := s.getNote(ctx, noteID, true); err != nil {
+		return err
+	}
+	return s.noteRepo.Delete(ctx, noteID)
+}
+
+// UnlinkNoteWord removes one word from a live note. A note keeps at least
+// one word; delete the note instead of unlinking its last one. A word still
+// referenced as [[word]] in the content is linked again on the next save.
+func (s *Service) UnlinkNoteWord(ctx context.Context, noteID, wordID int64) (*NoteDetail, error) {
+	if s.noteRepo == nil {
+		return nil, errcode.New(errcode.NoteRepoMissing)
+	}
+	if wordID <= 0 {
+		return nil, errcode.New(errcode.InvalidParam, "word_id")
+	}
+	if _, err := s.getNote(ctx, noteID, false); err != nil {
+		return nil, err
+	}
+	relations, err := s.noteRepo.ListWordRelationsByNoteID(ctx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	linked := false
+	for _, rel := range relations {
+		if rel.WordID == wordID {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		return nil, errcode.New(errcode.NoteWordNotLinked)
+	}
+	if len(relations) == 1 {
+		return nil, errcode.New(errcode.NoteLastWord)
+	}
+	if err := s.noteRepo.UnlinkWord(ctx, noteID, wordID); err != nil {
+		return nil, err
+	}
+	return s.GetNoteDetail(ctx, noteID)
+}
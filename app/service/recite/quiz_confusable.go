@@ -0,0 +1,130 @@
+package recite
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+// maxConfusableChoices caps the choices of a word, the answer included, when
+// its group is large.
+const maxConfusableChoices = 6
+
+// listConfusableQuizWords returns the words of a confusable quiz in random
+// order with the choices of each. The group of a word is every word sharing
+// one of the notes with it; notes linking a single word confuse nothing and
+// are skipped.
+func (s *Service) listConfusableQuizWords(ctx context.Context, noteID int64, noteType string) ([]int64, [][]int64, string, error) {
+	if s.noteRepo == nil {
+		return nil, nil, "", errcode.New(errcode.NoteRepoMissing)
+	}
+	var (
+		notes      []entity.Note
+		sourceName string
+	)
+	switch {
+	case noteID > 0:
+		note, err := s.getNote(ctx, noteID, false)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		notes = []entity.Note{*note}
+		sourceName = fmt.Sprintf("%s#%d", note.NoteType, note.ID)
+	case strings.TrimSpace(noteType) != "":
+		normalizedType, err := s.normalizeNoteTypeChoice(noteType)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		notes, err = s.noteRepo.ListByType(ctx, normalizedType)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		sourceName = normalizedType
+	default:
+		return nil, nil, "", errcode.New(errcode.InvalidParam, "note_id")
+	}
+
+	noteIDs := make([]int64, 0, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
+	}
+	relations, err := s.noteRepo.ListWordRelationsByNoteIDs(ctx, noteIDs)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	wordsByNote := make(map[int64][]int64, len(noteIDs))
+	for _, rel := range relations {
+		wordsByNote[rel.NoteID] = append(wordsByNote[rel.NoteID], rel.WordID)
+	}
+	order := make([]int64, 0)
+	groups := make(map[int64][]int64)
+	for _, id := range noteIDs {
+		words := wordsByNote[id]
+		if len(words) < 2 {
+			continue
+		}
+		for _, wordID := range words {
+			if _, ok := groups[wordID]; !ok {
+				groups[wordID] = []int64{}
+				order = append(order, wordID)
+			}
+			for _, other := range words {
+				if other != wordID && !containsID(groups[wordID], other) {
+					groups[wordID] = append(groups[wordID], other)
+				}
+			}
+		}
+	}
+	if len(order) == 0 {
+		return nil, nil, "", errcode.New(errcode.QuizNoConfusables)
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	rng.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	choices := make([][]int64, 0, len(order))
+	for _, wordID := range order {
+		others := groups[wordID]
+		rng.Shuffle(len(others), func(i, j int) {
+			others[i], others[j] = others[j], others[i]
+		})
+		if len(others) > maxConfusableChoices-1 {
+			others = others[:maxConfusableChoices-1]
+		}
+		item := append([]int64{wordID}, others...)
+		rng.Shuffle(len(item), func(i, j int) {
+			item[i], item[j] = item[j], item[i]
+		})
+		choices = append(choices, item)
+	}
+	return order, choices, sourceName, nil
+}
+
+// quizChoices resolves choice word ids, skipping words that are gone.
+func quizChoices(wordIDs []int64, wordMap map[int64]*entity.Word) []QuizChoice {
+	if len(wordIDs) == 0 {
+		return nil
+	}
+	ret := make([]QuizChoice, 0, len(wordIDs))
+	for _, id := range wordIDs {
+		if word := wordMap[id]; word != nil {
+			ret = append(ret, QuizChoice{WordID: id, Word: word.Word})
+		}
+	}
+	return ret
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package recite
+
+import (
+	"context"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/repository"
+)
+
+// The interfaces below are the storage Service depends on. The MySQL
+// implementations live in infra/recite/repository and in-memory ones for
+// tests in infra/recite/repository/memory.
+
+type WordRepository interface {
+	GetByWord(ctx context.Context, word string) (*entity.Word, error)
+	GetByID(ctx context.Context, id int64) (*entity.Word, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]*entity.Word, error)
+	GetByWords(ctx context.Context, words []string) (map[string]*entity.Word, error)
+	Search(ctx context.Context, filter repository.WordSearchFilter) ([]*entity.Word, error)
+	Create(ctx context.Context, word *entity.Word) error
+	Update(ctx context.Context, word *entity.Word) error
+	SetSentenceLocalAudio(ctx context.Context, id int64, localURLs map[string]string) error
+	ListAudioWords(ctx context.Context) ([]repository.AudioWord, error)
+	ListForRefresh(ctx context.Context, filter repository.WordRefreshFilter, afterID int64, limit int) ([]*entity.Word, error)
+}
+
+type UnitRepository interface {
+	List(ctx context.Context) ([]entity.ReciteUnit, error)
+	GetByID(ctx context.Context, id int64) (*entity.ReciteUnit, error)
+	Create(ctx context.Context, name string, reciteDate *time.Time) (*entity.ReciteUnit, error)
+	Rename(ctx context.Context, id int64, name string, reciteDate *time.Time) error
+	Count(ctx context.Context) (int64, error)
+	Reorder(ctx context.Context, unitIDs []int64) error
+	Delete(ctx context.Context, id int64) error
+	ListReviewByDate(ctx context.Context, targetDate time.Time, intervals []int) ([]entity.ReciteUnit, error)
+}
+
+type UnitWordRepository interface {
+	Add(ctx context.Context, unitID, wordID int64) error
+	ListByUnitID(ctx context.Context, unitID int64) ([]entity.UnitWordRelation, error)
+	ListByUnitIDs(ctx context.Context, unitIDs []int64) ([]entity.UnitWordRelation, error)
+}
+
+type ForgottenWordRepository interface {
+	Add(ctx context.Context, word string) error
+	ListUnrememberedDistinct(ctx context.Context) ([]string, error)
+	MarkRememberedByWord(ctx context.Context, word string) error
+	GetLatestUnrememberedAt(ctx context.Context, word string) (*time.Time, error)
+}
+
+// QuizRepository.UpdateWordResult returns sql.ErrNoRows when the quiz has no
+// word at orderNo.
+type QuizRepository interface {
+	Create(ctx context.Context, quiz *entity.Quiz, wordIDs []int64, choices [][]int64) (*entity.Quiz, error)
+	GetByID(ctx context.Context, quizID int64) (*entity.Quiz, error)
+	List(ctx context.Context, limit, offset int) ([]repository.QuizListRow, int64, error)
+	HasRunning(ctx context.Context) (bool, error)
+	ListWords(ctx context.Context, quizID int64) ([]entity.QuizWord, error)
+	GetWordByOrder(ctx context.Context, quizID int64, orderNo int) (*entity.QuizWord, error)
+	ListRecentResultsByWord(ctx context.Context, wordID int64, sourceKind string, since time.Time, limit int) ([]string, error)
+	UpdateWordResult(ctx context.Context, quizID int64, orderNo int, inputAnswer string, result string) error
+	Finish(ctx context.Context, quizID int64) error
+}
+
+type NoteRepository interface {
+	Create(ctx context.Context, noteType, content string, wordIDs, refWordIDs []int64) (*entity.Note, error)
+	Update(ctx context.Context, noteID int64, noteType, content string, wordIDs, refWordIDs []int64) error
+	GetByID(ctx context.Context, noteID int64) (*entity.Note, error)
+	List(ctx context.Context, limit, offset int) ([]repository.NoteListRow, int64, error)
+	ListTrashed(ctx context.Context, limit, offset int) ([]repository.NoteListRow, int64, error)
+	SoftDelete(ctx context.Context, noteID int64, at time.Time) error
+	Restore(ctx context.Context, noteID int64) error
+	Delete(ctx context.Context, noteID int64) error
+	UnlinkWord(ctx context.Context, noteID, wordID int64) error
+	Search(ctx context.Context, filter repository.NoteSearchFilter, limit, offset int) ([]repository.NoteSearchRow, int64, error)
+	ListByType(ctx context.Context, noteType string) ([]entity.Note, error)
+	ListWordRelationsByNoteID(ctx context.Context, noteID int64) ([]entity.NoteWordRelation, error)
+	ListWordRelationsByNoteIDs(ctx context.Context, noteIDs []int64) ([]entity.NoteWordRelation, error)
+	ListByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]entity.Note, error)
+}
+
+type WordFormRepository interface {
+	Add(ctx context.Context, wordID int64, form string) error
+	// GetWordIDsByForms returns the latest word recorded for each form,
+	// keyed by form; forms never recorded are left out.
+	GetWordIDsByForms(ctx context.Context, forms []string) (map[string]int64, error)
+	ListByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]string, error)
+}
@@ -0,0 +1,176 @@
+package recite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+type sentenceAudioSource struct {
+	name   string
+	source string
+}
+
+// cacheSentenceAudio keeps local copies of the dictionary's sentence audio so
+// playback works offline and the browser never requests the dictionary site.
+// Sentences already in the library are pointed at their local copy at once;
+// missing ones are downloaded in the background and show up on a later query.
+// Only the local urls are written back, so a concurrent edit of the
+// sentences is not lost.
+func (s *Service) cacheSentenceAudio(ctx context.Context, row *entity.Word) {
+	if s.audioLibrary == nil || row.ID <= 0 {
+		return
+	}
+	cached, pending := s.markCachedSentenceAudio(ctx, row)
+	if err := s.wordRepo.SetSentenceLocalAudio(ctx, row.ID, cached); err != nil {
+		util.ErrorfWithRequest(ctx, "service.recite.audio.save_sentence_audio_failed", "word=%s err=%v", row.Word, err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	wordID := row.ID
+	if _, running := s.sentenceAudioJobs.LoadOrStore(wordID, struct{}{}); running {
+		return
+	}
+	s.runBackground(func(ctx context.Context) {
+		defer s.sentenceAudioJobs.Delete(wordID)
+		downloaded := make(map[string]string, len(pending))
+		for _, item := range pending {
+			if err := s.wordFetcher.DownloadSentenceAudio(ctx, item.source, item.name); err != nil {
+				util.Errorf("download sentence audio failed: word_id=%d name=%s err=%v", wordID, item.name, err)
+				continue
+			}
+			if key := fetcher.SentenceAudioKey(item.name); s.audioLibrary.Ready(ctx, key) {
+				downloaded[item.source] = fetcher.AudioURL(key)
+			}
+		}
+		if err := s.wordRepo.SetSentenceLocalAudio(ctx, wordID, downloaded); err != nil {
+			util.Errorf("save cached sentence audio failed: word_id=%d err=%v", wordID, err)
+		}
+	})
+}
+
+// markCachedSentenceAudio sets the local url of sentences whose audio is in the
+// library. It returns those local urls by dictionary url, and the remote audio
+// still to download.
+func (s *Service) markCachedSentenceAudio(ctx context.Context, row *entity.Word) (map[string]string, []sentenceAudioSource) {
+	cached := make(map[string]string)
+	pending := make([]sentenceAudioSource, 0)
+	seen := make(map[string]struct{})
+	for gi := range row.SentenceGroups {
+		sentences := row.SentenceGroups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			if sentence.TTSURL == "" || sentence.LocalTTSURL != "" {
+				continue
+			}
+			name := fetcher.SentenceAudioName(sentence.ID, sentence.EN)
+			key := fetcher.SentenceAudioKey(name)
+			if s.audioLibrary.Ready(ctx, key) {
+				sentence.LocalTTSURL = fetcher.AudioURL(key)
+				cached[sentence.TTSURL] = sentence.LocalTTSURL
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			pending = append(pending, sentenceAudioSource{name: name, source: sentence.TTSURL})
+		}
+	}
+	return cached, pending
+}
+
+// runBackground runs fn outside the request so it isn't cancelled with it.
+// Shutdown waits for it and cancels its context when out of time.
+func (s *Service) runBackground(fn func(ctx context.Context)) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		fn(s.backgroundCtx)
+	}()
+}
+
+func sentenceTTSURL(sentence entity.WordSentence) string {
+	if sentence.LocalTTSURL != "" {
+		return sentence.LocalTTSURL
+	}
+	return sentence.TTSURL
+}
+
+// carrySentenceAudio copies the local audio of matching sentences in previous
+// onto freshly fetched sentences, so a refresh doesn't look like a change.
+func carrySentenceAudio(groups []entity.WordSentenceGroup, previous ...[]entity.WordSentenceGroup) {
+	known := make(map[string]entity.WordSentence)
+	for _, list := range previous {
+		for _, group := range list {
+			for _, sentence := range group.Sentences {
+				if sentence.LocalTTSURL != "" {
+					known[fetcher.SentenceAudioName(sentence.ID, sentence.EN)] = sentence
+				}
+			}
+		}
+	}
+	for gi := range groups {
+		sentences := groups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			old, ok := known[fetcher.SentenceAudioName(sentence.ID, sentence.EN)]
+			if !ok || old.TTSURL != sentence.TTSURL {
+				continue
+			}
+			sentence.LocalTTSURL = old.LocalTTSURL
+			sentence.Synthetic = old.Synthetic
+		}
+	}
+}
+
+// restoreSentenceAudio undoes the ttsUrl rewrite for sentences sent back by the
+// client: a local url is matched to the stored sentence it came from. Any
+// other url has to be one the dictionary gave for a sentence in previous; the
+// server downloads these, so taking one from the client would let it make the
+// server request an arbitrary address.
+func restoreSentenceAudio(groups []entity.WordSentenceGroup, previous ...[]entity.WordSentenceGroup) error {
+	byLocal := make(map[string]entity.WordSentence)
+	upstream := make(map[string]struct{})
+	for _, list := range previous {
+		for _, group := range list {
+			for _, sentence := range group.Sentences {
+				if sentence.LocalTTSURL != "" {
+					byLocal[sentence.LocalTTSURL] = sentence
+				}
+				if sentence.TTSURL != "" {
+					upstream[sentence.TTSURL] = struct{}{}
+				}
+			}
+		}
+	}
+	for gi := range groups {
+		sentences := groups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			if sentence.TTSURL == "" {
+				continue
+			}
+			if !strings.HasPrefix(sentence.TTSURL, "/word_mp3/") {
+				if _, ok := upstream[sentence.TTSURL]; !ok {
+					return errcode.New(errcode.SentenceAudioURLInvalid)
+				}
+				continue
+			}
+			if old, ok := byLocal[sentence.TTSURL]; ok {
+				sentence.TTSURL = old.TTSURL
+				sentence.LocalTTSURL = old.LocalTTSURL
+				sentence.Synthetic = old.Synthetic
+				continue
+			}
+			sentence.LocalTTSURL = sentence.TTSURL
+			sentence.TTSURL = ""
+		}
+	}
+	return nil
+}
@@ -3,16 +3,21 @@ package recite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/rand"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/wutianfang/moss/infra/metrics"
+	"github.com/wutianfang/moss/infra/recite/audio"
 	"github.com/wutianfang/moss/infra/recite/entity"
 	"github.com/wutianfang/moss/infra/recite/fetcher"
-	"github.com/wutianfang/moss/infra/recite/repository"
 	"github.com/wutianfang/moss/util"
+	"github.com/wutianfang/moss/util/errcode"
+	"github.com/wutianfang/moss/util/markdown"
 )
 
 const datetimeLayout = "2006-01-02 15:04:05"
@@ -34,35 +39,63 @@ const (
 	quizSourceUnit      = "unit"
 	quizSourceForgotten = "forgotten"
 	quizSourceReview    = "review"
+	// quizSourceConfusable quizzes the words grouped by notes, each answer
+	// picked among the words of its group.
+	quizSourceConfusable = "confusable"
 )
 
-var validWord = regexp.MustCompile(`^[a-z][a-z'-]*$`)
+// validWord accepts a single word or a phrase of words separated by one space.
+var validWord = regexp.MustCompile(`^[a-z][a-z'-]*( [a-z][a-z'-]*)*$`)
 
 type Service struct {
-	wordRepo        *repository.WordRepository
-	unitRepo        *repository.UnitRepository
-	unitWordRepo    *repository.UnitWordRepository
-	forgottenRepo   *repository.ForgottenWordRepository
-	quizRepo        *repository.QuizRepository
-	noteRepo        *repository.NoteRepository
+	wordRepo        WordRepository
+	unitRepo        UnitRepository
+	unitWordRepo    UnitWordRepository
+	forgottenRepo   ForgottenWordRepository
+	quizRepo        QuizRepository
+	noteRepo        NoteRepository
+	wordFormRepo    WordFormRepository
 	wordFetcher     fetcher.WordFetcher
+	audioLibrary    *fetcher.AudioLibrary
+	audioProvider   audio.Provider
 	defaultAccent   string
 	reviewIntervals []int
 	noteTypes       []string
+	forgottenPolicy ForgottenPolicy
+
+	background        sync.WaitGroup
+	backgroundCtx     context.Context
+	stopBackground    context.CancelFunc
+	sentenceAudioJobs sync.Map
+	audioRepairMu     sync.Mutex
+	audioRepair       AudioRepairJob
+}
+
+// ForgottenPolicy decides which quiz answers are written into the forgotten list
+// automatically. RememberAfterCorrect <= 0 disables the automatic remember.
+type ForgottenPolicy struct {
+	AddOnForgotten       bool
+	AddOnWrong           bool
+	RememberAfterCorrect int
 }
 
 func NewService(
-	wordRepo *repository.WordRepository,
-	unitRepo *repository.UnitRepository,
-	unitWordRepo *repository.UnitWordRepository,
-	forgottenRepo *repository.ForgottenWordRepository,
-	quizRepo *repository.QuizRepository,
-	noteRepo *repository.NoteRepository,
+	wordRepo WordRepository,
+	unitRepo UnitRepository,
+	unitWordRepo UnitWordRepository,
+	forgottenRepo ForgottenWordRepository,
+	quizRepo QuizRepository,
+	noteRepo NoteRepository,
+	wordFormRepo WordFormRepository,
 	wordFetcher fetcher.WordFetcher,
+	audioLibrary *fetcher.AudioLibrary,
+	audioProvider audio.Provider,
 	defaultAccent string,
 	reviewIntervals []int,
 	noteTypes []string,
+	forgottenPolicy ForgottenPolicy,
 ) *Service {
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
 	return &Service{
 		wordRepo:        wordRepo,
 		unitRepo:        unitRepo,
@@ -70,10 +103,34 @@ func NewService(
 		forgottenRepo:   forgottenRepo,
 		quizRepo:        quizRepo,
 		noteRepo:        noteRepo,
+		wordFormRepo:    wordFormRepo,
 		wordFetcher:     wordFetcher,
+		audioLibrary:    audioLibrary,
+		audioProvider:   audioProvider,
 		defaultAccent:   normalizeAccent(defaultAccent),
 		reviewIntervals: normalizeReviewIntervals(reviewIntervals),
 		noteTypes:       normalizeNoteTypes(noteTypes),
+		forgottenPolicy: normalizeForgottenPolicy(forgottenPolicy),
+		backgroundCtx:   backgroundCtx,
+		stopBackground:  stopBackground,
+	}
+}
+
+// Shutdown waits for the background jobs started by requests. When ctx ends
+// first the remaining jobs are cancelled and ctx.Err() is returned.
+func (s *Service) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.stopBackground()
+		return nil
+	case <-ctx.Done():
+		s.stopBackground()
+		return ctx.Err()
 	}
 }
 
@@ -105,7 +162,7 @@ func (s *Service) ListUnits(ctx context.Context) ([]UnitInfo, error) {
 func (s *Service) CreateUnit(ctx context.Context, name, reciteDate string) (*UnitInfo, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
-		return nil, NewBizError(1001, "单元名称不能为空")
+		return nil, errcode.New(errcode.UnitNameEmpty)
 	}
 	reciteDatePtr, err := parseOptionalDate(reciteDate)
 	if err != nil {
@@ -125,18 +182,18 @@ func (s *Service) CreateUnit(ctx context.Context, name, reciteDate string) (*Uni
 
 func (s *Service) RenameUnit(ctx context.Context, unitID int64, name, reciteDate string) error {
 	if unitID <= 0 {
-		return NewBizError(1001, "unit_id 非法")
+		return errcode.New(errcode.InvalidParam, "unit_id")
 	}
 	name = strings.TrimSpace(name)
 	if name == "" {
-		return NewBizError(1001, "单元名称不能为空")
+		return errcode.New(errcode.UnitNameEmpty)
 	}
 	unit, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		return err
 	}
 	if unit == nil {
-		return NewBizError(1002, "单元不存在")
+		return errcode.New(errcode.UnitNotFound)
 	}
 	reciteDatePtr, parseErr := parseOptionalDate(reciteDate)
 	if parseErr != nil {
@@ -147,16 +204,16 @@ func (s *Service) RenameUnit(ctx context.Context, unitID int64, name, reciteDate
 
 func (s *Service) ReorderUnits(ctx context.Context, unitIDs []int64) error {
 	if len(unitIDs) == 0 {
-		return NewBizError(1001, "排序列表不能为空")
+		return errcode.New(errcode.UnitOrderEmpty)
 	}
 
 	seen := make(map[int64]struct{}, len(unitIDs))
 	for _, id := range unitIDs {
 		if id <= 0 {
-			return NewBizError(1001, "unit_id 非法")
+			return errcode.New(errcode.InvalidParam, "unit_id")
 		}
 		if _, ok := seen[id]; ok {
-			return NewBizError(1001, "排序列表包含重复单元")
+			return errcode.New(errcode.UnitOrderDuplicate)
 		}
 		seen[id] = struct{}{}
 	}
@@ -166,7 +223,7 @@ func (s *Service) ReorderUnits(ctx context.Context, unitIDs []int64) error {
 		return err
 	}
 	if int64(len(unitIDs)) != total {
-		return NewBizError(1001, "排序列表必须包含全部单元")
+		return errcode.New(errcode.UnitOrderIncomplete)
 	}
 
 	if err := s.unitRepo.Reorder(ctx, unitIDs); err != nil {
@@ -177,88 +234,131 @@ func (s *Service) ReorderUnits(ctx context.Context, unitIDs []int64) error {
 
 func (s *Service) DeleteUnit(ctx context.Context, unitID int64) error {
 	if unitID <= 0 {
-		return NewBizError(1001, "unit_id 非法")
+		return errcode.New(errcode.InvalidParam, "unit_id")
 	}
 	unit, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		return err
 	}
 	if unit == nil {
-		return NewBizError(1002, "单元不存在")
+		return errcode.New(errcode.UnitNotFound)
 	}
 	return s.unitRepo.Delete(ctx, unitID)
 }
 
 func (s *Service) QueryWord(ctx context.Context, rawWord string) (*WordInfo, error) {
-	word := strings.ToLower(strings.TrimSpace(rawWord))
-	if word == "" {
-		return nil, NewBizError(1001, "单词不能为空")
+	word, err := normalizeWord(rawWord)
+	if err != nil {
+		return nil, err
 	}
-	if !validWord.MatchString(word) {
-		return nil, NewBizError(1001, "单词格式非法，仅支持英文字母/单引号/短横线")
+
+	row, err := s.loadOrFetchWord(ctx, word)
+	if err != nil {
+		return nil, err
+	}
+	result := buildWordInfo(row)
+	if err := s.fillWordLemma(ctx, &result); err != nil {
+		return nil, err
 	}
+	return &result, nil
+}
 
+// QueryWordLemma looks up the lemma of an inflected query instead of the query
+// itself and records the query as a seen form of the lemma.
+func (s *Service) QueryWordLemma(ctx context.Context, rawWord string) (*WordInfo, error) {
+	word, err := normalizeWord(rawWord)
+	if err != nil {
+		return nil, err
+	}
+	lemma, err := s.resolveLemma(ctx, word)
+	if err != nil {
+		return nil, err
+	}
+	if lemma == "" {
+		return s.QueryWord(ctx, word)
+	}
+	result, err := s.QueryWord(ctx, lemma)
+	if err != nil {
+		return nil, err
+	}
+	if s.wordFormRepo != nil {
+		if err := s.wordFormRepo.Add(ctx, result.ID, word); err != nil {
+			return nil, err
+		}
+		if !containsString(result.Forms, word) {
+			result.Forms = append(result.Forms, word)
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) loadOrFetchWord(ctx context.Context, word string) (*entity.Word, error) {
 	cached, err := s.wordRepo.GetByWord(ctx, word)
 	if err != nil {
 		return nil, err
 	}
 	if cached != nil {
-		_ = s.wordFetcher.EnsureAudioFiles(ctx, cached.Word)
-		result := buildWordInfo(cached)
-		return &result, nil
+		s.ensureWordAudio(ctx, cached)
+		return cached, nil
 	}
 
 	fetched, err := s.wordFetcher.FetchAndStore(ctx, word)
 	if err != nil {
-		return nil, NewBizError(1003, "查词失败: %v", err)
+		return nil, fetchError(err)
 	}
 	if err := s.wordRepo.Create(ctx, fetched); err != nil {
 		cached, qErr := s.wordRepo.GetByWord(ctx, word)
 		if qErr == nil && cached != nil {
-			_ = s.wordFetcher.EnsureAudioFiles(ctx, cached.Word)
-			result := buildWordInfo(cached)
-			return &result, nil
+			s.ensureWordAudio(ctx, cached)
+			return cached, nil
 		}
 		return nil, err
 	}
-	_ = s.wordFetcher.EnsureAudioFiles(ctx, fetched.Word)
-	result := buildWordInfo(fetched)
-	return &result, nil
+	s.ensureWordAudio(ctx, fetched)
+	return fetched, nil
 }
 
-func (s *Service) AddWordToUnit(ctx context.Context, unitID int64, rawWord string) error {
+func (s *Service) AddWordToUnit(ctx context.Context, unitID int64, rawWord string, useLemma bool) error {
 	if unitID <= 0 {
-		return NewBizError(1001, "unit_id 非法")
+		return errcode.New(errcode.InvalidParam, "unit_id")
 	}
 	unit, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		return err
 	}
 	if unit == nil {
-		return NewBizError(1002, "单元不存在")
+		return errcode.New(errcode.UnitNotFound)
 	}
 
-	wordInfo, err := s.QueryWord(ctx, rawWord)
+	query := s.QueryWord
+	if useLemma {
+		query = s.QueryWordLemma
+	}
+	wordInfo, err := query(ctx, rawWord)
 	if err != nil {
 		return err
 	}
-	return s.unitWordRepo.Add(ctx, unitID, wordInfo.ID)
+	if err := s.unitWordRepo.Add(ctx, unitID, wordInfo.ID); err != nil {
+		return err
+	}
+	metrics.WordAdded()
+	return nil
 }
 
 func (s *Service) ListUnitWords(ctx context.Context, unitID int64) ([]UnitWordItem, error) {
-	util.InfofWithRequest(ctx, "recite.list_unit_words.begin", "unit_id=%d", unitID)
+	util.DebugfWithRequest(ctx, "recite.list_unit_words.begin", "unit_id=%d", unitID)
 	if unitID <= 0 {
-		util.InfofWithRequest(ctx, "recite.list_unit_words.invalid_unit_id", "unit_id=%d", unitID)
-		return nil, NewBizError(1001, "unit_id 非法")
+		util.DebugfWithRequest(ctx, "recite.list_unit_words.invalid_unit_id", "unit_id=%d", unitID)
+		return nil, errcode.New(errcode.InvalidParam, "unit_id")
 	}
 	unit, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		util.ErrorfWithRequest(ctx, "recite.list_unit_words.get_unit_failed", "unit_id=%d err=%v", unitID, err)
 		return nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_unit_words.after_get_unit", "unit_exists=%t", unit != nil)
+	util.DebugfWithRequest(ctx, "recite.list_unit_words.after_get_unit", "unit_exists=%t", unit != nil)
 	if unit == nil {
-		return nil, NewBizError(1002, "单元不存在")
+		return nil, errcode.New(errcode.UnitNotFound)
 	}
 
 	relations, err := s.unitWordRepo.ListByUnitID(ctx, unitID)
@@ -266,13 +366,13 @@ func (s *Service) ListUnitWords(ctx context.Context, unitID int64) ([]UnitWordIt
 		util.ErrorfWithRequest(ctx, "recite.list_unit_words.list_relations_failed", "unit_id=%d err=%v", unitID, err)
 		return nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_unit_words.after_list_relations", "relation_count=%d", len(relations))
+	util.DebugfWithRequest(ctx, "recite.list_unit_words.after_list_relations", "relation_count=%d", len(relations))
 	ret, err := s.buildUnitWordItemsFromRelations(ctx, relations)
 	if err != nil {
 		util.ErrorfWithRequest(ctx, "recite.list_unit_words.get_words_failed", "unit_id=%d relations=%d err=%v", unitID, len(relations), err)
 		return nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_unit_words.finish", "output_count=%d", len(ret))
+	util.DebugfWithRequest(ctx, "recite.list_unit_words.finish", "output_count=%d", len(ret))
 	return ret, nil
 }
 
@@ -357,13 +457,13 @@ func (s *Service) ListReviewDateOptions(recentDays int) []string {
 }
 
 func (s *Service) ListReviewWordsByDate(ctx context.Context, rawDate string) ([]UnitWordItem, []ReviewUnitSummary, error) {
-	util.InfofWithRequest(ctx, "recite.list_review_words.begin", "raw_date=%q", strings.TrimSpace(rawDate))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.begin", "raw_date=%q", strings.TrimSpace(rawDate))
 	targetDate, err := parseReviewDate(rawDate)
 	if err != nil {
-		util.InfofWithRequest(ctx, "recite.list_review_words.invalid_date", "raw_date=%q err=%v", strings.TrimSpace(rawDate), err)
+		util.DebugfWithRequest(ctx, "recite.list_review_words.invalid_date", "raw_date=%q err=%v", strings.TrimSpace(rawDate), err)
 		return nil, nil, err
 	}
-	util.InfofWithRequest(
+	util.DebugfWithRequest(
 		ctx,
 		"recite.list_review_words.after_parse_date",
 		"target_date=%s interval_count=%d intervals=%v",
@@ -383,16 +483,16 @@ func (s *Service) ListReviewWordsByDate(ctx context.Context, rawDate string) ([]
 		)
 		return nil, nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_review_words.after_list_units", "unit_count=%d", len(units))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.after_list_units", "unit_count=%d", len(units))
 	if len(units) == 0 {
-		util.InfofWithRequest(ctx, "recite.list_review_words.finish", "output_count=0 review_unit_count=0")
+		util.DebugfWithRequest(ctx, "recite.list_review_words.finish", "output_count=0 review_unit_count=0")
 		return []UnitWordItem{}, []ReviewUnitSummary{}, nil
 	}
 	unitIDs := make([]int64, 0, len(units))
 	for _, u := range units {
 		unitIDs = append(unitIDs, u.ID)
 	}
-	util.InfofWithRequest(ctx, "recite.list_review_words.after_collect_unit_ids", "unit_id_count=%d", len(unitIDs))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.after_collect_unit_ids", "unit_id_count=%d", len(unitIDs))
 	relations, err := s.unitWordRepo.ListByUnitIDs(ctx, unitIDs)
 	if err != nil {
 		util.ErrorfWithRequest(
@@ -404,7 +504,7 @@ func (s *Service) ListReviewWordsByDate(ctx context.Context, rawDate string) ([]
 		)
 		return nil, nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_review_words.after_list_relations", "relation_count=%d", len(relations))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.after_list_relations", "relation_count=%d", len(relations))
 	words, err := s.buildUnitWordItemsFromRelations(ctx, relations)
 	if err != nil {
 		util.ErrorfWithRequest(
@@ -416,10 +516,10 @@ func (s *Service) ListReviewWordsByDate(ctx context.Context, rawDate string) ([]
 		)
 		return nil, nil, err
 	}
-	util.InfofWithRequest(ctx, "recite.list_review_words.after_build_words", "word_count=%d", len(words))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.after_build_words", "word_count=%d", len(words))
 	reviewUnits := buildReviewUnitSummary(units, relations, targetDate)
-	util.InfofWithRequest(ctx, "recite.list_review_words.after_build_summary", "review_unit_count=%d", len(reviewUnits))
-	util.InfofWithRequest(ctx, "recite.list_review_words.finish", "output_count=%d review_unit_count=%d", len(words), len(reviewUnits))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.after_build_summary", "review_unit_count=%d", len(reviewUnits))
+	util.DebugfWithRequest(ctx, "recite.list_review_words.finish", "output_count=%d review_unit_count=%d", len(words), len(reviewUnits))
 	return words, reviewUnits, nil
 }
 
@@ -444,7 +544,7 @@ func (s *Service) GetReviewDictationWordsByDate(ctx context.Context, rawDate str
 
 func (s *Service) StartQuiz(ctx context.Context, req StartQuizRequest) (*QuizDetail, error) {
 	if s.quizRepo == nil {
-		return nil, NewBizError(1, "测验仓储未初始化")
+		return nil, errcode.New(errcode.QuizRepoMissing)
 	}
 	quizType, err := normalizeQuizType(req.Type)
 	if err != nil {
@@ -455,20 +555,34 @@ func (s *Service) StartQuiz(ctx context.Context, req StartQuizRequest) (*QuizDet
 		return nil, err
 	}
 
-	words, sourceName, sourceUnitID, sourceReviewDate, err := s.listQuizSourceWords(ctx, sourceKind, req.UnitID, req.ReviewDate)
-	if err != nil {
-		return nil, err
-	}
-	if len(words) == 0 {
-		return nil, NewBizError(1002, "暂无可测试单词")
-	}
-
-	wordIDs := make([]int64, 0, len(words))
-	for _, row := range words {
-		if row.WordID <= 0 {
-			return nil, NewBizError(1, "单词ID异常")
+	var (
+		wordIDs          []int64
+		choices          [][]int64
+		sourceName       string
+		sourceUnitID     int64
+		sourceReviewDate *time.Time
+	)
+	if sourceKind == quizSourceConfusable {
+		wordIDs, choices, sourceName, err = s.listConfusableQuizWords(ctx, req.NoteID, req.NoteType)
+		if err != nil {
+			return nil, err
 		}
-		wordIDs = append(wordIDs, row.WordID)
+	} else {
+		var words []UnitWordItem
+		words, sourceName, sourceUnitID, sourceReviewDate, err = s.listQuizSourceWords(ctx, sourceKind, req.UnitID, req.ReviewDate)
+		if err != nil {
+			return nil, err
+		}
+		wordIDs = make([]int64, 0, len(words))
+		for _, row := range words {
+			if row.WordID <= 0 {
+				return nil, errcode.New(errcode.QuizBadWordID)
+			}
+			wordIDs = append(wordIDs, row.WordID)
+		}
+	}
+	if len(wordIDs) == 0 {
+		return nil, errcode.New(errcode.QuizNoWords)
 	}
 
 	quizTitle := fmt.Sprintf("%s-%s-%s", quizType, sourceName, time.Now().Format("01/02"))
@@ -479,87 +593,197 @@ func (s *Service) StartQuiz(ctx context.Context, req StartQuizRequest) (*QuizDet
 		SourceKind:       sourceKind,
 		SourceUnitID:     sourceUnitID,
 		SourceReviewDate: sourceReviewDate,
-	}, wordIDs)
+	}, wordIDs, choices)
 	if err != nil {
 		return nil, err
 	}
+	metrics.QuizStarted(quizType, sourceKind)
 	return s.GetQuizDetail(ctx, createdQuiz.ID)
 }
 
+// SubmitQuizWord records an answer and returns the result actually stored,
+// which may be upgraded to correct by the server side grading.
 func (s *Service) SubmitQuizWord(
 	ctx context.Context,
 	quizID int64,
 	seq int,
 	inputAnswer string,
 	result string,
-) error {
+) (string, error) {
 	if s.quizRepo == nil {
-		return NewBizError(1, "测验仓储未初始化")
+		return "", errcode.New(errcode.QuizRepoMissing)
 	}
 	if quizID <= 0 {
-		return NewBizError(1001, "quiz_id 非法")
+		return "", errcode.New(errcode.InvalidParam, "quiz_id")
 	}
 	if seq <= 0 {
-		return NewBizError(1001, "seq 非法")
+		return "", errcode.New(errcode.InvalidParam, "seq")
 	}
 
 	quiz, err := s.quizRepo.GetByID(ctx, quizID)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if quiz == nil {
-		return NewBizError(1002, "测验不存在")
+		return "", errcode.New(errcode.QuizNotFound)
 	}
 	if quiz.Status != quizStatusRunning {
-		return NewBizError(1001, "测验已完结")
+		return "", errcode.New(errcode.QuizFinished)
 	}
 
 	normalizedResult, err := normalizeQuizResult(result)
 	if err != nil {
-		return err
+		return "", err
+	}
+	// confusable quizzes are graded here alone; other clients grade and the
+	// server only upgrades answers they marked wrong
+	confusable := quiz.SourceKind == quizSourceConfusable && normalizedResult != quizResultForgotten
+	if confusable || normalizedResult == quizResultWrong {
+		matched, err := s.quizAnswerMatches(ctx, quizID, seq, inputAnswer)
+		if err != nil {
+			return "", err
+		}
+		normalizedResult = quizResultWrong
+		if matched {
+			normalizedResult = quizResultCorrect
+		}
 	}
 	if err := s.quizRepo.UpdateWordResult(ctx, quizID, seq, strings.TrimSpace(inputAnswer), normalizedResult); err != nil {
-		if err == sql.ErrNoRows {
-			return NewBizError(1002, "测验单词不存在")
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", errcode.New(errcode.QuizWordNotFound)
 		}
+		return "", err
+	}
+	// the answer is already recorded; a failed policy must not turn it into an
+	// error the client would retry
+	if err := s.applyForgottenPolicy(ctx, quiz, seq, normalizedResult); err != nil {
+		util.ErrorfWithRequest(ctx, "recite.forgotten_policy.failed", "quiz_id=%d seq=%d result=%s err=%v", quiz.ID, seq, normalizedResult, err)
+	}
+	return normalizedResult, nil
+}
+
+// quizAnswerMatches regrades an answer on the server so clients that compare
+// strictly do not mark "give  up" wrong for "give up".
+func (s *Service) quizAnswerMatches(ctx context.Context, quizID int64, seq int, inputAnswer string) (bool, error) {
+	if strings.TrimSpace(inputAnswer) == "" {
+		return false, nil
+	}
+	quizWord, err := s.quizRepo.GetWordByOrder(ctx, quizID, seq)
+	if err != nil {
+		return false, err
+	}
+	if quizWord == nil {
+		return false, nil
+	}
+	wordMap, err := s.wordRepo.GetByIDs(ctx, []int64{quizWord.WordID})
+	if err != nil {
+		return false, err
+	}
+	word := wordMap[quizWord.WordID]
+	if word == nil {
+		return false, nil
+	}
+	if answerMatches(word.Word, inputAnswer) {
+		return true, nil
+	}
+	return inflectedAnswerMatches(word, inputAnswer), nil
+}
+
+func (s *Service) applyForgottenPolicy(ctx context.Context, quiz *entity.Quiz, seq int, result string) error {
+	policy := s.forgottenPolicy
+	addToForgotten := (result == quizResultForgotten && policy.AddOnForgotten) ||
+		(result == quizResultWrong && policy.AddOnWrong)
+	checkRemember := result == quizResultCorrect &&
+		quiz.SourceKind == quizSourceForgotten &&
+		policy.RememberAfterCorrect > 0
+	if !addToForgotten && !checkRemember {
+		return nil
+	}
+
+	quizWord, err := s.quizRepo.GetWordByOrder(ctx, quiz.ID, seq)
+	if err != nil {
 		return err
 	}
-	return nil
+	if quizWord == nil {
+		return nil
+	}
+	wordMap, err := s.wordRepo.GetByIDs(ctx, []int64{quizWord.WordID})
+	if err != nil {
+		return err
+	}
+	word := wordMap[quizWord.WordID]
+	if word == nil {
+		return nil
+	}
+	since, err := s.forgottenRepo.GetLatestUnrememberedAt(ctx, word.Word)
+	if err != nil {
+		return err
+	}
+
+	if addToForgotten {
+		if since != nil {
+			// already waiting in the forgotten list
+			return nil
+		}
+		util.InfofWithRequest(ctx, "recite.forgotten_policy.add", "quiz_id=%d seq=%d word=%s result=%s", quiz.ID, seq, word.Word, result)
+		return s.forgottenRepo.Add(ctx, word.Word)
+	}
+
+	if since == nil {
+		return nil
+	}
+	results, err := s.quizRepo.ListRecentResultsByWord(ctx, word.ID, quizSourceForgotten, *since, policy.RememberAfterCorrect)
+	if err != nil {
+		return err
+	}
+	if len(results) < policy.RememberAfterCorrect {
+		return nil
+	}
+	for _, item := range results {
+		if item != quizResultCorrect {
+			return nil
+		}
+	}
+	util.InfofWithRequest(ctx, "recite.forgotten_policy.remember", "quiz_id=%d seq=%d word=%s streak=%d", quiz.ID, seq, word.Word, len(results))
+	return s.forgottenRepo.MarkRememberedByWord(ctx, word.Word)
 }
 
 func (s *Service) FinishQuiz(ctx context.Context, quizID int64) (*QuizDetail, error) {
 	if s.quizRepo == nil {
-		return nil, NewBizError(1, "测验仓储未初始化")
+		return nil, errcode.New(errcode.QuizRepoMissing)
 	}
 	if quizID <= 0 {
-		return nil, NewBizError(1001, "quiz_id 非法")
+		return nil, errcode.New(errcode.InvalidParam, "quiz_id")
 	}
 	quiz, err := s.quizRepo.GetByID(ctx, quizID)
 	if err != nil {
 		return nil, err
 	}
 	if quiz == nil {
-		return nil, NewBizError(1002, "测验不存在")
+		return nil, errcode.New(errcode.QuizNotFound)
 	}
 	if err := s.quizRepo.Finish(ctx, quizID); err != nil {
 		return nil, err
 	}
+	if quiz.Status != quizStatusFinished {
+		metrics.QuizFinished()
+	}
 	return s.GetQuizDetail(ctx, quizID)
 }
 
 func (s *Service) GetQuizDetail(ctx context.Context, quizID int64) (*QuizDetail, error) {
 	if s.quizRepo == nil {
-		return nil, NewBizError(1, "测验仓储未初始化")
+		return nil, errcode.New(errcode.QuizRepoMissing)
 	}
 	if quizID <= 0 {
-		return nil, NewBizError(1001, "quiz_id 非法")
+		return nil, errcode.New(errcode.InvalidParam, "quiz_id")
 	}
 	quiz, err := s.quizRepo.GetByID(ctx, quizID)
 	if err != nil {
 		return nil, err
 	}
 	if quiz == nil {
-		return nil, NewBizError(1002, "测验不存在")
+		return nil, errcode.New(errcode.QuizNotFound)
 	}
 
 	quizWords, err := s.quizRepo.ListWords(ctx, quizID)
@@ -569,6 +793,7 @@ func (s *Service) GetQuizDetail(ctx context.Context, quizID int64) (*QuizDetail,
 	wordIDs := make([]int64, 0, len(quizWords))
 	for _, row := range quizWords {
 		wordIDs = append(wordIDs, row.WordID)
+		wordIDs = append(wordIDs, row.ChoiceWordIDs...)
 	}
 	wordMap, err := s.wordRepo.GetByIDs(ctx, wordIDs)
 	if err != nil {
@@ -607,6 +832,7 @@ func (s *Service) GetQuizDetail(ctx context.Context, quizID int64) (*QuizDetail,
 			InputAnswer: row.InputAnswer,
 			Result:      row.Result,
 			WordDetail:  detail,
+			Choices:     quizChoices(row.ChoiceWordIDs, wordMap),
 		})
 	}
 	reviewDate := ""
@@ -636,7 +862,7 @@ func (s *Service) ListQuizzes(
 	pageSize int,
 ) ([]QuizListItem, int64, bool, error) {
 	if s.quizRepo == nil {
-		return nil, 0, false, NewBizError(1, "测验仓储未初始化")
+		return nil, 0, false, errcode.New(errcode.QuizRepoMissing)
 	}
 	if page <= 0 {
 		page = 1
@@ -685,14 +911,14 @@ func (s *Service) ListQuizzes(
 
 func (s *Service) HasRunningQuiz(ctx context.Context) (bool, error) {
 	if s.quizRepo == nil {
-		return false, NewBizError(1, "测验仓储未初始化")
+		return false, errcode.New(errcode.QuizRepoMissing)
 	}
 	return s.quizRepo.HasRunning(ctx)
 }
 
 func (s *Service) CreateNote(ctx context.Context, noteType, content string, wordIDs []int64) (*NoteDetail, error) {
 	if s.noteRepo == nil {
-		return nil, NewBizError(1, "笔记仓储未初始化")
+		return nil, errcode.New(errcode.NoteRepoMissing)
 	}
 	normalizedType, err := s.normalizeNoteTypeChoice(noteType)
 	if err != nil {
@@ -700,13 +926,13 @@ func (s *Service) CreateNote(ctx context.Context, noteType, content string, word
 	}
 	normalizedContent := strings.TrimSpace(content)
 	if normalizedContent == "" {
-		return nil, NewBizError(1001, "笔记内容不能为空")
+		return nil, errcode.New(errcode.NoteContentEmpty)
 	}
-	normalizedWordIDs, err := s.normalizeWordIDs(ctx, wordIDs)
+	normalizedWordIDs, refWordIDs, err := s.noteWordLinks(ctx, normalizedContent, wordIDs)
 	if err != nil {
 		return nil, err
 	}
-	created, err := s.noteRepo.Create(ctx, normalizedType, normalizedContent, normalizedWordIDs)
+	created, err := s.noteRepo.Create(ctx, normalizedType, normalizedContent, normalizedWordIDs, refWordIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -715,18 +941,11 @@ func (s *Service) CreateNote(ctx context.Context, noteType, content string, word
 
 func (s *Service) UpdateNote(ctx context.Context, noteID int64, noteType, content string, wordIDs []int64) (*NoteDetail, error) {
 	if s.noteRepo == nil {
-		return nil, NewBizError(1, "笔记仓储未初始化")
-	}
-	if noteID <= 0 {
-		return nil, NewBizError(1001, "note_id 非法")
+		return nil, errcode.New(errcode.NoteRepoMissing)
 	}
-	exist, err := s.noteRepo.GetByID(ctx, noteID)
-	if err != nil {
+	if _, err := s.getNote(ctx, noteID, false); err != nil {
 		return nil, err
 	}
-	if exist == nil {
-		return nil, NewBizError(1002, "笔记不存在")
-	}
 
 	normalizedType, err := s.normalizeNoteTypeChoice(noteType)
 	if err != nil {
@@ -734,13 +953,13 @@ func (s *Service) UpdateNote(ctx context.Context, noteID int64, noteType, conten
 	}
 	normalizedContent := strings.TrimSpace(content)
 	if normalizedContent == "" {
-		return nil, NewBizError(1001, "笔记内容不能为空")
+		return nil, errcode.New(errcode.NoteContentEmpty)
 	}
-	normalizedWordIDs, err := s.normalizeWordIDs(ctx, wordIDs)
+	normalizedWordIDs, refWordIDs, err := s.noteWordLinks(ctx, normalizedContent, wordIDs)
 	if err != nil {
 		return nil, err
 	}
-	if err := s.noteRepo.Update(ctx, noteID, normalizedType, normalizedContent, normalizedWordIDs); err != nil {
+	if err := s.noteRepo.Update(ctx, noteID, normalizedType, normalizedContent, normalizedWordIDs, refWordIDs); err != nil {
 		return nil, err
 	}
 	return s.GetNoteDetail(ctx, noteID)
@@ -748,43 +967,56 @@ func (s *Service) UpdateNote(ctx context.Context, noteID int64, noteType, conten
 
 func (s *Service) GetNoteDetail(ctx context.Context, noteID int64) (*NoteDetail, error) {
 	if s.noteRepo == nil {
-		return nil, NewBizError(1, "笔记仓储未初始化")
-	}
-	if noteID <= 0 {
-		return nil, NewBizError(1001, "note_id 非法")
+		return nil, errcode.New(errcode.NoteRepoMissing)
 	}
-	note, err := s.noteRepo.GetByID(ctx, noteID)
+	note, err := s.getNote(ctx, noteID, false)
 	if err != nil {
 		return nil, err
 	}
-	if note == nil {
-		return nil, NewBizError(1002, "笔记不存在")
-	}
 	relations, err := s.noteRepo.ListWordRelationsByNoteID(ctx, noteID)
 	if err != nil {
 		return nil, err
 	}
 	wordIDs := make([]int64, 0, len(relations))
+	refWordIDs := make([]int64, 0)
 	for _, rel := range relations {
 		wordIDs = append(wordIDs, rel.WordID)
+		if rel.FromRef {
+			refWordIDs = append(refWordIDs, rel.WordID)
+		}
 	}
 	words, err := s.buildUnitWordItemsByWordIDs(ctx, wordIDs)
 	if err != nil {
 		return nil, err
 	}
+	rendered, err := markdown.Render(note.Content)
+	if err != nil {
+		return nil, err
+	}
 	return &NoteDetail{
-		ID:        note.ID,
-		Type:      note.NoteType,
-		Content:   note.Content,
-		CreatedAt: note.CreatedAt.Format(datetimeLayout),
-		UpdatedAt: note.UpdatedAt.Format(datetimeLayout),
-		Words:     words,
+		ID:         note.ID,
+		Type:       note.NoteType,
+		Content:    note.Content,
+		HTML:       rendered,
+		CreatedAt:  note.CreatedAt.Format(datetimeLayout),
+		UpdatedAt:  note.UpdatedAt.Format(datetimeLayout),
+		Words:      words,
+		RefWordIDs: refWordIDs,
 	}, nil
 }
 
 func (s *Service) ListNotes(ctx context.Context, page, pageSize int) ([]NoteListItem, int64, error) {
+	return s.listNotes(ctx, false, page, pageSize)
+}
+
+// ListTrashedNotes pages the notes in the trash, most recently trashed first.
+func (s *Service) ListTrashedNotes(ctx context.Context, page, pageSize int) ([]NoteListItem, int64, error) {
+	return s.listNotes(ctx, true, page, pageSize)
+}
+
+func (s *Service) listNotes(ctx context.Context, trashed bool, page, pageSize int) ([]NoteListItem, int64, error) {
 	if s.noteRepo == nil {
-		return nil, 0, NewBizError(1, "笔记仓储未初始化")
+		return nil, 0, errcode.New(errcode.NoteRepoMissing)
 	}
 	if page <= 0 {
 		page = 1
@@ -796,7 +1028,11 @@ func (s *Service) ListNotes(ctx context.Context, page, pageSize int) ([]NoteList
 		pageSize = 200
 	}
 	offset := (page - 1) * pageSize
-	rows, total, err := s.noteRepo.List(ctx, pageSize, offset)
+	list := s.noteRepo.List
+	if trashed {
+		list = s.noteRepo.ListTrashed
+	}
+	rows, total, err := list(ctx, pageSize, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -805,43 +1041,56 @@ func (s *Service) ListNotes(ctx context.Context, page, pageSize int) ([]NoteList
 	for _, row := range rows {
 		noteIDs = append(noteIDs, row.Note.ID)
 	}
-	relations, err := s.noteRepo.ListWordRelationsByNoteIDs(ctx, noteIDs)
+	wordTextsByNote, err := s.noteWordTexts(ctx, noteIDs)
 	if err != nil {
 		return nil, 0, err
 	}
+
+	ret := make([]NoteListItem, 0, len(rows))
+	for _, row := range rows {
+		item := NoteListItem{
+			ID:        row.Note.ID,
+			Type:      row.Note.NoteType,
+			Content:   row.Note.Content,
+			Words:     wordTextsByNote[row.Note.ID],
+			CreatedAt: row.Note.CreatedAt.Format(datetimeLayout),
+		}
+		if row.Note.DeletedAt != nil {
+			item.DeletedAt = row.Note.DeletedAt.Format(datetimeLayout)
+		}
+		ret = append(ret, item)
+	}
+	return ret, total, nil
+}
+
+// noteWordTexts returns the linked words of notes, keyed by note id.
+func (s *Service) noteWordTexts(ctx context.Context, noteIDs []int64) (map[int64][]string, error) {
+	relations, err := s.noteRepo.ListWordRelationsByNoteIDs(ctx, noteIDs)
+	if err != nil {
+		return nil, err
+	}
 	wordIDs := make([]int64, 0, len(relations))
 	for _, rel := range relations {
 		wordIDs = append(wordIDs, rel.WordID)
 	}
 	wordMap, err := s.wordRepo.GetByIDs(ctx, wordIDs)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	wordTextsByNote := make(map[int64][]string, len(rows))
+	ret := make(map[int64][]string, len(noteIDs))
 	for _, rel := range relations {
 		word := wordMap[rel.WordID]
 		if word == nil {
 			continue
 		}
-		wordTextsByNote[rel.NoteID] = append(wordTextsByNote[rel.NoteID], word.Word)
-	}
-
-	ret := make([]NoteListItem, 0, len(rows))
-	for _, row := range rows {
-		ret = append(ret, NoteListItem{
-			ID:        row.Note.ID,
-			Type:      row.Note.NoteType,
-			Content:   row.Note.Content,
-			Words:     wordTextsByNote[row.Note.ID],
-			CreatedAt: row.Note.CreatedAt.Format(datetimeLayout),
-		})
+		ret[rel.NoteID] = append(ret[rel.NoteID], word.Word)
 	}
-	return ret, total, nil
+	return ret, nil
 }
 
 func (s *Service) ListNotesByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]NoteTag, error) {
 	if s.noteRepo == nil {
-		return nil, NewBizError(1, "笔记仓储未初始化")
+		return nil, errcode.New(errcode.NoteRepoMissing)
 	}
 	normalizedWordIDs, err := s.normalizeWordIDsFast(wordIDs)
 	if err != nil {
@@ -891,14 +1140,14 @@ func (s *Service) listQuizSourceWords(
 		return words, "今日复习", 0, &reviewDateValue, nil
 	default:
 		if unitID <= 0 {
-			return nil, "", 0, nil, NewBizError(1001, "unit_id 非法")
+			return nil, "", 0, nil, errcode.New(errcode.InvalidParam, "unit_id")
 		}
 		unit, err := s.unitRepo.GetByID(ctx, unitID)
 		if err != nil {
 			return nil, "", 0, nil, err
 		}
 		if unit == nil {
-			return nil, "", 0, nil, NewBizError(1002, "单元不存在")
+			return nil, "", 0, nil, errcode.New(errcode.UnitNotFound)
 		}
 		words, err := s.GetDictationWords(ctx, unitID)
 		if err != nil {
@@ -916,7 +1165,7 @@ func normalizeQuizType(raw string) (string, error) {
 	case quizTypeSpelling, "spelling":
 		return quizTypeSpelling, nil
 	default:
-		return "", NewBizError(1001, "测验类型非法")
+		return "", errcode.New(errcode.QuizTypeInvalid)
 	}
 }
 
@@ -929,8 +1178,10 @@ func normalizeQuizSourceKind(raw string) (string, error) {
 		return quizSourceForgotten, nil
 	case quizSourceReview:
 		return quizSourceReview, nil
+	case quizSourceConfusable:
+		return quizSourceConfusable, nil
 	default:
-		return "", NewBizError(1001, "测验来源非法")
+		return "", errcode.New(errcode.QuizSourceInvalid)
 	}
 }
 
@@ -944,7 +1195,7 @@ func normalizeQuizResult(raw string) (string, error) {
 	case quizResultForgotten, "记住", "operated":
 		return quizResultForgotten, nil
 	default:
-		return "", NewBizError(1001, "测验结果非法")
+		return "", errcode.New(errcode.QuizResultInvalid)
 	}
 }
 
@@ -978,31 +1229,23 @@ func (s *Service) normalizeNoteTypeChoice(raw string) (string, error) {
 			return text, nil
 		}
 	}
-	return "", NewBizError(1001, "笔记类型非法")
+	return "", errcode.New(errcode.NoteTypeInvalid)
 }
 
 func (s *Service) normalizeWordIDs(ctx context.Context, wordIDs []int64) ([]int64, error) {
-	if len(wordIDs) == 0 {
-		return nil, NewBizError(1001, "关联单词不能为空")
+	ret, err := s.normalizeWordIDsFast(wordIDs)
+	if err != nil {
+		return nil, err
 	}
-	seen := make(map[int64]struct{}, len(wordIDs))
-	ret := make([]int64, 0, len(wordIDs))
-	for _, id := range wordIDs {
-		if id <= 0 {
-			return nil, NewBizError(1001, "word_id 非法")
-		}
-		if _, ok := seen[id]; ok {
-			continue
-		}
-		seen[id] = struct{}{}
-		ret = append(ret, id)
+	if len(ret) == 0 {
+		return ret, nil
 	}
 	wordMap, err := s.wordRepo.GetByIDs(ctx, ret)
 	if err != nil {
 		return nil, err
 	}
 	if len(wordMap) != len(ret) {
-		return nil, NewBizError(1002, "存在无效关联单词")
+		return nil, errcode.New(errcode.NoteWordsUnknown)
 	}
 	return ret, nil
 }
@@ -1012,7 +1255,7 @@ func (s *Service) normalizeWordIDsFast(wordIDs []int64) ([]int64, error) {
 	ret := make([]int64, 0, len(wordIDs))
 	for _, id := range wordIDs {
 		if id <= 0 {
-			return nil, NewBizError(1001, "word_id 非法")
+			return nil, errcode.New(errcode.InvalidParam, "word_id")
 		}
 		if _, ok := seen[id]; ok {
 			continue
@@ -1024,16 +1267,30 @@ func (s *Service) normalizeWordIDsFast(wordIDs []int64) ([]int64, error) {
 }
 
 func normalizeWord(rawWord string) (string, error) {
-	word := strings.ToLower(strings.TrimSpace(rawWord))
+	word := fetcher.NormalizeWord(rawWord)
 	if word == "" {
-		return "", NewBizError(1001, "单词不能为空")
+		return "", errcode.New(errcode.WordEmpty)
 	}
 	if !validWord.MatchString(word) {
-		return "", NewBizError(1001, "单词格式非法，仅支持英文字母/单引号/短横线")
+		return "", errcode.New(errcode.WordInvalid)
 	}
 	return word, nil
 }
 
+// answerMatches compares a quiz answer with the expected word, ignoring case
+// and differences in whitespace.
+func answerMatches(expected, input string) bool {
+	want := fetcher.NormalizeWord(expected)
+	return want != "" && want == fetcher.NormalizeWord(input)
+}
+
+func normalizeForgottenPolicy(raw ForgottenPolicy) ForgottenPolicy {
+	if raw.RememberAfterCorrect < 0 {
+		raw.RememberAfterCorrect = 0
+	}
+	return raw
+}
+
 func normalizeAccent(raw string) string {
 	switch strings.ToLower(strings.TrimSpace(raw)) {
 	case "am":
@@ -1129,7 +1386,7 @@ func parseOptionalDate(raw string) (*time.Time, error) {
 	}
 	t, err := time.ParseInLocation("2006-01-02", text, time.Local)
 	if err != nil {
-		return nil, NewBizError(1001, "背诵时间格式错误，需为 yyyy-mm-dd")
+		return nil, errcode.New(errcode.InvalidReciteDate)
 	}
 	value := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
 	return &value, nil
@@ -1143,7 +1400,7 @@ func parseReviewDate(raw string) (time.Time, error) {
 	}
 	t, err := time.ParseInLocation("2006-01-02", text, time.Local)
 	if err != nil {
-		return time.Time{}, NewBizError(1001, "日期格式错误，需为 yyyy-mm-dd")
+		return time.Time{}, errcode.New(errcode.InvalidDate)
 	}
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
 }
@@ -1187,23 +1444,27 @@ func buildReviewUnitSummary(
 }
 
 func buildWordInfo(row *entity.Word) WordInfo {
-	parts := make([]WordPart, 0, len(row.Parts))
-	for _, part := range row.Parts {
-		parts = append(parts, WordPart{Part: part.Part, Means: part.Means})
+	parts := make([]WordPart, 0, len(row.Parts)+len(row.CustomParts))
+	if row.CustomMode != wordCustomModeOverride || len(row.CustomParts) == 0 {
+		parts = append(parts, toWordParts(row.Parts)...)
+	}
+	if row.CustomMode != "" {
+		parts = append(parts, toWordParts(row.CustomParts)...)
 	}
 	sentenceGroups := make([]WordSentenceGroup, 0, len(row.SentenceGroups))
 	for _, group := range row.SentenceGroups {
 		sentences := make([]WordSentence, 0, len(group.Sentences))
 		for _, sentence := range group.Sentences {
 			sentences = append(sentences, WordSentence{
-				ID:      sentence.ID,
-				Type:    sentence.Type,
-				EN:      sentence.EN,
-				CN:      sentence.CN,
-				From:    sentence.From,
-				TTSURL:  sentence.TTSURL,
-				TTSSize: sentence.TTSSize,
-				LikeNum: sentence.LikeNum,
+				ID:           sentence.ID,
+				Type:         sentence.Type,
+				EN:           sentence.EN,
+				CN:           sentence.CN,
+				From:         sentence.From,
+				TTSURL:       sentenceTTSURL(sentence),
+				TTSSize:      sentence.TTSSize,
+				LikeNum:      sentence.LikeNum,
+				TTSSynthetic: sentence.Synthetic,
 			})
 		}
 		sentenceGroups = append(sentenceGroups, WordSentenceGroup{
@@ -1214,42 +1475,53 @@ func buildWordInfo(row *entity.Word) WordInfo {
 		})
 	}
 	return WordInfo{
-		ID:             row.ID,
-		Word:           row.Word,
-		PhEn:           row.PhEn,
-		PhAm:           row.PhAm,
-		MeanTag:        row.MeanTag,
-		EnAudioURL:     buildAudioURL(row.Word, "en"),
-		AmAudioURL:     buildAudioURL(row.Word, "am"),
-		Parts:          parts,
-		SentenceGroups: sentenceGroups,
+		ID:               row.ID,
+		Word:             row.Word,
+		PhEn:             row.PhEn,
+		PhAm:             row.PhAm,
+		MeanTag:          row.MeanTag,
+		EnAudioURL:       buildAudioURL(row.Word, "en"),
+		AmAudioURL:       buildAudioURL(row.Word, "am"),
+		Parts:            parts,
+		SentenceGroups:   sentenceGroups,
+		Mnemonic:         row.Mnemonic,
+		CustomParts:      toWordParts(row.CustomParts),
+		CustomMode:       row.CustomMode,
+		Edited:           row.Origin != nil,
+		EnAudioSynthetic: containsString(row.SyntheticAccents, "en"),
+		AmAudioSynthetic: containsString(row.SyntheticAccents, "am"),
+	}
+}
+
+func toWordParts(rows []entity.WordPart) []WordPart {
+	ret := make([]WordPart, 0, len(rows))
+	for _, part := range rows {
+		ret = append(ret, WordPart{Part: part.Part, Means: part.Means})
 	}
+	return ret
 }
 
 func buildUnitWordItem(row *entity.Word, seq int) UnitWordItem {
 	wordInfo := buildWordInfo(row)
 	return UnitWordItem{
-		Seq:            seq,
-		WordID:         row.ID,
-		Word:           wordInfo.Word,
-		PhEn:           wordInfo.PhEn,
-		PhAm:           wordInfo.PhAm,
-		MeanTag:        wordInfo.MeanTag,
-		EnAudio:        wordInfo.EnAudioURL,
-		AmAudio:        wordInfo.AmAudioURL,
-		Parts:          wordInfo.Parts,
-		SentenceGroups: wordInfo.SentenceGroups,
+		Seq:              seq,
+		WordID:           row.ID,
+		Word:             wordInfo.Word,
+		PhEn:             wordInfo.PhEn,
+		PhAm:             wordInfo.PhAm,
+		MeanTag:          wordInfo.MeanTag,
+		EnAudio:          wordInfo.EnAudioURL,
+		AmAudio:          wordInfo.AmAudioURL,
+		Parts:            wordInfo.Parts,
+		SentenceGroups:   wordInfo.SentenceGroups,
+		Mnemonic:         wordInfo.Mnemonic,
+		EnAudioSynthetic: wordInfo.EnAudioSynthetic,
+		AmAudioSynthetic: wordInfo.AmAudioSynthetic,
 	}
 }
 
+// buildAudioURL returns the /word_mp3 path of a word's audio. The route serves
+// it from the audio store, proxied or redirected to a presigned URL.
 func buildAudioURL(word, prefix string) string {
-	return "/word_mp3/" + prefix + "/" + buildWordPrefix(word) + "/" + word + ".mp3"
-}
-
-func buildWordPrefix(word string) string {
-	runes := []rune(word)
-	if len(runes) < 2 {
-		return word
-	}
-	return string(runes[:2])
+	return fetcher.AudioURL(fetcher.WordAudioKey(prefix, word))
 }
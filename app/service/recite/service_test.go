@@ -0,0 +1,1100 @@
+package recite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/infra/recite/repository/memory"
+	"github.com/wutianfang/moss/infra/storage"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+type testEnv struct {
+	svc     *Service
+	store   *memory.Store
+	fetcher *fixtureFetcher
+}
+
+func newTestEnv(t *testing.T, policy ForgottenPolicy) *testEnv {
+	t.Helper()
+	store := memory.NewStore()
+	wordFetcher := newFixtureFetcher()
+	svc := NewService(
+		memory.NewWordRepository(store),
+		memory.NewUnitRepository(store),
+		memory.NewUnitWordRepository(store),
+		memory.NewForgottenWordRepository(store),
+		memory.NewQuizRepository(store),
+		memory.NewNoteRepository(store),
+		memory.NewWordFormRepository(store),
+		wordFetcher,
+		nil,
+		nil,
+		"en",
+		[]int{1, 2, 4, 7},
+		nil,
+		policy,
+	)
+	t.Cleanup(svc.background.Wait)
+	return &testEnv{svc: svc, store: store, fetcher: wordFetcher}
+}
+
+func (e *testEnv) createUnit(t *testing.T, name, reciteDate string, words ...string) int64 {
+	t.Helper()
+	ctx := context.Background()
+	unit, err := e.svc.CreateUnit(ctx, name, reciteDate)
+	if err != nil {
+		t.Fatalf("CreateUnit(%q) error: %v", name, err)
+	}
+	for _, word := range words {
+		if err := e.svc.AddWordToUnit(ctx, unit.ID, word, false); err != nil {
+			t.Fatalf("AddWordToUnit(%q) error: %v", word, err)
+		}
+	}
+	return unit.ID
+}
+
+func (e *testEnv) wordID(t *testing.T, word string) int64 {
+	t.Helper()
+	info, err := e.svc.QueryWord(context.Background(), word)
+	if err != nil {
+		t.Fatalf("QueryWord(%q) error: %v", word, err)
+	}
+	return info.ID
+}
+
+// errKey returns the catalogue key of err, "" when err is not an API error.
+func errKey(err error) string {
+	var apiErr *errcode.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code.Key
+	}
+	return ""
+}
+
+// seqOf returns the quiz position of word; unit quizzes are shuffled.
+func seqOf(t *testing.T, detail *QuizDetail, word string) int {
+	t.Helper()
+	for _, item := range detail.Words {
+		if item.WordDetail.Word == word {
+			return item.Seq
+		}
+	}
+	t.Fatalf("word %q not in quiz", word)
+	return 0
+}
+
+func TestQueryWordCachesFetchedWord(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+
+	first, err := env.svc.QueryWord(ctx, " Abandon ")
+	if err != nil {
+		t.Fatalf("QueryWord error: %v", err)
+	}
+	second, err := env.svc.QueryWord(ctx, "abandon")
+	if err != nil {
+		t.Fatalf("QueryWord error: %v", err)
+	}
+	if first.ID != second.ID || first.Word != "abandon" {
+		t.Fatalf("got ids %d/%d word %q, want one cached abandon", first.ID, second.ID, first.Word)
+	}
+	if got := env.fetcher.fetchCount(); got != 1 {
+		t.Fatalf("fetch count = %d, want 1", got)
+	}
+	if len(first.SentenceGroups) != 2 || first.EnAudioURL == "" {
+		t.Fatalf("unexpected word info %+v", first)
+	}
+
+	if _, err := env.svc.QueryWord(ctx, "nonexistent"); errKey(err) != "word_unknown" {
+		t.Fatalf("QueryWord(nonexistent) error = %v, want word_unknown", err)
+	}
+}
+
+func TestQueryWordsBatch(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	env.wordID(t, "abandon")
+	env.svc.background.Wait()
+	ensured := env.fetcher.ensureCount("abandon")
+
+	items, err := env.svc.QueryWords(ctx, []string{"abandon", " Desert", "desert", "x1", "nonexistent"}, false)
+	if err != nil {
+		t.Fatalf("QueryWords error: %v", err)
+	}
+	want := []struct{ status, key string }{
+		{wordBatchCached, ""},
+		{wordBatchFetched, ""},
+		{wordBatchFetched, ""},
+		{wordBatchInvalid, "word_invalid"},
+		{wordBatchFailed, "word_unknown"},
+	}
+	for i, w := range want {
+		if items[i].Status != w.status || errKey(items[i].Err) != w.key {
+			t.Errorf("item %d (%q) = %s/%v, want %s/%s", i, items[i].Query, items[i].Status, items[i].Err, w.status, w.key)
+		}
+	}
+	if items[1].Word == nil || items[1].Word.Word != "desert" || items[2].Word != items[1].Word {
+		t.Fatalf("duplicate queries should share one entry, got %+v and %+v", items[1].Word, items[2].Word)
+	}
+	// abandon was cached, desert fetched once for both queries
+	if got := env.fetcher.fetchCount(); got != 3 {
+		t.Fatalf("fetch count = %d, want 3", got)
+	}
+	// a cached hit repairs its audio like a single lookup
+	if got := env.fetcher.ensureCount("abandon"); got != ensured+1 {
+		t.Fatalf("abandon audio checks = %d, want %d", got, ensured+1)
+	}
+
+	if _, err := env.svc.QueryWords(ctx, nil, false); errKey(err) != "word_batch_empty" {
+		t.Fatalf("QueryWords(nil) error = %v, want word_batch_empty", err)
+	}
+	if _, err := env.svc.QueryWords(ctx, make([]string, maxWordBatch+1), false); errKey(err) != "word_batch_too_large" {
+		t.Fatalf("QueryWords(too many) error = %v, want word_batch_too_large", err)
+	}
+}
+
+func TestQueryWordsBatchLemma(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	env.wordID(t, "desert")
+
+	items, err := env.svc.QueryWords(ctx, []string{"abandoned", "deserts", "gave up", "desert"}, true)
+	if err != nil {
+		t.Fatalf("QueryWords error: %v", err)
+	}
+	want := []string{"abandon", "desert", "give up", "desert"}
+	for i, word := range want {
+		if items[i].Word == nil || items[i].Word.Word != word {
+			t.Fatalf("item %d (%q) = %+v, want %s", i, items[i].Query, items[i].Word, word)
+		}
+	}
+	if forms := items[1].Word.Forms; !containsString(forms, "deserts") {
+		t.Fatalf("desert forms = %v, want deserts recorded", forms)
+	}
+
+	// the recorded form now resolves without guessing
+	lemmas, err := env.svc.resolveLemmas(ctx, []string{"deserts", "abandoned", "abandon", "news"})
+	if err != nil {
+		t.Fatalf("resolveLemmas error: %v", err)
+	}
+	if len(lemmas) != 2 || lemmas["deserts"] != "desert" || lemmas["abandoned"] != "abandon" {
+		t.Fatalf("lemmas = %v", lemmas)
+	}
+}
+
+func TestSearchWords(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	env.createUnit(t, "unit", "", "forsake")
+	env.wordID(t, "abandon")
+	env.wordID(t, "give up")
+	env.wordID(t, "desert")
+
+	cases := []struct {
+		req  WordSearchRequest
+		want []string
+	}{
+		{WordSearchRequest{Query: "  FOR"}, []string{"forsake"}},
+		{WordSearchRequest{Query: "e", Mode: "substring"}, []string{"desert", "give up", "forsake"}},
+		// meaning matches come shortest first, then in word order
+		{WordSearchRequest{Query: "放弃"}, []string{"abandon", "forsake", "give up"}},
+		{WordSearchRequest{Query: "放弃", InUnit: true}, []string{"forsake"}},
+		{WordSearchRequest{Query: "弃", Limit: 2}, []string{"desert", "abandon"}},
+	}
+	for _, c := range cases {
+		items, err := env.svc.SearchWords(ctx, c.req)
+		if err != nil {
+			t.Fatalf("SearchWords(%+v) error: %v", c.req, err)
+		}
+		got := make([]string, 0, len(items))
+		for _, item := range items {
+			got = append(got, item.Word)
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("SearchWords(%+v) = %v, want %v", c.req, got, c.want)
+		}
+	}
+
+	if _, err := env.svc.SearchWords(ctx, WordSearchRequest{Query: " "}); errKey(err) != "word_search_query_empty" {
+		t.Fatalf("empty query error = %v, want word_search_query_empty", err)
+	}
+	if _, err := env.svc.SearchWords(ctx, WordSearchRequest{Query: "a", Mode: "fuzzy"}); errKey(err) != "word_search_mode_invalid" {
+		t.Fatalf("fuzzy mode error = %v, want word_search_mode_invalid", err)
+	}
+}
+
+func TestUpdateWordSentenceAudioURL(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	wordID := env.wordID(t, "abandon")
+	const dictURL = "https://dict-tts.example.com/tts/1201.mp3"
+	row, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	row.SentenceGroups[0].Sentences[0].TTSURL = dictURL
+	if err := env.svc.wordRepo.Update(ctx, row); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+
+	info, err := env.svc.GetWord(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetWord error: %v", err)
+	}
+	groups := info.SentenceGroups
+	groups[0].Sentences[0].CN = "他们不得不弃车。"
+	info, err = env.svc.UpdateWord(ctx, wordID, UpdateWordRequest{SentenceGroups: &groups})
+	if err != nil {
+		t.Fatalf("UpdateWord(dictionary url) error: %v", err)
+	}
+	if got := info.SentenceGroups[0].Sentences[0].TTSURL; got != dictURL {
+		t.Fatalf("ttsUrl = %q, want the dictionary url kept", got)
+	}
+
+	for _, url := range []string{"http://169.254.169.254/latest/meta-data", "https://dict-tts.example.com/tts/other.mp3", "file:///etc/passwd"} {
+		groups[0].Sentences[0].TTSURL = url
+		if _, err := env.svc.UpdateWord(ctx, wordID, UpdateWordRequest{SentenceGroups: &groups}); errKey(err) != errcode.SentenceAudioURLInvalid.Key {
+			t.Errorf("UpdateWord(ttsUrl %q) error = %v, want sentence_audio_url_invalid", url, err)
+		}
+	}
+}
+
+// testMP3 is a single silent MPEG-1 layer III frame.
+func testMP3() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x64})
+	return frame
+}
+
+// TestCacheSentenceAudioKeepsEdits caches sentence audio for a word loaded
+// before the user edited it; only the local url may be written back.
+func TestCacheSentenceAudioKeepsEdits(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	wordID := env.wordID(t, "abandon")
+	row, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	sentence := &row.SentenceGroups[0].Sentences[0]
+	sentence.TTSURL = "https://dict-tts.example.com/tts/1201.mp3"
+	if err := env.svc.wordRepo.Update(ctx, row); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	key := fetcher.SentenceAudioKey(fetcher.SentenceAudioName(sentence.ID, sentence.EN))
+	if err := env.svc.audioLibrary.Put(ctx, key, testMP3()); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+
+	stale, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	info, err := env.svc.GetWord(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetWord error: %v", err)
+	}
+	groups := info.SentenceGroups
+	groups[0].Sentences[0].CN = "他们不得不弃车。"
+	if _, err := env.svc.UpdateWord(ctx, wordID, UpdateWordRequest{SentenceGroups: &groups}); err != nil {
+		t.Fatalf("UpdateWord error: %v", err)
+	}
+
+	env.svc.cacheSentenceAudio(ctx, stale)
+	saved, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	got := saved.SentenceGroups[0].Sentences[0]
+	if got.CN != "他们不得不弃车。" {
+		t.Fatalf("cn = %q, the edit was overwritten", got.CN)
+	}
+	if got.LocalTTSURL != fetcher.AudioURL(key) {
+		t.Fatalf("localTtsUrl = %q, want %q", got.LocalTTSURL, fetcher.AudioURL(key))
+	}
+}
+
+// TestCheckAudioSkipsNoAudio repairs a word the dictionary has no american
+// audio for: the file is reported unavailable once, then no longer missing.
+func TestCheckAudioSkipsNoAudio(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	env.wordID(t, "abandon")
+	env.svc.background.Wait()
+	env.fetcher.noAudio = map[string][]string{"abandon": {"am"}}
+	if err := env.svc.audioLibrary.Put(ctx, fetcher.WordAudioKey("en", "abandon"), testMP3()); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+
+	report, err := env.svc.CheckAudio(ctx, false)
+	if err != nil {
+		t.Fatalf("CheckAudio error: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Problem != fetcher.AudioProblemMissing {
+		t.Fatalf("issues = %+v, want the american audio missing", report.Issues)
+	}
+
+	report, err = env.svc.CheckAudio(ctx, true)
+	if err != nil {
+		t.Fatalf("CheckAudio repair error: %v", err)
+	}
+	if report.Unavailable != 1 || report.Failed != 0 || report.Issues[0].Action != audioActionUnavailable {
+		t.Fatalf("report = %+v, want the american audio unavailable", report)
+	}
+
+	report, err = env.svc.CheckAudio(ctx, false)
+	if err != nil {
+		t.Fatalf("CheckAudio error: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("issues = %+v, want none once the missing audio is known", report.Issues)
+	}
+}
+
+// TestCheckAudioSentences checks the sentence audio of a word: a broken and a
+// missing file are repaired and a file no sentence uses is removed.
+func TestCheckAudioSentences(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	store := storage.NewLocalStore(t.TempDir())
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(store)
+	env.fetcher.library = env.svc.audioLibrary
+	ctx := context.Background()
+	for _, accent := range wordAudioAccents {
+		if err := store.Put(ctx, fetcher.WordAudioKey(accent, "abandon"), testMP3()); err != nil {
+			t.Fatalf("Put error: %v", err)
+		}
+	}
+	env.wordID(t, "abandon")
+	env.svc.background.Wait()
+
+	broken := fetcher.SentenceAudioKey("2791054")
+	missing := fetcher.SentenceAudioKey("2791101")
+	orphan := fetcher.SentenceAudioKey("999")
+	if err := store.Put(ctx, broken, []byte("oops")); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if err := store.Delete(ctx, missing); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if err := store.Put(ctx, orphan, testMP3()); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+
+	report, err := env.svc.CheckAudio(ctx, false)
+	if err != nil {
+		t.Fatalf("CheckAudio error: %v", err)
+	}
+	got := make(map[string]string)
+	for _, issue := range report.Issues {
+		got[issue.Key] = issue.Problem
+	}
+	want := map[string]string{
+		broken:  fetcher.AudioProblemTruncated,
+		missing: fetcher.AudioProblemMissing,
+		orphan:  fetcher.AudioProblemOrphan,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("issues = %v, want %v", got, want)
+	}
+
+	report, err = env.svc.CheckAudio(ctx, true)
+	if err != nil {
+		t.Fatalf("CheckAudio repair error: %v", err)
+	}
+	if report.Repaired != 2 || report.Removed != 1 || report.Failed != 0 {
+		t.Fatalf("report = %+v, want two sentences repaired and one removed", report)
+	}
+	report, err = env.svc.CheckAudio(ctx, false)
+	if err != nil {
+		t.Fatalf("CheckAudio error: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("issues = %+v, want none after the repair", report.Issues)
+	}
+}
+
+// fakeAudioProvider synthesizes test audio and records what it was asked for.
+type fakeAudioProvider struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (p *fakeAudioProvider) Name() string    { return "fake" }
+func (p *fakeAudioProvider) Synthetic() bool { return true }
+
+func (p *fakeAudioProvider) Generate(ctx context.Context, text, accent string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, accent+":"+text)
+	return testMP3(), nil
+}
+
+func (p *fakeAudioProvider) callList() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+// TestEnsureWordAudioKnownNoAudio queries a cached word whose american audio
+// the dictionary lacks: once that is known the site isn't asked again, and
+// only the missing accent is synthesized.
+func TestEnsureWordAudioKnownNoAudio(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	env.fetcher.noAudio = map[string][]string{"abandon": {"am"}}
+	ctx := context.Background()
+	if err := env.svc.audioLibrary.Put(ctx, fetcher.WordAudioKey("en", "abandon"), testMP3()); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	env.wordID(t, "abandon")
+	if got := env.fetcher.ensureCount("abandon"); got != 1 {
+		t.Fatalf("audio checked %d times on the first query, want 1", got)
+	}
+
+	env.wordID(t, "abandon")
+	if got := env.fetcher.ensureCount("abandon"); got != 1 {
+		t.Fatalf("audio checked %d times, want the known missing accent not asked for again", got)
+	}
+
+	provider := &fakeAudioProvider{}
+	env.svc.audioProvider = provider
+	info, err := env.svc.QueryWord(ctx, "abandon")
+	if err != nil {
+		t.Fatalf("QueryWord error: %v", err)
+	}
+	var wordCalls []string
+	for _, call := range provider.callList() {
+		if strings.HasSuffix(call, ":abandon") {
+			wordCalls = append(wordCalls, call)
+		}
+	}
+	if !reflect.DeepEqual(wordCalls, []string{"am:abandon"}) {
+		t.Fatalf("synthesized word audio %v, want only the american one", wordCalls)
+	}
+	if env.fetcher.ensureCount("abandon") != 1 || !info.AmAudioSynthetic || info.EnAudioSynthetic {
+		t.Fatalf("ensured %d times, synthetic en=%v am=%v", env.fetcher.ensureCount("abandon"), info.EnAudioSynthetic, info.AmAudioSynthetic)
+	}
+}
+
+// TestEnsureWordAudioSentenceLimit synthesizes the example sentences of a
+// query only up to querySentenceSynthesis; the rest follow on later queries.
+func TestEnsureWordAudioSentenceLimit(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	ctx := context.Background()
+	wordID := env.wordID(t, "abandon")
+	row, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	group := &row.SentenceGroups[0]
+	// sentences the user added have no dictionary audio
+	for i := 1; i <= 3; i++ {
+		group.Sentences = append(group.Sentences, entity.WordSentence{ID: 9000 + i, EN: fmt.Sprintf("Sentence %d.", i)})
+	}
+	if err := env.svc.wordRepo.Update(ctx, row); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+
+	provider := &fakeAudioProvider{}
+	env.svc.audioProvider = provider
+	sentenceCalls := func() int {
+		count := 0
+		for _, call := range provider.callList() {
+			if call != "en:abandon" && call != "am:abandon" {
+				count++
+			}
+		}
+		return count
+	}
+	env.wordID(t, "abandon")
+	if got := sentenceCalls(); got != querySentenceSynthesis {
+		t.Fatalf("synthesized %d sentences in one query, want %d", got, querySentenceSynthesis)
+	}
+	env.wordID(t, "abandon")
+	if got := sentenceCalls(); got != 3 {
+		t.Fatalf("synthesized %d sentences after two queries, want 3", got)
+	}
+	saved, err := env.svc.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		t.Fatalf("GetByID error: %v", err)
+	}
+	for _, sentence := range saved.SentenceGroups[0].Sentences {
+		if sentence.TTSURL != "" {
+			continue
+		}
+		if sentence.LocalTTSURL == "" || !sentence.Synthetic {
+			t.Fatalf("sentence %d = %+v, want synthesized audio", sentence.ID, sentence)
+		}
+	}
+}
+
+func TestStartAudioRepair(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	if _, err := env.svc.StartAudioRepair(ctx); errKey(err) != "audio_check_missing" {
+		t.Fatalf("StartAudioRepair without a library error = %v, want audio_check_missing", err)
+	}
+
+	env.svc.audioLibrary = fetcher.NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	env.wordID(t, "abandon")
+	env.svc.background.Wait()
+	job, err := env.svc.StartAudioRepair(ctx)
+	if err != nil {
+		t.Fatalf("StartAudioRepair error: %v", err)
+	}
+	if !job.Running || job.StartedAt == "" {
+		t.Fatalf("job = %+v, want it running", job)
+	}
+	env.svc.background.Wait()
+	job = env.svc.AudioRepairStatus()
+	if job.Running || job.FinishedAt == "" || job.Report == nil || job.Error != "" {
+		t.Fatalf("job = %+v, want it finished with a report", job)
+	}
+	if job.Report.WordCount != 1 || job.Report.Failed != 2 {
+		t.Fatalf("report = %+v, want both accents of abandon failed", job.Report)
+	}
+
+	env.svc.audioRepair.Running = true
+	if _, err := env.svc.StartAudioRepair(ctx); errKey(err) != "audio_repair_running" {
+		t.Fatalf("StartAudioRepair while running error = %v, want audio_repair_running", err)
+	}
+}
+
+func TestQuizLifecycle(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{AddOnForgotten: true, AddOnWrong: true})
+	ctx := context.Background()
+	unitID := env.createUnit(t, "Unit 1", "", "abandon", "desert", "give up")
+
+	detail, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "dictation", SourceKind: "unit", UnitID: unitID})
+	if err != nil {
+		t.Fatalf("StartQuiz error: %v", err)
+	}
+	quizID := detail.Quiz.ID
+	if detail.Quiz.Status != quizStatusRunning || detail.Quiz.Type != quizTypeDictation {
+		t.Fatalf("unexpected quiz %+v", detail.Quiz)
+	}
+	if detail.Quiz.Stats.Total != 3 || detail.Quiz.NextSeq != 1 {
+		t.Fatalf("stats = %+v next = %d, want 3 words from seq 1", detail.Quiz.Stats, detail.Quiz.NextSeq)
+	}
+	running, err := env.svc.HasRunningQuiz(ctx)
+	if err != nil || !running {
+		t.Fatalf("HasRunningQuiz = %v, %v; want true", running, err)
+	}
+
+	// The client marked "give  up" wrong; the server regrades it.
+	result, err := env.svc.SubmitQuizWord(ctx, quizID, seqOf(t, detail, "give up"), "give  up", quizResultWrong)
+	if err != nil || result != quizResultCorrect {
+		t.Fatalf("SubmitQuizWord(give up) = %q, %v; want regraded to correct", result, err)
+	}
+	result, err = env.svc.SubmitQuizWord(ctx, quizID, seqOf(t, detail, "abandon"), "abandom", quizResultWrong)
+	if err != nil || result != quizResultWrong {
+		t.Fatalf("SubmitQuizWord(abandon) = %q, %v; want wrong", result, err)
+	}
+	result, err = env.svc.SubmitQuizWord(ctx, quizID, seqOf(t, detail, "desert"), "", "operated")
+	if err != nil || result != quizResultForgotten {
+		t.Fatalf("SubmitQuizWord(desert) = %q, %v; want forgotten", result, err)
+	}
+	if _, err := env.svc.SubmitQuizWord(ctx, quizID, 99, "x", quizResultCorrect); errKey(err) != "quiz_word_not_found" {
+		t.Fatalf("SubmitQuizWord(seq 99) error = %v, want quiz_word_not_found", err)
+	}
+
+	forgotten, err := env.svc.ListForgottenWords(ctx)
+	if err != nil {
+		t.Fatalf("ListForgottenWords error: %v", err)
+	}
+	if len(forgotten) != 2 {
+		t.Fatalf("forgotten words = %d, want abandon and desert", len(forgotten))
+	}
+
+	finished, err := env.svc.FinishQuiz(ctx, quizID)
+	if err != nil {
+		t.Fatalf("FinishQuiz error: %v", err)
+	}
+	want := QuizStats{Total: 3, Tested: 3, Correct: 1, Wrong: 1, Forgotten: 1}
+	if finished.Quiz.Status != quizStatusFinished || finished.Quiz.Stats != want || finished.Quiz.NextSeq != 0 {
+		t.Fatalf("finished quiz = %+v, want stats %+v", finished.Quiz, want)
+	}
+	if _, err := env.svc.SubmitQuizWord(ctx, quizID, 1, "abandon", quizResultCorrect); errKey(err) != "quiz_finished" {
+		t.Fatalf("SubmitQuizWord after finish error = %v, want quiz_finished", err)
+	}
+
+	items, total, hasRunning, err := env.svc.ListQuizzes(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("ListQuizzes error: %v", err)
+	}
+	if total != 1 || hasRunning || len(items) != 1 || items[0].Stats != want {
+		t.Fatalf("ListQuizzes = %+v total=%d running=%v", items, total, hasRunning)
+	}
+}
+
+func TestConfusableQuiz(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+	forsakeID := env.wordID(t, "forsake")
+	giveUpID := env.wordID(t, "give up")
+	for _, note := range []struct {
+		noteType string
+		wordIDs  []int64
+	}{
+		{"近义词", []int64{abandonID, desertID}},
+		{"近义词", []int64{abandonID, forsakeID}},
+		{"反义词", []int64{giveUpID, forsakeID}},
+		{"关联词跟", []int64{giveUpID}},
+	} {
+		if _, err := env.svc.CreateNote(ctx, note.noteType, "note", note.wordIDs); err != nil {
+			t.Fatalf("CreateNote error: %v", err)
+		}
+	}
+
+	detail, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "默写", SourceKind: "confusable", NoteType: "近义词"})
+	if err != nil {
+		t.Fatalf("StartQuiz error: %v", err)
+	}
+	wantChoices := map[string]int{"abandon": 3, "desert": 2, "forsake": 2}
+	if len(detail.Words) != len(wantChoices) {
+		t.Fatalf("StartQuiz words = %d, want %d", len(detail.Words), len(wantChoices))
+	}
+	for _, item := range detail.Words {
+		word := item.WordDetail.Word
+		found := false
+		for _, choice := range item.Choices {
+			found = found || choice.Word == word
+		}
+		if len(item.Choices) != wantChoices[word] || !found {
+			t.Fatalf("choices of %s = %+v, want %d including the word", word, item.Choices, wantChoices[word])
+		}
+	}
+
+	quizID := detail.Quiz.ID
+	seq := seqOf(t, detail, "desert")
+	result, err := env.svc.SubmitQuizWord(ctx, quizID, seq, "abandon", quizResultCorrect)
+	if err != nil || result != quizResultWrong {
+		t.Fatalf("SubmitQuizWord(other choice) = %q, %v; want graded wrong", result, err)
+	}
+	seq = seqOf(t, detail, "abandon")
+	if result, err := env.svc.SubmitQuizWord(ctx, quizID, seq, " Abandon ", quizResultWrong); err != nil || result != quizResultCorrect {
+		t.Fatalf("SubmitQuizWord(typed answer) = %q, %v; want graded correct", result, err)
+	}
+	seq = seqOf(t, detail, "forsake")
+	if result, err := env.svc.SubmitQuizWord(ctx, quizID, seq, "", quizResultForgotten); err != nil || result != quizResultForgotten {
+		t.Fatalf("SubmitQuizWord(forgotten) = %q, %v; want forgotten kept", result, err)
+	}
+
+	if _, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "默写", SourceKind: "confusable", NoteType: "关联词跟"}); errKey(err) != "quiz_no_confusables" {
+		t.Fatalf("StartQuiz(single word notes) error = %v, want quiz_no_confusables", err)
+	}
+	if _, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "默写", SourceKind: "confusable"}); errKey(err) != "invalid_param" {
+		t.Fatalf("StartQuiz(no source) error = %v, want invalid_param", err)
+	}
+}
+
+func TestForgottenQuizRemembersAfterCorrectStreak(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{RememberAfterCorrect: 2})
+	ctx := context.Background()
+	if err := env.svc.AddForgottenWord(ctx, "forsake"); err != nil {
+		t.Fatalf("AddForgottenWord error: %v", err)
+	}
+
+	for round := 1; round <= 2; round++ {
+		detail, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "spelling", SourceKind: "forgotten"})
+		if err != nil {
+			t.Fatalf("round %d StartQuiz error: %v", round, err)
+		}
+		if _, err := env.svc.SubmitQuizWord(ctx, detail.Quiz.ID, 1, "forsake", quizResultCorrect); err != nil {
+			t.Fatalf("round %d SubmitQuizWord error: %v", round, err)
+		}
+		if _, err := env.svc.FinishQuiz(ctx, detail.Quiz.ID); err != nil {
+			t.Fatalf("round %d FinishQuiz error: %v", round, err)
+		}
+	}
+
+	forgotten, err := env.svc.ListForgottenWords(ctx)
+	if err != nil {
+		t.Fatalf("ListForgottenWords error: %v", err)
+	}
+	if len(forgotten) != 0 {
+		t.Fatalf("forgotten words = %+v, want forsake remembered", forgotten)
+	}
+	if _, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "spelling", SourceKind: "forgotten"}); errKey(err) != "quiz_no_words" {
+		t.Fatalf("StartQuiz with empty source error = %v, want quiz_no_words", err)
+	}
+}
+
+// failingForgottenRepo fails every write to the forgotten list.
+type failingForgottenRepo struct {
+	ForgottenWordRepository
+}
+
+func (failingForgottenRepo) Add(ctx context.Context, word string) error {
+	return errors.New("forgotten list down")
+}
+
+func TestForgottenPolicyFailureKeepsAnswer(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{AddOnWrong: true})
+	env.svc.forgottenRepo = failingForgottenRepo{env.svc.forgottenRepo}
+	ctx := context.Background()
+	unitID := env.createUnit(t, "Unit 1", "", "abandon")
+
+	detail, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "spelling", SourceKind: "unit", UnitID: unitID})
+	if err != nil {
+		t.Fatalf("StartQuiz error: %v", err)
+	}
+	result, err := env.svc.SubmitQuizWord(ctx, detail.Quiz.ID, 1, "abandom", quizResultWrong)
+	if err != nil || result != quizResultWrong {
+		t.Fatalf("SubmitQuizWord = %q, %v; want wrong recorded despite the policy failure", result, err)
+	}
+	finished, err := env.svc.FinishQuiz(ctx, detail.Quiz.ID)
+	if err != nil {
+		t.Fatalf("FinishQuiz error: %v", err)
+	}
+	if want := (QuizStats{Total: 1, Tested: 1, Wrong: 1}); finished.Quiz.Stats != want {
+		t.Fatalf("stats = %+v, want %+v", finished.Quiz.Stats, want)
+	}
+}
+
+func TestListReviewWordsByDate(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	env.createUnit(t, "day before", "2026-03-09", "abandon")
+	env.createUnit(t, "week before", "2026-03-03", "desert", "forsake")
+	env.createUnit(t, "five days before", "2026-03-05", "give up")
+	env.createUnit(t, "undated", "", "abandon")
+	env.createUnit(t, "leap day", "2024-02-29", "forsake")
+
+	words, units, err := env.svc.ListReviewWordsByDate(ctx, "2026-03-10")
+	if err != nil {
+		t.Fatalf("ListReviewWordsByDate error: %v", err)
+	}
+	want := []ReviewUnitSummary{
+		{Name: "week before", WordCount: 2, ReciteDate: "2026-03-03", DistanceDays: 7},
+		{Name: "day before", WordCount: 1, ReciteDate: "2026-03-09", DistanceDays: 1},
+	}
+	if len(units) != len(want) {
+		t.Fatalf("review units = %+v, want %d units", units, len(want))
+	}
+	for i := range want {
+		want[i].UnitID = units[i].UnitID
+		if units[i] != want[i] {
+			t.Fatalf("review unit %d = %+v, want %+v", i, units[i], want[i])
+		}
+	}
+	if len(words) != 3 {
+		t.Fatalf("review words = %d, want 3", len(words))
+	}
+
+	// Day counting crosses month ends and leap days by calendar date.
+	_, units, err = env.svc.ListReviewWordsByDate(ctx, "2024-03-01")
+	if err != nil {
+		t.Fatalf("ListReviewWordsByDate error: %v", err)
+	}
+	if len(units) != 1 || units[0].Name != "leap day" || units[0].DistanceDays != 1 {
+		t.Fatalf("review units on 2024-03-01 = %+v, want leap day at distance 1", units)
+	}
+
+	if _, _, err := env.svc.ListReviewWordsByDate(ctx, "2026/03/10"); errKey(err) != "invalid_date" {
+		t.Fatalf("ListReviewWordsByDate(bad date) error = %v, want invalid_date", err)
+	}
+
+	detail, err := env.svc.StartQuiz(ctx, StartQuizRequest{Type: "读写", SourceKind: "review", ReviewDate: "2026-03-10"})
+	if err != nil {
+		t.Fatalf("StartQuiz(review) error: %v", err)
+	}
+	if detail.Quiz.ReviewDate != "2026-03-10" || detail.Quiz.Stats.Total != 3 {
+		t.Fatalf("review quiz = %+v, want 3 words on 2026-03-10", detail.Quiz)
+	}
+}
+
+func TestNoteLinking(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+	forsakeID := env.wordID(t, "forsake")
+
+	note, err := env.svc.CreateNote(ctx, "近义词", "  abandon / desert  ", []int64{abandonID, desertID, abandonID})
+	if err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+	if note.Content != "abandon / desert" || len(note.Words) != 2 {
+		t.Fatalf("created note = %+v, want trimmed content and 2 words", note)
+	}
+
+	if _, err := env.svc.CreateNote(ctx, "近义词", "x", []int64{abandonID, 9999}); errKey(err) != "note_words_unknown" {
+		t.Fatalf("CreateNote(unknown word) error = %v, want note_words_unknown", err)
+	}
+	if _, err := env.svc.CreateNote(ctx, "同音词", "x", []int64{abandonID}); errKey(err) != "note_type_invalid" {
+		t.Fatalf("CreateNote(unknown type) error = %v, want note_type_invalid", err)
+	}
+	if _, err := env.svc.CreateNote(ctx, "近义词", "x", nil); errKey(err) != "note_words_empty" {
+		t.Fatalf("CreateNote(no words) error = %v, want note_words_empty", err)
+	}
+
+	updated, err := env.svc.UpdateNote(ctx, note.ID, "近义词", "abandon / forsake", []int64{abandonID, forsakeID})
+	if err != nil {
+		t.Fatalf("UpdateNote error: %v", err)
+	}
+	linked := make(map[string]bool)
+	for _, word := range updated.Words {
+		linked[word.Word] = true
+	}
+	if len(linked) != 2 || !linked["abandon"] || !linked["forsake"] {
+		t.Fatalf("updated note words = %v, want abandon and forsake", linked)
+	}
+
+	other, err := env.svc.CreateNote(ctx, "反义词", "forsake <-> keep", []int64{forsakeID})
+	if err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+
+	tags, err := env.svc.ListNotesByWordIDs(ctx, []int64{abandonID, desertID, forsakeID})
+	if err != nil {
+		t.Fatalf("ListNotesByWordIDs error: %v", err)
+	}
+	if len(tags[desertID]) != 0 {
+		t.Fatalf("desert notes = %+v, want none after update", tags[desertID])
+	}
+	if len(tags[abandonID]) != 1 || tags[abandonID][0].ID != note.ID {
+		t.Fatalf("abandon notes = %+v, want note %d", tags[abandonID], note.ID)
+	}
+	if got := tags[forsakeID]; len(got) != 2 || got[0].ID != other.ID || got[1].Type != "近义词" {
+		t.Fatalf("forsake notes = %+v, want newest first", got)
+	}
+
+	list, total, err := env.svc.ListNotes(ctx, 1, 20)
+	if err != nil {
+		t.Fatalf("ListNotes error: %v", err)
+	}
+	if total != 2 || len(list) != 2 || list[0].ID != other.ID || len(list[1].Words) != 2 {
+		t.Fatalf("ListNotes = %+v total=%d", list, total)
+	}
+}
+
+func TestNoteWordRefs(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+
+	note, err := env.svc.CreateNote(ctx, "近义词", "[[Abandon|abandoned]] vs [[desert]], not `[[code]]` <script>x</script>", nil)
+	if err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+	if len(note.Words) != 2 || len(note.RefWordIDs) != 2 {
+		t.Fatalf("CreateNote words = %+v refs = %v, want abandon and desert from refs", note.Words, note.RefWordIDs)
+	}
+	if !strings.Contains(note.HTML, `<span class="word-ref" data-word="Abandon">abandoned</span>`) ||
+		!strings.Contains(note.HTML, "<code>[[code]]</code>") || strings.Contains(note.HTML, "<script>") {
+		t.Fatalf("CreateNote html = %q, want word refs rendered and raw html dropped", note.HTML)
+	}
+
+	note, err = env.svc.UpdateNote(ctx, note.ID, "近义词", "see [[desert]]", []int64{abandonID})
+	if err != nil {
+		t.Fatalf("UpdateNote error: %v", err)
+	}
+	if len(note.Words) != 2 || len(note.RefWordIDs) != 1 || note.RefWordIDs[0] != desertID {
+		t.Fatalf("UpdateNote words = %+v refs = %v, want abandon by hand and desert by ref", note.Words, note.RefWordIDs)
+	}
+	note, err = env.svc.UpdateNote(ctx, note.ID, "近义词", "no refs", []int64{abandonID})
+	if err != nil || len(note.Words) != 1 || note.Words[0].WordID != abandonID || len(note.RefWordIDs) != 0 {
+		t.Fatalf("UpdateNote = %+v, %v; want the desert ref link dropped", note, err)
+	}
+
+	if _, err := env.svc.CreateNote(ctx, "近义词", "[[abandon]] and [[zzz]]", nil); errKey(err) != "note_word_ref_unknown" {
+		t.Fatalf("CreateNote(unknown ref) error = %v, want note_word_ref_unknown", err)
+	}
+	if _, err := env.svc.CreateNote(ctx, "近义词", "`[[abandon]]`", nil); errKey(err) != "note_words_empty" {
+		t.Fatalf("CreateNote(no words) error = %v, want note_words_empty", err)
+	}
+}
+
+func TestWordGraph(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	unitID := env.createUnit(t, "unit", "", "abandon", "desert")
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+	forsakeID := env.wordID(t, "forsake")
+	giveUpID := env.wordID(t, "give up")
+	for _, note := range []struct {
+		noteType string
+		wordIDs  []int64
+	}{
+		{"近义词", []int64{abandonID, desertID}},
+		{"近义词", []int64{desertID, forsakeID}},
+		{"反义词", []int64{abandonID, giveUpID}},
+		{"反义词", []int64{giveUpID, forsakeID}},
+	} {
+		if _, err := env.svc.CreateNote(ctx, note.noteType, "note", note.wordIDs); err != nil {
+			t.Fatalf("CreateNote error: %v", err)
+		}
+	}
+
+	graph, err := env.svc.GetWordGraph(ctx, abandonID, 2)
+	if err != nil {
+		t.Fatalf("GetWordGraph error: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, group := range graph.Groups {
+		for _, item := range group.Neighbours {
+			got[group.NoteType] = append(got[group.NoteType], fmt.Sprintf("%s@%d", item.Word, item.Depth))
+		}
+	}
+	want := map[string][]string{"近义词": {"desert@1", "forsake@2"}, "反义词": {"give up@1", "forsake@2"}}
+	if !reflect.DeepEqual(got, want) || graph.Groups[0].NoteType != "近义词" {
+		t.Fatalf("GetWordGraph groups = %v, want %v", got, want)
+	}
+
+	unitGraph, err := env.svc.GetUnitGraph(ctx, unitID)
+	if err != nil {
+		t.Fatalf("GetUnitGraph error: %v", err)
+	}
+	if len(unitGraph.Nodes) != 4 || len(unitGraph.Edges) != 3 || !unitGraph.Nodes[0].InUnit || unitGraph.Nodes[3].InUnit {
+		t.Fatalf("GetUnitGraph = %+v, want the 2 unit words, 2 neighbours and 3 edges", unitGraph)
+	}
+	dot := unitGraph.DOT()
+	edge := fmt.Sprintf("w%d -- w%d [label=\"近义词\"", abandonID, desertID)
+	if !strings.HasPrefix(dot, `graph "unit" {`) || !strings.Contains(dot, edge) {
+		t.Fatalf("DOT = %q, want it to contain %q", dot, edge)
+	}
+}
+
+func TestSearchNotes(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+	if _, err := env.svc.CreateNote(ctx, "近义词", "abandon: 放弃 a <plan>\n\nAbandon ship", []int64{abandonID}); err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+	if _, err := env.svc.CreateNote(ctx, "反义词", "desert vs dessert, 放弃 the desert", []int64{desertID}); err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+
+	items, total, err := env.svc.SearchNotes(ctx, NoteSearchRequest{Query: "ABANDON"})
+	if err != nil {
+		t.Fatalf("SearchNotes error: %v", err)
+	}
+	want := "<mark>abandon</mark>: 放弃 a &lt;plan&gt; <mark>Abandon</mark> ship"
+	if total != 1 || len(items) != 1 || items[0].Snippet != want || items[0].Words[0] != "abandon" {
+		t.Fatalf("SearchNotes(abandon) = %d %+v, want one note with snippet %q", total, items, want)
+	}
+
+	items, total, err = env.svc.SearchNotes(ctx, NoteSearchRequest{Query: "放弃"})
+	if err != nil || total != 2 {
+		t.Fatalf("SearchNotes(放弃) = %d, %v; want 2 notes", total, err)
+	}
+	items, _, err = env.svc.SearchNotes(ctx, NoteSearchRequest{Query: "放弃", WordID: desertID})
+	if err != nil || len(items) != 1 || items[0].Type != "反义词" {
+		t.Fatalf("SearchNotes(word filter) = %+v, %v; want the desert note", items, err)
+	}
+	items, _, err = env.svc.SearchNotes(ctx, NoteSearchRequest{NoteType: "近义词"})
+	if err != nil || len(items) != 1 || items[0].Type != "近义词" {
+		t.Fatalf("SearchNotes(type filter) = %+v, %v; want the abandon note", items, err)
+	}
+
+	if _, _, err := env.svc.SearchNotes(ctx, NoteSearchRequest{Sort: "random"}); errKey(err) != "note_search_sort_invalid" {
+		t.Fatalf("SearchNotes(random sort) error = %v, want note_search_sort_invalid", err)
+	}
+}
+
+func TestNoteTrash(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	ctx := context.Background()
+	abandonID := env.wordID(t, "abandon")
+	desertID := env.wordID(t, "desert")
+	note, err := env.svc.CreateNote(ctx, "近义词", "abandon vs desert", []int64{abandonID, desertID})
+	if err != nil {
+		t.Fatalf("CreateNote error: %v", err)
+	}
+
+	detail, err := env.svc.UnlinkNoteWord(ctx, note.ID, desertID)
+	if err != nil || len(detail.Words) != 1 || detail.Words[0].Word != "abandon" {
+		t.Fatalf("UnlinkNoteWord = %+v, %v; want only abandon left", detail, err)
+	}
+	if _, err := env.svc.UnlinkNoteWord(ctx, note.ID, desertID); errKey(err) != "note_word_not_linked" {
+		t.Fatalf("UnlinkNoteWord(unlinked) error = %v, want note_word_not_linked", err)
+	}
+	if _, err := env.svc.UnlinkNoteWord(ctx, note.ID, abandonID); errKey(err) != "note_last_word" {
+		t.Fatalf("UnlinkNoteWord(last) error = %v, want note_last_word", err)
+	}
+
+	if err := env.svc.PurgeNote(ctx, note.ID); errKey(err) != "note_not_in_trash" {
+		t.Fatalf("PurgeNote(live) error = %v, want note_not_in_trash", err)
+	}
+	if err := env.svc.DeleteNote(ctx, note.ID); err != nil {
+		t.Fatalf("DeleteNote error: %v", err)
+	}
+	if _, err := env.svc.GetNoteDetail(ctx, note.ID); errKey(err) != "note_not_found" {
+		t.Fatalf("GetNoteDetail(trashed) error = %v, want note_not_found", err)
+	}
+	if items, total, err := env.svc.ListNotes(ctx, 1, 20); err != nil || total != 0 || len(items) != 0 {
+		t.Fatalf("ListNotes = %d %+v, %v; want no live notes", total, items, err)
+	}
+	tags, err := env.svc.ListNotesByWordIDs(ctx, []int64{abandonID})
+	if err != nil || len(tags[abandonID]) != 0 {
+		t.Fatalf("ListNotesByWordIDs = %+v, %v; want trashed note hidden", tags, err)
+	}
+	trashed, total, err := env.svc.ListTrashedNotes(ctx, 1, 20)
+	if err != nil || total != 1 || trashed[0].DeletedAt == "" {
+		t.Fatalf("ListTrashedNotes = %d %+v, %v; want the trashed note", total, trashed, err)
+	}
+
+	restored, err := env.svc.RestoreNote(ctx, note.ID)
+	if err != nil || restored.ID != note.ID || len(restored.Words) != 1 {
+		t.Fatalf("RestoreNote = %+v, %v; want the note with its word", restored, err)
+	}
+	if _, err := env.svc.RestoreNote(ctx, note.ID); errKey(err) != "note_not_in_trash" {
+		t.Fatalf("RestoreNote(live) error = %v, want note_not_in_trash", err)
+	}
+
+	if err := env.svc.DeleteNote(ctx, note.ID); err != nil {
+		t.Fatalf("DeleteNote error: %v", err)
+	}
+	if err := env.svc.PurgeNote(ctx, note.ID); err != nil {
+		t.Fatalf("PurgeNote error: %v", err)
+	}
+	if err := env.svc.DeleteNote(ctx, note.ID); errKey(err) != "note_not_found" {
+		t.Fatalf("DeleteNote(purged) error = %v, want note_not_found", err)
+	}
+	if _, total, _ := env.svc.ListTrashedNotes(ctx, 1, 20); total != 0 {
+		t.Fatalf("ListTrashedNotes total = %d after purge, want 0", total)
+	}
+}
+
+func TestNoteSnippet(t *testing.T) {
+	content := strings.Repeat("x", 100) + " key " + strings.Repeat("y", 200)
+	got := noteSnippet(content, []string{"key"})
+	want := "…" + strings.Repeat("x", 29) + " <mark>key</mark> " + strings.Repeat("y", 86) + "…"
+	if got != want {
+		t.Fatalf("noteSnippet = %q, want %q", got, want)
+	}
+}
+
+func TestMemoryStoreTimestamps(t *testing.T) {
+	env := newTestEnv(t, ForgottenPolicy{})
+	now := time.Date(2026, 3, 10, 8, 30, 15, 500, time.Local)
+	env.store.Now = func() time.Time { return now }
+	unitID := env.createUnit(t, "Unit 1", "2026-03-10", "abandon")
+
+	units, err := env.svc.ListUnits(context.Background())
+	if err != nil {
+		t.Fatalf("ListUnits error: %v", err)
+	}
+	if len(units) != 1 || units[0].ID != unitID || units[0].CreatedAt != "2026-03-10 08:30:15" {
+		t.Fatalf("ListUnits = %+v, want created_at from the store clock", units)
+	}
+}
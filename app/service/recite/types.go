@@ -14,6 +14,8 @@ type WordSentence struct {
 	TTSURL  string `json:"ttsUrl"`
 	TTSSize int    `json:"ttsSize"`
 	LikeNum int    `json:"likeNum"`
+	// TTSSynthetic is true when the sentence audio was generated locally.
+	TTSSynthetic bool `json:"ttsSynthetic"`
 }
 
 type WordSentenceGroup struct {
@@ -33,6 +35,30 @@ type WordInfo struct {
 	AmAudioURL     string              `json:"am_audio_url"`
 	Parts          []WordPart          `json:"parts"`
 	SentenceGroups []WordSentenceGroup `json:"sentence_groups"`
+	Mnemonic       string              `json:"mnemonic"`
+	CustomParts    []WordPart          `json:"custom_parts"`
+	CustomMode     string              `json:"custom_mode"`
+	Edited         bool                `json:"edited"`
+	Lemma          string              `json:"lemma,omitempty"`
+	Forms          []string            `json:"forms,omitempty"`
+	// EnAudioSynthetic / AmAudioSynthetic are true when the word audio was
+	// generated by the local TTS backend.
+	EnAudioSynthetic bool `json:"en_audio_synthetic"`
+	AmAudioSynthetic bool `json:"am_audio_synthetic"`
+}
+
+// UpdateWordRequest edits a word entry. Nil fields are left unchanged.
+// CustomMode is "override" to show CustomParts instead of the fetched parts,
+// or "supplement" to show them after the fetched parts.
+type UpdateWordRequest struct {
+	PhEn           *string              `json:"ph_en"`
+	PhAm           *string              `json:"ph_am"`
+	MeanTag        *string              `json:"mean_tag"`
+	Parts          *[]WordPart          `json:"parts"`
+	SentenceGroups *[]WordSentenceGroup `json:"sentence_groups"`
+	Mnemonic       *string              `json:"mnemonic"`
+	CustomParts    *[]WordPart          `json:"custom_parts"`
+	CustomMode     *string              `json:"custom_mode"`
 }
 
 type UnitInfo struct {
@@ -49,16 +75,19 @@ type ClientConfig struct {
 }
 
 type UnitWordItem struct {
-	Seq            int                 `json:"seq"`
-	WordID         int64               `json:"word_id"`
-	Word           string              `json:"word"`
-	PhEn           string              `json:"ph_en"`
-	PhAm           string              `json:"ph_am"`
-	MeanTag        string              `json:"mean_tag"`
-	EnAudio        string              `json:"en_audio"`
-	AmAudio        string              `json:"am_audio"`
-	Parts          []WordPart          `json:"parts"`
-	SentenceGroups []WordSentenceGroup `json:"sentence_groups"`
+	Seq              int                 `json:"seq"`
+	WordID           int64               `json:"word_id"`
+	Word             string              `json:"word"`
+	PhEn             string              `json:"ph_en"`
+	PhAm             string              `json:"ph_am"`
+	MeanTag          string              `json:"mean_tag"`
+	EnAudio          string              `json:"en_audio"`
+	AmAudio          string              `json:"am_audio"`
+	Parts            []WordPart          `json:"parts"`
+	SentenceGroups   []WordSentenceGroup `json:"sentence_groups"`
+	Mnemonic         string              `json:"mnemonic"`
+	EnAudioSynthetic bool                `json:"en_audio_synthetic"`
+	AmAudioSynthetic bool                `json:"am_audio_synthetic"`
 }
 
 type ReviewUnitSummary struct {
@@ -69,12 +98,20 @@ type ReviewUnitSummary struct {
 	DistanceDays int    `json:"distance_days"`
 }
 
+// QuizWordItem is a word of a quiz. Choices are set for confusable quizzes:
+// the words of its group, the answer among them, to choose from.
 type QuizWordItem struct {
 	Seq         int          `json:"seq"`
 	WordStatus  string       `json:"word_status"`
 	InputAnswer string       `json:"input_answer"`
 	Result      string       `json:"result"`
 	WordDetail  UnitWordItem `json:"word_detail"`
+	Choices     []QuizChoice `json:"choices,omitempty"`
+}
+
+type QuizChoice struct {
+	WordID int64  `json:"word_id"`
+	Word   string `json:"word"`
 }
 
 type QuizStats struct {
@@ -113,11 +150,15 @@ type QuizListItem struct {
 	NextSeq   int       `json:"next_seq"`
 }
 
+// StartQuizRequest starts a quiz. A confusable quiz takes the words of the
+// note NoteID, or of all notes of NoteType when NoteID is 0.
 type StartQuizRequest struct {
 	Type       string `json:"type"`
 	SourceKind string `json:"source_kind"`
-	UnitID     int64  `json:"unit_id"`
-	ReviewDate string `json:"review_date"`
+	UnitID     int64  `json:"unit_id,omitempty"`
+	ReviewDate string `json:"review_date,omitempty"`
+	NoteID     int64  `json:"note_id,omitempty"`
+	NoteType   string `json:"note_type,omitempty"`
 }
 
 type NoteTag struct {
@@ -125,13 +166,18 @@ type NoteTag struct {
 	Type string `json:"type"`
 }
 
+// NoteDetail is a note with its linked words. Content is Markdown and HTML
+// its sanitized rendering; RefWordIDs are the linked words that come from
+// [[word]] references in the content, and are relinked on every save.
 type NoteDetail struct {
-	ID        int64          `json:"id"`
-	Type      string         `json:"type"`
-	Content   string         `json:"content"`
-	CreatedAt string         `json:"created_at"`
-	UpdatedAt string         `json:"updated_at"`
-	Words     []UnitWordItem `json:"words"`
+	ID         int64          `json:"id"`
+	Type       string         `json:"type"`
+	Content    string         `json:"content"`
+	HTML       string         `json:"html"`
+	CreatedAt  string         `json:"created_at"`
+	UpdatedAt  string         `json:"updated_at"`
+	Words      []UnitWordItem `json:"words"`
+	RefWordIDs []int64        `json:"ref_word_ids"`
 }
 
 type NoteListItem struct {
@@ -140,4 +186,6 @@ type NoteListItem struct {
 	Content   string   `json:"content"`
 	Words     []string `json:"words"`
 	CreatedAt string   `json:"created_at"`
+	// DeletedAt is set on notes listed from the trash.
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,185 @@
+package recite
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	// maxWordBatch bounds the words of one batch lookup.
+	maxWordBatch = 300
+	// wordBatchConcurrency bounds the dictionary fetches one batch lookup
+	// runs at once.
+	wordBatchConcurrency = 4
+)
+
+const (
+	wordBatchCached  = "cached"
+	wordBatchFetched = "fetched"
+	wordBatchInvalid = "invalid"
+	wordBatchFailed  = "failed"
+)
+
+// WordBatchItem is the outcome of one query of a batch lookup. Status is
+// cached, fetched, invalid or failed; Err tells why for the last two and is
+// turned into Key and Error by the handler.
+type WordBatchItem struct {
+	Query  string    `json:"query"`
+	Status string    `json:"status"`
+	Word   *WordInfo `json:"word,omitempty"`
+	Key    string    `json:"key,omitempty"`
+	Error  string    `json:"error,omitempty"`
+	Err    error     `json:"-"`
+}
+
+// QueryWords looks a list of words up like QueryWord, or QueryWordLemma with
+// useLemma, and returns one item per query in request order. Cached words are
+// read in one query and get their audio checked like QueryWord does; the
+// missing ones are fetched from the dictionary. Both run wordBatchConcurrency
+// at a time. A word that cannot be looked up only fails
+// its own item.
+func (s *Service) QueryWords(ctx context.Context, rawWords []string, useLemma bool) ([]WordBatchItem, error) {
+	if len(rawWords) == 0 {
+		return nil, errcode.New(errcode.WordBatchEmpty)
+	}
+	if len(rawWords) > maxWordBatch {
+		return nil, errcode.New(errcode.WordBatchTooLarge, maxWordBatch)
+	}
+
+	items := make([]WordBatchItem, len(rawWords))
+	words := make([]string, len(rawWords))
+	for i, raw := range rawWords {
+		items[i].Query = raw
+		word, err := normalizeWord(raw)
+		if err != nil {
+			items[i].Status = wordBatchInvalid
+			items[i].Err = err
+			continue
+		}
+		words[i] = word
+	}
+	var lemmas map[string]string
+	if useLemma {
+		var err error
+		if lemmas, err = s.resolveLemmas(ctx, nonEmpty(words)); err != nil {
+			return nil, err
+		}
+	}
+
+	// forms[i] is the inflected query of item i when its lemma is looked up
+	forms := make([]string, len(rawWords))
+	positions := make(map[string][]int)
+	lookups := make([]string, 0, len(rawWords))
+	for i, word := range words {
+		if word == "" {
+			continue
+		}
+		lookup := word
+		if lemma := lemmas[word]; lemma != "" {
+			lookup = lemma
+			forms[i] = word
+		}
+		if _, ok := positions[lookup]; !ok {
+			lookups = append(lookups, lookup)
+		}
+		positions[lookup] = append(positions[lookup], i)
+	}
+
+	results, err := s.lookupWordBatch(ctx, lookups)
+	if err != nil {
+		return nil, err
+	}
+	for lookup, indexes := range positions {
+		result := results[lookup]
+		for _, i := range indexes {
+			items[i].Status = result.Status
+			items[i].Word = result.Word
+			items[i].Err = result.Err
+		}
+	}
+
+	if s.wordFormRepo != nil {
+		for i, form := range forms {
+			info := items[i].Word
+			if form == "" || info == nil {
+				continue
+			}
+			if err := s.wordFormRepo.Add(ctx, info.ID, form); err != nil {
+				return nil, err
+			}
+			if !containsString(info.Forms, form) {
+				info.Forms = append(info.Forms, form)
+			}
+		}
+	}
+	return items, nil
+}
+
+// lookupWordBatch resolves distinct normalized words, keyed by word. Cached
+// words go through the same audio check as QueryWord; all lemmas are filled in
+// at the end in bulk.
+func (s *Service) lookupWordBatch(ctx context.Context, words []string) (map[string]WordBatchItem, error) {
+	results := make(map[string]WordBatchItem, len(words))
+	cached, err := s.wordRepo.GetByWords(ctx, words)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, wordBatchConcurrency)
+	)
+	for _, word := range words {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(word string, row *entity.Word) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result := WordBatchItem{Status: wordBatchCached}
+			if row != nil {
+				s.ensureWordAudio(ctx, row)
+			} else {
+				var err error
+				result.Status = wordBatchFetched
+				if row, err = s.loadOrFetchWord(ctx, word); err != nil {
+					result = WordBatchItem{Status: wordBatchFailed, Err: err}
+				}
+			}
+			if row != nil {
+				info := buildWordInfo(row)
+				result.Word = &info
+			}
+			mu.Lock()
+			results[word] = result
+			mu.Unlock()
+		}(word, cached[word])
+	}
+	wg.Wait()
+
+	infos := make([]*WordInfo, 0, len(results))
+	for _, word := range words {
+		if info := results[word].Word; info != nil {
+			infos = append(infos, info)
+		}
+	}
+	if err := s.fillWordLemmas(ctx, infos); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func nonEmpty(items []string) []string {
+	ret := make([]string, 0, len(items))
+	for _, item := range items {
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,199 @@
+package recite
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	wordCustomModeOverride   = "override"
+	wordCustomModeSupplement = "supplement"
+)
+
+func (s *Service) GetWord(ctx context.Context, wordID int64) (*WordInfo, error) {
+	row, err := s.getWordByID(ctx, wordID)
+	if err != nil {
+		return nil, err
+	}
+	result := buildWordInfo(row)
+	return &result, nil
+}
+
+// UpdateWord applies user edits to a word. The fetched content is kept aside on
+// the first edit of phonetics, mean tag, parts or sentences so RevertWord can
+// restore it.
+func (s *Service) UpdateWord(ctx context.Context, wordID int64, req UpdateWordRequest) (*WordInfo, error) {
+	row, err := s.getWordByID(ctx, wordID)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchedEdited := req.PhEn != nil || req.PhAm != nil || req.MeanTag != nil || req.Parts != nil || req.SentenceGroups != nil
+	if fetchedEdited && row.Origin == nil {
+		row.Origin = &entity.WordOrigin{
+			PhEn:           row.PhEn,
+			PhAm:           row.PhAm,
+			MeanTag:        row.MeanTag,
+			Parts:          row.Parts,
+			SentenceGroups: row.SentenceGroups,
+		}
+	}
+	if req.PhEn != nil {
+		row.PhEn = strings.TrimSpace(*req.PhEn)
+	}
+	if req.PhAm != nil {
+		row.PhAm = strings.TrimSpace(*req.PhAm)
+	}
+	if req.MeanTag != nil {
+		row.MeanTag = strings.TrimSpace(*req.MeanTag)
+	}
+	if req.Parts != nil {
+		row.Parts = normalizeWordParts(*req.Parts)
+	}
+	if req.SentenceGroups != nil {
+		groups, err := normalizeSentenceGroups(*req.SentenceGroups)
+		if err != nil {
+			return nil, err
+		}
+		if err := restoreSentenceAudio(groups, row.SentenceGroups, row.Origin.SentenceGroups); err != nil {
+			return nil, err
+		}
+		row.SentenceGroups = groups
+	}
+	if req.Mnemonic != nil {
+		mnemonic := strings.TrimSpace(*req.Mnemonic)
+		if len([]rune(mnemonic)) > 300 {
+			return nil, errcode.New(errcode.MnemonicTooLong, 300)
+		}
+		row.Mnemonic = mnemonic
+	}
+	if req.CustomParts != nil {
+		row.CustomParts = normalizeWordParts(*req.CustomParts)
+	}
+	if req.CustomMode != nil {
+		mode, err := normalizeWordCustomMode(*req.CustomMode)
+		if err != nil {
+			return nil, err
+		}
+		row.CustomMode = mode
+	}
+	if len(row.CustomParts) == 0 {
+		row.CustomMode = ""
+	} else if row.CustomMode == "" {
+		row.CustomMode = wordCustomModeSupplement
+	}
+
+	now := time.Now()
+	row.EditedAt = &now
+	if err := s.wordRepo.Update(ctx, row); err != nil {
+		return nil, err
+	}
+	return s.GetWord(ctx, wordID)
+}
+
+// RevertWord restores the fetched phonetics, mean tag, parts and sentences of a
+// word. Personal definitions and the mnemonic are kept.
+func (s *Service) RevertWord(ctx context.Context, wordID int64) (*WordInfo, error) {
+	row, err := s.getWordByID(ctx, wordID)
+	if err != nil {
+		return nil, err
+	}
+	if row.Origin == nil {
+		return nil, errcode.New(errcode.WordNotEdited)
+	}
+	row.PhEn = row.Origin.PhEn
+	row.PhAm = row.Origin.PhAm
+	row.MeanTag = row.Origin.MeanTag
+	row.Parts = row.Origin.Parts
+	row.SentenceGroups = row.Origin.SentenceGroups
+	row.Origin = nil
+	now := time.Now()
+	row.EditedAt = &now
+	if err := s.wordRepo.Update(ctx, row); err != nil {
+		return nil, err
+	}
+	return s.GetWord(ctx, wordID)
+}
+
+func (s *Service) getWordByID(ctx context.Context, wordID int64) (*entity.Word, error) {
+	if wordID <= 0 {
+		return nil, errcode.New(errcode.InvalidParam, "word_id")
+	}
+	row, err := s.wordRepo.GetByID(ctx, wordID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, errcode.New(errcode.WordNotFound)
+	}
+	return row, nil
+}
+
+func normalizeWordCustomMode(raw string) (string, error) {
+	switch strings.TrimSpace(raw) {
+	case "":
+		return "", nil
+	case wordCustomModeOverride:
+		return wordCustomModeOverride, nil
+	case wordCustomModeSupplement:
+		return wordCustomModeSupplement, nil
+	default:
+		return "", errcode.New(errcode.CustomModeInvalid)
+	}
+}
+
+func normalizeWordParts(raw []WordPart) []entity.WordPart {
+	ret := make([]entity.WordPart, 0, len(raw))
+	for _, part := range raw {
+		means := make([]string, 0, len(part.Means))
+		for _, mean := range part.Means {
+			text := strings.TrimSpace(mean)
+			if text == "" {
+				continue
+			}
+			means = append(means, text)
+		}
+		if len(means) == 0 {
+			continue
+		}
+		ret = append(ret, entity.WordPart{
+			Part:  strings.TrimSpace(part.Part),
+			Means: means,
+		})
+	}
+	return ret
+}
+
+func normalizeSentenceGroups(raw []WordSentenceGroup) ([]entity.WordSentenceGroup, error) {
+	ret := make([]entity.WordSentenceGroup, 0, len(raw))
+	for _, group := range raw {
+		sentences := make([]entity.WordSentence, 0, len(group.Sentences))
+		for _, sentence := range group.Sentences {
+			en := strings.TrimSpace(sentence.EN)
+			if en == "" {
+				return nil, errcode.New(errcode.SentenceEmpty)
+			}
+			sentences = append(sentences, entity.WordSentence{
+				ID:      sentence.ID,
+				Type:    sentence.Type,
+				EN:      en,
+				CN:      strings.TrimSpace(sentence.CN),
+				From:    strings.TrimSpace(sentence.From),
+				TTSURL:  sentence.TTSURL,
+				TTSSize: sentence.TTSSize,
+				LikeNum: sentence.LikeNum,
+			})
+		}
+		ret = append(ret, entity.WordSentenceGroup{
+			Tag:       strings.TrimSpace(group.Tag),
+			Word:      strings.TrimSpace(group.Word),
+			Meaning:   strings.TrimSpace(group.Meaning),
+			Sentences: sentences,
+		})
+	}
+	return ret, nil
+}
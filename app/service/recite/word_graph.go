@@ -0,0 +1,388 @@
+package recite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	defaultWordGraphDepth = 1
+	maxWordGraphDepth     = 3
+)
+
+// WordGraphNode is a word of a graph.
+type WordGraphNode struct {
+	ID   int64  `json:"id"`
+	Word string `json:"word"`
+	// InUnit tells the words of the exported unit from their neighbours.
+	InUnit bool `json:"in_unit,omitempty"`
+}
+
+// WordGraphNeighbour is a word reached in Depth steps; NoteIDs are the notes
+// of the last step.
+type WordGraphNeighbour struct {
+	WordGraphNode
+	Depth   int     `json:"depth"`
+	NoteIDs []int64 `json:"note_ids"`
+}
+
+// WordGraphGroup holds the neighbours reached through notes of one type only,
+// so that the antonym of an antonym is not listed as an antonym.
+type WordGraphGroup struct {
+	NoteType   string               `json:"note_type"`
+	Neighbours []WordGraphNeighbour `json:"neighbours"`
+}
+
+// WordGraph is the neighbourhood of a word. Two words are neighbours when a
+// note links both, the type of the note being the kind of their relation;
+// trashed notes take no part.
+type WordGraph struct {
+	Word   WordGraphNode    `json:"word"`
+	Depth  int              `json:"depth"`
+	Groups []WordGraphGroup `json:"groups"`
+}
+
+// WordGraphEdge joins two words linked by notes of one type, Source < Target.
+type WordGraphEdge struct {
+	Source   int64   `json:"source"`
+	Target   int64   `json:"target"`
+	NoteType string  `json:"note_type"`
+	NoteIDs  []int64 `json:"note_ids"`
+}
+
+// UnitGraph is the graph of the words of a unit and of the words sharing a
+// note with them.
+type UnitGraph struct {
+	UnitID   int64           `json:"unit_id"`
+	UnitName string          `json:"unit_name"`
+	Nodes    []WordGraphNode `json:"nodes"`
+	Edges    []WordGraphEdge `json:"edges"`
+}
+
+// graphNote is a live note with its words.
+type graphNote struct {
+	id       int64
+	noteType string
+	wordIDs  []int64
+}
+
+// notesOfWords returns the live notes linked to wordIDs, oldest first.
+func (s *Service) notesOfWords(ctx context.Context, wordIDs []int64) ([]graphNote, error) {
+	if len(wordIDs) == 0 {
+		return nil, nil
+	}
+	byWord, err := s.noteRepo.ListByWordIDs(ctx, wordIDs)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[int64]string)
+	for _, notes := range byWord {
+		for _, note := range notes {
+			types[note.ID] = note.NoteType
+		}
+	}
+	noteIDs := make([]int64, 0, len(types))
+	for id := range types {
+		noteIDs = append(noteIDs, id)
+	}
+	sort.Slice(noteIDs, func(i, j int) bool { return noteIDs[i] < noteIDs[j] })
+	relations, err := s.noteRepo.ListWordRelationsByNoteIDs(ctx, noteIDs)
+	if err != nil {
+		return nil, err
+	}
+	words := make(map[int64][]int64, len(noteIDs))
+	for _, rel := range relations {
+		words[rel.NoteID] = append(words[rel.NoteID], rel.WordID)
+	}
+	ret := make([]graphNote, 0, len(noteIDs))
+	for _, id := range noteIDs {
+		ret = append(ret, graphNote{id: id, noteType: types[id], wordIDs: words[id]})
+	}
+	return ret, nil
+}
+
+// graphNodes loads the words of ids, skipping ids whose word is gone.
+func (s *Service) graphNodes(ctx context.Context, ids []int64) (map[int64]WordGraphNode, error) {
+	wordMap, err := s.wordRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[int64]WordGraphNode, len(wordMap))
+	for id, item := range wordMap {
+		ret[id] = WordGraphNode{ID: id, Word: item.Word}
+	}
+	return ret, nil
+}
+
+// GetWordGraph walks the notes from a word up to depth steps, one note type
+// at a time. depth defaults to 1 and is capped at 3.
+func (s *Service) GetWordGraph(ctx context.Context, wordID int64, depth int) (*WordGraph, error) {
+	if s.noteRepo == nil {
+		return nil, errcode.New(errcode.NoteRepoMissing)
+	}
+	if wordID <= 0 {
+		return nil, errcode.New(errcode.InvalidParam, "word_id")
+	}
+	if depth <= 0 {
+		depth = defaultWordGraphDepth
+	}
+	if depth > maxWordGraphDepth {
+		depth = maxWordGraphDepth
+	}
+	root, err := s.graphNodes(ctx, []int64{wordID})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := root[wordID]; !ok {
+		return nil, errcode.New(errcode.WordNotFound)
+	}
+
+	type walk struct {
+		seen     map[int64]struct{}
+		frontier []int64
+		found    []WordGraphNeighbour
+	}
+	walks := make(map[string]*walk)
+	typeOrder := make([]string, 0)
+	notesByWord := make(map[int64][]graphNote)
+	queried := make(map[int64]struct{})
+	frontier := []int64{wordID}
+	for step := 1; step <= depth && len(frontier) > 0; step++ {
+		pending := make(map[int64]struct{}, len(frontier))
+		pendingIDs := make([]int64, 0, len(frontier))
+		for _, id := range frontier {
+			if _, ok := queried[id]; !ok {
+				queried[id] = struct{}{}
+				pending[id] = struct{}{}
+				pendingIDs = append(pendingIDs, id)
+			}
+		}
+		notes, err := s.notesOfWords(ctx, pendingIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range notes {
+			for _, id := range note.wordIDs {
+				if _, ok := pending[id]; ok {
+					notesByWord[id] = append(notesByWord[id], note)
+				}
+			}
+			if _, ok := walks[note.noteType]; !ok && step == 1 {
+				walks[note.noteType] = &walk{seen: map[int64]struct{}{wordID: {}}, frontier: []int64{wordID}}
+				typeOrder = append(typeOrder, note.noteType)
+			}
+		}
+
+		frontier = nil
+		for _, noteType := range typeOrder {
+			w := walks[noteType]
+			via := make(map[int64][]int64)
+			next := make([]int64, 0)
+			for _, from := range w.frontier {
+				for _, note := range notesByWord[from] {
+					if note.noteType != noteType {
+						continue
+					}
+					for _, to := range note.wordIDs {
+						if _, ok := w.seen[to]; ok {
+							continue
+						}
+						if _, ok := via[to]; !ok {
+							next = append(next, to)
+						}
+						if ids := via[to]; len(ids) == 0 || ids[len(ids)-1] != note.id {
+							via[to] = append(ids, note.id)
+						}
+					}
+				}
+			}
+			for _, id := range next {
+				w.seen[id] = struct{}{}
+				w.found = append(w.found, WordGraphNeighbour{WordGraphNode: WordGraphNode{ID: id}, Depth: step, NoteIDs: via[id]})
+			}
+			w.frontier = next
+			frontier = append(frontier, next...)
+		}
+	}
+
+	ids := make([]int64, 0)
+	for _, w := range walks {
+		for _, item := range w.found {
+			ids = append(ids, item.ID)
+		}
+	}
+	nodes, err := s.graphNodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]WordGraphGroup, 0, len(typeOrder))
+	for _, noteType := range s.sortNoteTypes(typeOrder) {
+		neighbours := make([]WordGraphNeighbour, 0, len(walks[noteType].found))
+		for _, item := range walks[noteType].found {
+			node, ok := nodes[item.ID]
+			if !ok {
+				continue
+			}
+			item.WordGraphNode = node
+			neighbours = append(neighbours, item)
+		}
+		sort.SliceStable(neighbours, func(i, j int) bool {
+			if neighbours[i].Depth != neighbours[j].Depth {
+				return neighbours[i].Depth < neighbours[j].Depth
+			}
+			return neighbours[i].Word < neighbours[j].Word
+		})
+		groups = append(groups, WordGraphGroup{NoteType: noteType, Neighbours: neighbours})
+	}
+	return &WordGraph{Word: root[wordID], Depth: depth, Groups: groups}, nil
+}
+
+// sortNoteTypes orders note types as configured, unknown ones last by name.
+func (s *Service) sortNoteTypes(types []string) []string {
+	rank := make(map[string]int, len(s.noteTypes))
+	for i, item := range s.noteTypes {
+		rank[item] = i
+	}
+	ret := append([]string{}, types...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		ri, iok := rank[ret[i]]
+		rj, jok := rank[ret[j]]
+		if iok != jok {
+			return iok
+		}
+		if iok {
+			return ri < rj
+		}
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+// GetUnitGraph returns the words of a unit, the words sharing a note with
+// them and one edge per pair of words and note type.
+func (s *Service) GetUnitGraph(ctx context.Context, unitID int64) (*UnitGraph, error) {
+	if s.noteRepo == nil {
+		return nil, errcode.New(errcode.NoteRepoMissing)
+	}
+	if unitID <= 0 {
+		return nil, errcode.New(errcode.InvalidParam, "unit_id")
+	}
+	unit, err := s.unitRepo.GetByID(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	if unit == nil {
+		return nil, errcode.New(errcode.UnitNotFound)
+	}
+	relations, err := s.unitWordRepo.ListByUnitID(ctx, unitID)
+	if err != nil {
+		return nil, err
+	}
+	inUnit := make(map[int64]struct{}, len(relations))
+	ids := make([]int64, 0, len(relations))
+	for _, rel := range relations {
+		if _, ok := inUnit[rel.WordID]; !ok {
+			inUnit[rel.WordID] = struct{}{}
+			ids = append(ids, rel.WordID)
+		}
+	}
+	notes, err := s.notesOfWords(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	type edgeKey struct {
+		source, target int64
+		noteType       string
+	}
+	edges := make(map[edgeKey]*WordGraphEdge)
+	edgeOrder := make([]edgeKey, 0)
+	for _, note := range notes {
+		for i, a := range note.wordIDs {
+			if _, ok := inUnit[a]; !ok {
+				ids = append(ids, a)
+			}
+			for _, b := range note.wordIDs[i+1:] {
+				key := edgeKey{source: min(a, b), target: max(a, b), noteType: note.noteType}
+				edge, ok := edges[key]
+				if !ok {
+					edge = &WordGraphEdge{Source: key.source, Target: key.target, NoteType: key.noteType}
+					edges[key] = edge
+					edgeOrder = append(edgeOrder, key)
+				}
+				edge.NoteIDs = append(edge.NoteIDs, note.id)
+			}
+		}
+	}
+	nodeMap, err := s.graphNodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &UnitGraph{UnitID: unit.ID, UnitName: unit.Name, Nodes: make([]WordGraphNode, 0, len(nodeMap)), Edges: make([]WordGraphEdge, 0, len(edgeOrder))}
+	added := make(map[int64]struct{}, len(nodeMap))
+	for _, id := range ids {
+		node, ok := nodeMap[id]
+		if _, dup := added[id]; !ok || dup {
+			continue
+		}
+		added[id] = struct{}{}
+		_, node.InUnit = inUnit[id]
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, key := range edgeOrder {
+		_, sourceOK := added[key.source]
+		_, targetOK := added[key.target]
+		if sourceOK && targetOK {
+			graph.Edges = append(graph.Edges, *edges[key])
+		}
+	}
+	return graph, nil
+}
+
+// dotEdgeColors tells note types apart in DOT output; types take colours in
+// order of first appearance.
+var dotEdgeColors = []string{"#2563eb", "#dc2626", "#16a34a", "#9333ea", "#ea580c", "#0891b2"}
+
+// DOT renders the graph in the Graphviz DOT language. Words of the unit are
+// boxes and their neighbours outside the unit dashed ellipses.
+func (g *UnitGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph %s {\n", dotQuote(g.UnitName))
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for _, node := range g.Nodes {
+		style := "shape=ellipse, style=dashed"
+		if node.InUnit {
+			style = "shape=box"
+		}
+		fmt.Fprintf(&b, "  w%d [label=%s, %s];\n", node.ID, dotQuote(node.Word), style)
+	}
+	colors := make(map[string]string)
+	for _, edge := range g.Edges {
+		color, ok := colors[edge.NoteType]
+		if !ok {
+			color = dotEdgeColors[len(colors)%len(dotEdgeColors)]
+			colors[edge.NoteType] = color
+		}
+		noteIDs := make([]string, 0, len(edge.NoteIDs))
+		for _, id := range edge.NoteIDs {
+			noteIDs = append(noteIDs, strconv.FormatInt(id, 10))
+		}
+		fmt.Fprintf(&b, "  w%d -- w%d [label=%s, color=%s, tooltip=%s];\n",
+			edge.Source, edge.Target, dotQuote(edge.NoteType), dotQuote(color), dotQuote("notes "+strings.Join(noteIDs, ",")))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns s as a DOT double-quoted string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,183 @@
+package recite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/repository"
+	"github.com/wutianfang/moss/util"
+)
+
+// WordRefreshResult describes what a dictionary refresh changed on a word.
+// Kept lists the fields that changed upstream but were left alone because the
+// user edited them; the new upstream value still replaces the revert origin.
+type WordRefreshResult struct {
+	WordID  int64    `json:"word_id"`
+	Word    string   `json:"word"`
+	Changed []string `json:"changed"`
+	Kept    []string `json:"kept"`
+	Updated bool     `json:"updated"`
+}
+
+// WordRefreshOptions controls a bulk refresh. Limit <= 0 refreshes every
+// matching word; Sleep is waited between two fetches.
+type WordRefreshOptions struct {
+	Filter repository.WordRefreshFilter
+	Limit  int
+	DryRun bool
+	Sleep  time.Duration
+}
+
+func (s *Service) RefreshWord(ctx context.Context, wordID int64) (*WordInfo, *WordRefreshResult, error) {
+	row, err := s.getWordByID(ctx, wordID)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := s.refreshWordRow(ctx, row, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := s.GetWord(ctx, wordID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, result, nil
+}
+
+// RefreshStaleWords re-fetches every word matching the filter and reports each
+// outcome through onResult. It only stops early when listing words fails or
+// the context is cancelled.
+func (s *Service) RefreshStaleWords(
+	ctx context.Context,
+	opts WordRefreshOptions,
+	onResult func(word string, result *WordRefreshResult, err error),
+) (int, error) {
+	const pageSize = 100
+	var afterID int64
+	done := 0
+	for {
+		rows, err := s.wordRepo.ListForRefresh(ctx, opts.Filter, afterID, pageSize)
+		if err != nil {
+			return done, err
+		}
+		if len(rows) == 0 {
+			return done, nil
+		}
+		for _, row := range rows {
+			if opts.Limit > 0 && done >= opts.Limit {
+				return done, nil
+			}
+			if err := ctx.Err(); err != nil {
+				return done, err
+			}
+			afterID = row.ID
+			if done > 0 && opts.Sleep > 0 {
+				time.Sleep(opts.Sleep)
+			}
+			result, err := s.refreshWordRow(ctx, row, opts.DryRun)
+			done++
+			if onResult != nil {
+				onResult(row.Word, result, err)
+			}
+		}
+	}
+}
+
+func (s *Service) refreshWordRow(ctx context.Context, row *entity.Word, dryRun bool) (*WordRefreshResult, error) {
+	var droppedAudio []string
+	if !dryRun {
+		droppedAudio = s.dropSyntheticWordAudio(ctx, row)
+	}
+	fetched, err := s.wordFetcher.FetchAndStore(ctx, row.Word)
+	if err != nil {
+		if len(droppedAudio) > 0 {
+			s.ensureWordAudio(ctx, row)
+		}
+		return nil, fetchError(err)
+	}
+
+	result := &WordRefreshResult{
+		WordID:  row.ID,
+		Word:    row.Word,
+		Changed: []string{},
+		Kept:    []string{},
+	}
+	var origin *entity.WordOrigin
+	if row.Origin != nil {
+		copied := *row.Origin
+		origin = &copied
+	}
+	if origin != nil {
+		carrySentenceAudio(fetched.SentenceGroups, row.SentenceGroups, origin.SentenceGroups)
+	} else {
+		carrySentenceAudio(fetched.SentenceGroups, row.SentenceGroups)
+	}
+	mergeRefreshedField(result, "ph_en", &row.PhEn, originField(origin, func(o *entity.WordOrigin) *string { return &o.PhEn }), fetched.PhEn, fetched.PhEn == "")
+	mergeRefreshedField(result, "ph_am", &row.PhAm, originField(origin, func(o *entity.WordOrigin) *string { return &o.PhAm }), fetched.PhAm, fetched.PhAm == "")
+	mergeRefreshedField(result, "mean_tag", &row.MeanTag, originField(origin, func(o *entity.WordOrigin) *string { return &o.MeanTag }), fetched.MeanTag, fetched.MeanTag == "")
+	mergeRefreshedField(result, "parts", &row.Parts, originField(origin, func(o *entity.WordOrigin) *[]entity.WordPart { return &o.Parts }), fetched.Parts, len(fetched.Parts) == 0)
+	mergeRefreshedField(result, "sentence_groups", &row.SentenceGroups, originField(origin, func(o *entity.WordOrigin) *[]entity.WordSentenceGroup { return &o.SentenceGroups }), fetched.SentenceGroups, len(fetched.SentenceGroups) == 0)
+	row.Origin = origin
+
+	util.InfofWithRequest(ctx, "recite.refresh_word.diff", "word=%s changed=%v kept=%v dry_run=%t", row.Word, result.Changed, result.Kept, dryRun)
+	if dryRun {
+		return result, nil
+	}
+	now := time.Now()
+	row.FetchedAt = &now
+	row.NoAudioAccents = fetched.NoAudioAccents
+	s.settleSyntheticWordAudio(ctx, row, droppedAudio)
+	if err := s.wordRepo.Update(ctx, row); err != nil {
+		return nil, err
+	}
+	s.ensureWordAudio(ctx, row)
+	result.Updated = len(result.Changed) > 0 || len(result.Kept) > 0
+	return result, nil
+}
+
+func originField[T any](origin *entity.WordOrigin, pick func(*entity.WordOrigin) *T) *T {
+	if origin == nil {
+		return nil
+	}
+	return pick(origin)
+}
+
+// mergeRefreshedField applies a freshly fetched value. An empty fetched value
+// never overwrites stored content, and a field edited by the user (current
+// differs from origin) only gets its origin updated.
+func mergeRefreshedField[T any](result *WordRefreshResult, name string, current *T, origin *T, fresh T, freshEmpty bool) {
+	if freshEmpty {
+		return
+	}
+	base := current
+	if origin != nil {
+		base = origin
+	}
+	if sameJSON(*base, fresh) {
+		return
+	}
+	if origin != nil && !sameJSON(*current, *origin) {
+		*origin = fresh
+		result.Kept = append(result.Kept, name)
+		return
+	}
+	*current = fresh
+	if origin != nil {
+		*origin = fresh
+	}
+	result.Changed = append(result.Changed, name)
+}
+
+func sameJSON(a, b any) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(left) == string(right)
+}
@@ -0,0 +1,101 @@
+package recite
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/infra/recite/repository"
+	"github.com/wutianfang/moss/util/errcode"
+)
+
+const (
+	defaultWordSearchLimit = 20
+	maxWordSearchLimit     = 100
+)
+
+// WordSearchRequest searches the cached words. Mode is "prefix" for
+// autocomplete, "substring" or "meaning" for Chinese meanings; empty picks
+// meaning when the query contains Chinese and prefix otherwise.
+type WordSearchRequest struct {
+	Query       string
+	Mode        string
+	InUnit      bool
+	InForgotten bool
+	HasNotes    bool
+	Limit       int
+}
+
+type WordSearchItem struct {
+	ID      int64      `json:"id"`
+	Word    string     `json:"word"`
+	PhEn    string     `json:"ph_en"`
+	PhAm    string     `json:"ph_am"`
+	MeanTag string     `json:"mean_tag"`
+	Parts   []WordPart `json:"parts"`
+}
+
+// SearchWords searches the local dictionary cache; it never fetches.
+func (s *Service) SearchWords(ctx context.Context, req WordSearchRequest) ([]WordSearchItem, error) {
+	mode := strings.TrimSpace(req.Mode)
+	if mode == "" {
+		mode = repository.WordSearchPrefix
+		if containsHan(req.Query) {
+			mode = repository.WordSearchMeaning
+		}
+	}
+	var query string
+	switch mode {
+	case repository.WordSearchPrefix, repository.WordSearchSubstring:
+		query = fetcher.NormalizeWord(req.Query)
+	case repository.WordSearchMeaning:
+		query = strings.TrimSpace(req.Query)
+	default:
+		return nil, errcode.New(errcode.WordSearchModeInvalid)
+	}
+	if query == "" {
+		return nil, errcode.New(errcode.WordSearchQueryEmpty)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultWordSearchLimit
+	}
+	if limit > maxWordSearchLimit {
+		limit = maxWordSearchLimit
+	}
+
+	rows, err := s.wordRepo.Search(ctx, repository.WordSearchFilter{
+		Query:       query,
+		Mode:        mode,
+		InUnit:      req.InUnit,
+		InForgotten: req.InForgotten,
+		HasNotes:    req.HasNotes,
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]WordSearchItem, 0, len(rows))
+	for _, row := range rows {
+		info := buildWordInfo(row)
+		ret = append(ret, WordSearchItem{
+			ID:      info.ID,
+			Word:    info.Word,
+			PhEn:    info.PhEn,
+			PhAm:    info.PhAm,
+			MeanTag: info.MeanTag,
+			Parts:   info.Parts,
+		})
+	}
+	return ret, nil
+}
+
+func containsHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -23,12 +23,15 @@ import (
 const (
 	lokiDBPath  = "/Users/bytedance/go/src/github.com/wutianfang/loki/store/loki.db"
 	mossBaseURL = "http://14.103.100.153:1324/"
-	configPath  = "conf/config.yaml"
 )
 
 type options struct {
-	UnitIDs []int64
-	Sleep   time.Duration
+	UnitIDs    []int64
+	Sleep      time.Duration
+	ConfigPath string
+	// Token is the moss API token sent as "Authorization: Bearer"; the
+	// read scope is enough for word queries.
+	Token string
 }
 
 type lokiUnit struct {
@@ -59,7 +62,7 @@ func main() {
 		os.Exit(2)
 	}
 
-	cfg, err := conf.Load(configPath)
+	cfg, err := conf.Load(opts.ConfigPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "load config failed: %v\n", err)
 		os.Exit(1)
@@ -107,7 +110,7 @@ func main() {
 
 		for idx, item := range srcWords {
 			totalWords++
-			wordID, err := queryWordAndGetID(ctx, client, item.Word)
+			wordID, err := queryWordAndGetID(ctx, client, opts.Token, item.Word)
 			if err != nil {
 				failedWords++
 				fmt.Fprintf(os.Stderr, "  [%d/%d] word=%s query failed: %v\n",
@@ -138,8 +141,12 @@ func main() {
 func parseOptions() (*options, error) {
 	var unitIDsRaw string
 	var sleep time.Duration
+	var configPath string
+	var token string
 	flag.StringVar(&unitIDsRaw, "unit-ids", "", "comma-separated source unit ids in loki db, e.g. 1,2,3")
 	flag.DurationVar(&sleep, "sleep", time.Second, "sleep interval between each word query")
+	flag.StringVar(&configPath, "config", "", "moss config file, default "+conf.DefaultPath)
+	flag.StringVar(&token, "token", os.Getenv("MOSS_API_TOKEN"), "moss API token, default $MOSS_API_TOKEN")
 	flag.Parse()
 
 	unitIDs, err := parseIDList(unitIDsRaw)
@@ -155,8 +162,10 @@ func parseOptions() (*options, error) {
 	}
 
 	return &options{
-		UnitIDs: unitIDs,
-		Sleep:   sleep,
+		UnitIDs:    unitIDs,
+		Sleep:      sleep,
+		ConfigPath: configPath,
+		Token:      token,
 	}, nil
 }
 
@@ -311,7 +320,7 @@ func ensureTargetUnit(ctx context.Context, mysqlDB *sql.DB, srcUnit *lokiUnit) (
 	return newID, false, nil
 }
 
-func queryWordAndGetID(ctx context.Context, client *http.Client, word string) (int64, error) {
+func queryWordAndGetID(ctx context.Context, client *http.Client, token, word string) (int64, error) {
 	payload := map[string]string{"word": word}
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -324,6 +333,9 @@ func queryWordAndGetID(ctx context.Context, client *http.Client, word string) (i
 		return 0, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
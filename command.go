@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/conf"
+	"github.com/wutianfang/moss/infra/db"
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+	"github.com/wutianfang/moss/infra/recite/repository"
+)
+
+const commandUsage = `usage: moss [-config path] [-set key=value]...
+       moss command [flags]
+
+Without a command the HTTP server is started.
+
+commands:
+  words refresh   re-fetch cached dictionary entries that look stale
+  audio check     report missing, broken and orphan audio files
+  audio repair    re-download broken audio files and remove orphan files
+  fixtures update record live iciba pages for the parser regression tests
+  config print    print the effective config with secrets masked
+  token create    issue an API token, e.g. -name phone -scope quiz-write
+  token list      list API tokens with their scope and last use
+  token revoke    revoke the API token with the given id
+
+The config is layered: defaults, the config file, MOSS_* environment
+variables (mysql.dsn is MOSS_MYSQL_DSN), then -set flags.
+`
+
+// runCommand runs a maintenance sub command and returns the process exit code.
+func runCommand(args []string) int {
+	var err error
+	switch strings.Join(firstN(args, 2), " ") {
+	case "words refresh":
+		err = runWordsRefresh(args[2:])
+	case "audio check":
+		err = runAudioCheck(args[2:], false)
+	case "audio repair":
+		err = runAudioCheck(args[2:], true)
+	case "fixtures update":
+		err = runFixturesUpdate(args[2:])
+	case "config print":
+		err = runConfigPrint(args[2:])
+	case "token create":
+		err = runTokenCreate(args[2:])
+	case "token list":
+		err = runTokenList(args[2:])
+	case "token revoke":
+		err = runTokenRevoke(args[2:])
+	default:
+		fmt.Fprint(os.Stderr, commandUsage)
+		return 2
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", strings.Join(firstN(args, 2), " "), err)
+		return 1
+	}
+	return 0
+}
+
+func runWordsRefresh(args []string) error {
+	fs := flag.NewFlagSet("words refresh", flag.ContinueOnError)
+	missingMeanTag := fs.Bool("missing-mean-tag", false, "refresh words with an empty mean_tag")
+	noSentences := fs.Bool("no-sentences", false, "refresh words without example sentences")
+	before := fs.String("before", "", "refresh words last fetched before this date, yyyy-mm-dd")
+	all := fs.Bool("all", false, "refresh every cached word")
+	limit := fs.Int("limit", 0, "max number of words to refresh, 0 means no limit")
+	sleep := fs.Duration("sleep", time.Second, "sleep interval between two fetches")
+	dryRun := fs.Bool("dry-run", false, "only print the diff without updating rows (audio files are still downloaded)")
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := repository.WordRefreshFilter{
+		MissingMeanTag: *missingMeanTag,
+		NoSentences:    *noSentences,
+	}
+	if *before != "" {
+		t, err := time.ParseInLocation("2006-01-02", *before, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid -before %q: %w", *before, err)
+		}
+		filter.FetchedBefore = &t
+	}
+	if !*all && !filter.MissingMeanTag && !filter.NoSentences && filter.FetchedBefore == nil {
+		return errors.New("no filter given, use -missing-mean-tag, -no-sentences, -before or -all")
+	}
+	if *sleep < 0 {
+		return errors.New("sleep must be >= 0")
+	}
+
+	return withCommandDB(configFlags, func(cfg *conf.Config, database *sql.DB) error {
+		audioStore, err := newAudioStore(cfg)
+		if err != nil {
+			return err
+		}
+		svc := newReciteService(cfg, database, audioStore)
+		// let sentence audio downloads started by the command finish
+		defer svc.Shutdown(context.Background())
+		var failed int
+		total, err := svc.RefreshStaleWords(context.Background(), recite.WordRefreshOptions{
+			Filter: filter,
+			Limit:  *limit,
+			DryRun: *dryRun,
+			Sleep:  *sleep,
+		}, func(word string, result *recite.WordRefreshResult, err error) {
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "  word=%s refresh failed: %v\n", word, err)
+				return
+			}
+			fmt.Printf("  word=%s changed=%v kept=%v\n", word, result.Changed, result.Kept)
+		})
+		fmt.Printf("refresh done: total=%d failed=%d dry_run=%t\n", total, failed, *dryRun)
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d words failed", failed)
+		}
+		return nil
+	})
+}
+
+func runAudioCheck(args []string, repair bool) error {
+	name := "audio check"
+	if repair {
+		name = "audio repair"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return withCommandDB(configFlags, func(cfg *conf.Config, database *sql.DB) error {
+		audioStore, err := newAudioStore(cfg)
+		if err != nil {
+			return err
+		}
+		svc := newReciteService(cfg, database, audioStore)
+		// let sentence audio downloads started by the command finish
+		defer svc.Shutdown(context.Background())
+		report, err := svc.CheckAudio(context.Background(), repair)
+		if err != nil {
+			return err
+		}
+		for _, issue := range report.Issues {
+			line := fmt.Sprintf("  %s word=%q accent=%s key=%s", issue.Problem, issue.Word, issue.Accent, issue.Key)
+			if issue.Action != "" {
+				line += " action=" + issue.Action
+			}
+			if issue.Error != "" {
+				line += " error=" + issue.Error
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("%s done: words=%d issues=%d repaired=%d removed=%d unavailable=%d failed=%d\n",
+			name, report.WordCount, len(report.Issues), report.Repaired, report.Removed, report.Unavailable, report.Failed)
+		if report.Failed > 0 {
+			return fmt.Errorf("%d files could not be repaired", report.Failed)
+		}
+		return nil
+	})
+}
+
+// runFixturesUpdate records live iciba pages the parser tests replay. Without
+// words every existing fixture is refreshed. It needs no database.
+func runFixturesUpdate(args []string) error {
+	fs := flag.NewFlagSet("fixtures update", flag.ContinueOnError)
+	dir := fs.String("dir", "infra/recite/fetcher/testdata/iciba_recorded", "fixture directory")
+	sleep := fs.Duration("sleep", time.Second, "sleep interval between two fetches")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	words := fs.Args()
+	if len(words) == 0 {
+		fixtures, err := fetcher.ListIcibaFixtures(*dir)
+		if err != nil {
+			return err
+		}
+		for _, fixture := range fixtures {
+			words = append(words, fixture.Word)
+		}
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("no fixtures in %s, give the words to record", *dir)
+	}
+
+	icibaFetcher := fetcher.NewIcibaFetcher(nil)
+	var failed int
+	for i, word := range words {
+		if i > 0 && *sleep > 0 {
+			time.Sleep(*sleep)
+		}
+		fixture, err := icibaFetcher.RecordIcibaFixture(context.Background(), *dir, word)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "  word=%s record failed: %v\n", word, err)
+			continue
+		}
+		if fixture.Error != "" {
+			fmt.Printf("  word=%s recorded, parse error=%q\n", fixture.Word, fixture.Error)
+			continue
+		}
+		fmt.Printf("  word=%s recorded\n", fixture.Word)
+	}
+	fmt.Printf("fixtures update done: total=%d failed=%d dir=%s\n", len(words), failed, *dir)
+	if failed > 0 {
+		return fmt.Errorf("%d words failed", failed)
+	}
+	return nil
+}
+
+// runConfigPrint prints the effective config as YAML, with secrets masked.
+func runConfigPrint(args []string) error {
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := configFlags.load()
+	if err != nil {
+		return err
+	}
+	data, err := cfg.Masked().YAML()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// runTokenCreate issues an API token and prints its secret, which cannot be
+// shown again.
+func runTokenCreate(args []string) error {
+	fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+	name := fs.String("name", "", "what the token is for, e.g. the client using it")
+	scope := fs.String("scope", auth.ScopeRead, "one of "+strings.Join(auth.Scopes(), ", "))
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return withCommandDB(configFlags, func(cfg *conf.Config, database *sql.DB) error {
+		created, err := newAuthService(database).CreateToken(context.Background(), *name, *scope)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("token created: id=%d name=%q scope=%s\n", created.Token.ID, created.Token.Name, created.Token.Scope)
+		fmt.Printf("%s\n", created.Secret)
+		fmt.Fprintln(os.Stderr, "store the token now, it is not shown again")
+		return nil
+	})
+}
+
+func runTokenList(args []string) error {
+	fs := flag.NewFlagSet("token list", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return withCommandDB(configFlags, func(cfg *conf.Config, database *sql.DB) error {
+		tokens, err := newAuthService(database).ListTokens(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			lastUsed := "never"
+			if token.LastUsedAt != nil {
+				lastUsed = token.LastUsedAt.Format(time.DateTime)
+			}
+			line := fmt.Sprintf("  id=%d name=%q prefix=%s scope=%s created=%s last_used=%s",
+				token.ID, token.Name, token.TokenPrefix, token.Scope, token.CreatedAt.Format(time.DateTime), lastUsed)
+			if token.RevokedAt != nil {
+				line += " revoked=" + token.RevokedAt.Format(time.DateTime)
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("token list done: total=%d\n", len(tokens))
+		return nil
+	})
+}
+
+func runTokenRevoke(args []string) error {
+	fs := flag.NewFlagSet("token revoke", flag.ContinueOnError)
+	configFlags := addConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("give the id of the token to revoke")
+	}
+	tokenID, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid token id %q", fs.Arg(0))
+	}
+
+	return withCommandDB(configFlags, func(cfg *conf.Config, database *sql.DB) error {
+		if err := newAuthService(database).RevokeToken(context.Background(), tokenID); err != nil {
+			return err
+		}
+		fmt.Printf("token revoked: id=%d\n", tokenID)
+		return nil
+	})
+}
+
+// configFlags are the -config and -set flags shared by the server and the
+// commands reading the config.
+type configFlags struct {
+	path     string
+	settings stringList
+}
+
+func addConfigFlags(fs *flag.FlagSet) *configFlags {
+	f := &configFlags{}
+	fs.StringVar(&f.path, "config", "", "config file, default "+conf.DefaultPath)
+	fs.Var(&f.settings, "set", "override a config key, e.g. -set log.level=debug; repeatable")
+	return f
+}
+
+func (f *configFlags) load() (*conf.Config, error) {
+	return conf.LoadFrom(conf.Source{
+		Path:     f.path,
+		Env:      os.Environ(),
+		Settings: f.settings,
+	})
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// withCommandDB loads the config, connects and migrates MySQL, then runs fn.
+func withCommandDB(configFlags *configFlags, fn func(cfg *conf.Config, database *sql.DB) error) error {
+	cfg, err := configFlags.load()
+	if err != nil {
+		return err
+	}
+	database, err := db.InitMySQL(&cfg.MySQL)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	if err := db.AutoMigrate(database); err != nil {
+		return err
+	}
+	return fn(cfg, database)
+}
+
+func firstN(args []string, n int) []string {
+	if len(args) < n {
+		return args
+	}
+	return args[:n]
+}
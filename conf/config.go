@@ -1,23 +1,22 @@
 package conf
 
-import (
-	"fmt"
-	"os"
-	"strings"
-
-	"gopkg.in/yaml.v3"
-)
+import "strings"
 
 type Config struct {
 	Server  ConfigServer  `yaml:"server"`
 	MySQL   ConfigMySQL   `yaml:"mysql"`
+	Auth    ConfigAuth    `yaml:"auth"`
 	Storage ConfigStorage `yaml:"storage"`
 	Recite  ConfigRecite  `yaml:"recite"`
+	TTS     ConfigTTS     `yaml:"tts"`
 	Log     ConfigLog     `yaml:"log"`
 }
 
 type ConfigServer struct {
 	Addr string `yaml:"addr"`
+	// ShutdownTimeoutSec bounds how long a stopping server waits for in-flight
+	// requests and background jobs.
+	ShutdownTimeoutSec int `yaml:"shutdown_timeout_sec"`
 }
 
 type ConfigMySQL struct {
@@ -25,50 +24,122 @@ type ConfigMySQL struct {
 	MaxOpenConns       int    `yaml:"max_open_conns"`
 	MaxIdleConns       int    `yaml:"max_idle_conns"`
 	ConnMaxLifetimeSec int    `yaml:"conn_max_lifetime_sec"`
+	// ConnectTimeoutSec is how long the server keeps retrying MySQL at startup.
+	ConnectTimeoutSec int `yaml:"connect_timeout_sec"`
+}
+
+// ConfigAuth controls the API tokens checked on /api. With RequireToken every
+// request needs an "Authorization: Bearer" token; without it requests that
+// only read may omit one. Writes and the admin routes always need a token.
+// The first admin token is created with "moss token create".
+type ConfigAuth struct {
+	RequireToken bool `yaml:"require_token"`
 }
 
+// ConfigStorage selects where audio files are kept. Backend "local" uses
+// WordMP3Dir on disk; "s3" uses an S3 compatible bucket so several server
+// instances can share the library.
 type ConfigStorage struct {
-	WordMP3Dir string `yaml:"word_mp3_dir"`
+	WordMP3Dir string          `yaml:"word_mp3_dir"`
+	Backend    string          `yaml:"backend"`
+	S3         ConfigStorageS3 `yaml:"s3"`
 }
 
-type ConfigRecite struct {
-	DefaultAccent       string   `yaml:"default_accent"`
-	ReviewIntervalsDays []int    `yaml:"review_intervals_days"`
-	NoteTypes           []string `yaml:"note_types"`
+// ConfigStorageS3 configures the s3 backend. URLMode "proxy" streams audio
+// through this server, "presign" redirects clients to a presigned URL valid
+// for PresignExpirySec.
+type ConfigStorageS3 struct {
+	Endpoint         string `yaml:"endpoint"`
+	AccessKey        string `yaml:"access_key"`
+	SecretKey        string `yaml:"secret_key"`
+	Bucket           string `yaml:"bucket"`
+	Region           string `yaml:"region"`
+	Prefix           string `yaml:"prefix"`
+	UseSSL           bool   `yaml:"use_ssl"`
+	PathStyle        bool   `yaml:"path_style"`
+	URLMode          string `yaml:"url_mode"`
+	PresignExpirySec int    `yaml:"presign_expiry_sec"`
 }
 
-type ConfigLog struct {
-	Dir              string `yaml:"dir"`
-	EnableRequestLog bool   `yaml:"enable_request_log"`
+type ConfigRecite struct {
+	DefaultAccent       string                `yaml:"default_accent"`
+	ReviewIntervalsDays []int                 `yaml:"review_intervals_days"`
+	NoteTypes           []string              `yaml:"note_types"`
+	ForgottenPolicy     ConfigForgottenPolicy `yaml:"forgotten_policy"`
 }
 
-func Load(path string) (*Config, error) {
-	cfg := defaultConfig()
+// ConfigForgottenPolicy controls how quiz answers feed the forgotten list.
+// RememberAfterCorrect <= 0 disables the automatic remember.
+type ConfigForgottenPolicy struct {
+	AddOnForgotten       bool `yaml:"add_on_forgotten"`
+	AddOnWrong           bool `yaml:"add_on_wrong"`
+	RememberAfterCorrect int  `yaml:"remember_after_correct"`
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("read config failed: %w", err)
-	}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parse config failed: %w", err)
-	}
+// ConfigTTS configures the local text-to-speech fallback used when no
+// dictionary audio can be downloaded. Command gets the text on stdin; its
+// arguments may contain the placeholders {voice}, {wav} and {output}, and
+// {text} for programs that cannot read stdin. EncodeCommand, when set, turns
+// {wav} into the mp3 at {output}.
+type ConfigTTS struct {
+	Enabled       bool              `yaml:"enabled"`
+	Command       []string          `yaml:"command"`
+	EncodeCommand []string          `yaml:"encode_command"`
+	Voices        map[string]string `yaml:"voices"`
+	TimeoutSec    int               `yaml:"timeout_sec"`
+}
 
-	fillDefault(cfg)
-	return cfg, nil
+// ConfigLog configures logging. Level is debug, info, warn or error and
+// Levels overrides it per package, keyed by import path relative to the
+// module. Format is "json" or "text". The file log.dir/moss.log is rotated
+// when it reaches MaxSizeMB and at every Rotate period ("daily", "hourly" or
+// "none"); rotated files older than MaxAgeDays or beyond MaxBackups are removed.
+type ConfigLog struct {
+	Dir        string            `yaml:"dir"`
+	Level      string            `yaml:"level"`
+	Levels     map[string]string `yaml:"levels"`
+	Format     string            `yaml:"format"`
+	Stdout     bool              `yaml:"stdout"`
+	AccessLog  bool              `yaml:"access_log"`
+	Rotate     string            `yaml:"rotate"`
+	MaxSizeMB  int               `yaml:"max_size_mb"`
+	MaxBackups int               `yaml:"max_backups"`
+	MaxAgeDays int               `yaml:"max_age_days"`
+	Compress   bool              `yaml:"compress"`
 }
 
 func defaultConfig() *Config {
 	cfg := &Config{}
 	cfg.Server.Addr = ":1324"
+	cfg.Server.ShutdownTimeoutSec = 20
 	cfg.MySQL.MaxOpenConns = 10
 	cfg.MySQL.MaxIdleConns = 5
 	cfg.MySQL.ConnMaxLifetimeSec = 300
+	cfg.MySQL.ConnectTimeoutSec = 60
 	cfg.Storage.WordMP3Dir = "store/word_mp3"
+	cfg.Storage.Backend = "local"
+	cfg.Storage.S3.URLMode = "proxy"
+	cfg.Storage.S3.PresignExpirySec = 3600
 	cfg.Recite.DefaultAccent = "en"
 	cfg.Recite.ReviewIntervalsDays = []int{1, 2, 4, 7, 15, 30}
 	cfg.Recite.NoteTypes = []string{"近义词", "反义词", "关联词跟"}
+	cfg.Recite.ForgottenPolicy.AddOnForgotten = false
+	cfg.Recite.ForgottenPolicy.AddOnWrong = false
+	cfg.Recite.ForgottenPolicy.RememberAfterCorrect = 0
+	cfg.TTS.Enabled = false
+	cfg.TTS.Command = []string{"espeak-ng", "-v", "{voice}", "-w", "{wav}", "--stdin"}
+	cfg.TTS.EncodeCommand = []string{"lame", "--quiet", "{wav}", "{output}"}
+	cfg.TTS.Voices = map[string]string{"en": "en-gb", "am": "en-us"}
+	cfg.TTS.TimeoutSec = 20
 	cfg.Log.Dir = "log"
-	cfg.Log.EnableRequestLog = false
+	cfg.Log.Level = "info"
+	cfg.Log.Format = "json"
+	cfg.Log.Stdout = true
+	cfg.Log.AccessLog = true
+	cfg.Log.Rotate = "daily"
+	cfg.Log.MaxSizeMB = 100
+	cfg.Log.MaxBackups = 30
+	cfg.Log.MaxAgeDays = 30
 	return cfg
 }
 
@@ -76,6 +147,9 @@ func fillDefault(cfg *Config) {
 	if cfg.Server.Addr == "" {
 		cfg.Server.Addr = ":1324"
 	}
+	if cfg.Server.ShutdownTimeoutSec <= 0 {
+		cfg.Server.ShutdownTimeoutSec = 20
+	}
 	if cfg.MySQL.MaxOpenConns <= 0 {
 		cfg.MySQL.MaxOpenConns = 10
 	}
@@ -85,15 +159,51 @@ func fillDefault(cfg *Config) {
 	if cfg.MySQL.ConnMaxLifetimeSec <= 0 {
 		cfg.MySQL.ConnMaxLifetimeSec = 300
 	}
+	if cfg.MySQL.ConnectTimeoutSec < 0 {
+		cfg.MySQL.ConnectTimeoutSec = 0
+	}
 	if cfg.Storage.WordMP3Dir == "" {
 		cfg.Storage.WordMP3Dir = "store/word_mp3"
 	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Storage.Backend)) {
+	case "s3":
+		cfg.Storage.Backend = "s3"
+	default:
+		cfg.Storage.Backend = "local"
+	}
+	if cfg.Storage.S3.URLMode != "presign" {
+		cfg.Storage.S3.URLMode = "proxy"
+	}
+	if cfg.Storage.S3.PresignExpirySec <= 0 {
+		cfg.Storage.S3.PresignExpirySec = 3600
+	}
 	cfg.Recite.DefaultAccent = normalizeAccent(cfg.Recite.DefaultAccent)
 	cfg.Recite.ReviewIntervalsDays = normalizeReviewIntervals(cfg.Recite.ReviewIntervalsDays)
 	cfg.Recite.NoteTypes = normalizeNoteTypes(cfg.Recite.NoteTypes)
+	if cfg.Recite.ForgottenPolicy.RememberAfterCorrect < 0 {
+		cfg.Recite.ForgottenPolicy.RememberAfterCorrect = 0
+	}
+	if len(cfg.TTS.Command) == 0 {
+		cfg.TTS.Command = []string{"espeak-ng", "-v", "{voice}", "-w", "{wav}", "--stdin"}
+	}
+	if cfg.TTS.TimeoutSec <= 0 {
+		cfg.TTS.TimeoutSec = 20
+	}
 	if cfg.Log.Dir == "" {
 		cfg.Log.Dir = "log"
 	}
+	if cfg.Log.Level == "" {
+		cfg.Log.Level = "info"
+	}
+	if cfg.Log.Format == "" {
+		cfg.Log.Format = "json"
+	}
+	if cfg.Log.Rotate == "" {
+		cfg.Log.Rotate = "daily"
+	}
+	if cfg.Log.MaxSizeMB <= 0 {
+		cfg.Log.MaxSizeMB = 100
+	}
 }
 
 func normalizeAccent(raw string) string {
@@ -0,0 +1,169 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the config file read when no path is given.
+const DefaultPath = "conf/config.yaml"
+
+// EnvPrefix starts the environment variables overriding config keys:
+// mysql.dsn is MOSS_MYSQL_DSN, storage.s3.secret_key is
+// MOSS_STORAGE_S3_SECRET_KEY.
+const EnvPrefix = "MOSS_"
+
+// Source lists the layers applied over the defaults, in this order: the
+// config file, the environment, then "key=value" settings from flags.
+//
+// An empty Path reads DefaultPath when it exists, so a deployment can be
+// configured by environment alone. Values of list and map keys are written in
+// YAML flow style, e.g. recite.review_intervals_days=[1,3,7].
+type Source struct {
+	Path     string
+	Env      []string
+	Settings []string
+}
+
+// Load reads the config file at path with the environment of the process
+// layered on top.
+func Load(path string) (*Config, error) {
+	return LoadFrom(Source{Path: path, Env: os.Environ()})
+}
+
+// LoadFrom builds the config from src. Bad overrides and invalid values are
+// reported together in one *ValidationError.
+func LoadFrom(src Source) (*Config, error) {
+	cfg := defaultConfig()
+
+	if err := loadFile(cfg, src.Path); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	keys := configKeys(cfg)
+	envNames := envKeys(keys)
+	for _, pair := range src.Env {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		// unknown MOSS_ variables are left alone, they may belong to scripts
+		key := envNames[name]
+		if key == "" {
+			continue
+		}
+		if err := setValue(keys[key], value); err != nil {
+			problems = append(problems, fmt.Sprintf("env %s: %v", name, err))
+		}
+	}
+	for _, setting := range src.Settings {
+		key, value, ok := strings.Cut(setting, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("setting %q: want key=value", setting))
+			continue
+		}
+		field, known := keys[key]
+		if !known {
+			problems = append(problems, fmt.Sprintf("setting %s: unknown key", key))
+			continue
+		}
+		if err := setValue(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("setting %s: %v", key, err))
+		}
+	}
+
+	problems = append(problems, validate(cfg)...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	fillDefault(cfg)
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read config failed: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse config failed: %w", err)
+	}
+	return nil
+}
+
+// Keys returns every config key in dotted form ("mysql.dsn"), sorted.
+func Keys() []string {
+	keys := configKeys(defaultConfig())
+	ret := make([]string, 0, len(keys))
+	for key := range keys {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// EnvName returns the environment variable overriding key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// configKeys maps the dotted yaml key of every leaf field of cfg to the field.
+func configKeys(cfg *Config) map[string]reflect.Value {
+	keys := make(map[string]reflect.Value)
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			field := v.Field(i)
+			if field.Kind() == reflect.Struct {
+				walk(prefix+name+".", field)
+				continue
+			}
+			keys[prefix+name] = field
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return keys
+}
+
+func envKeys(keys map[string]reflect.Value) map[string]string {
+	ret := make(map[string]string, len(keys))
+	for key := range keys {
+		ret[EnvName(key)] = key
+	}
+	return ret
+}
+
+// setValue replaces field with raw. Strings are taken verbatim so a DSN or a
+// secret never goes through YAML; other kinds are parsed as YAML.
+func setValue(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(raw)
+		return nil
+	}
+	value := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(raw), value.Interface()); err != nil {
+		return fmt.Errorf("invalid %s value %q", field.Type(), raw)
+	}
+	field.Set(value.Elem())
+	return nil
+}
@@ -0,0 +1,184 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDSN = "moss:secret@tcp(127.0.0.1:3306)/moss"
+
+// writeConfig writes a config file into a temporary directory.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+// chdir moves into dir for the rest of the test, so DefaultPath resolves
+// inside it.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(previous) })
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		env      []string
+		settings []string
+		want     string
+	}{
+		{"defaults", "", nil, nil, ":1324"},
+		{"file over defaults", "server:\n  addr: \":2000\"\n", nil, nil, ":2000"},
+		{"env over file", "server:\n  addr: \":2000\"\n", []string{"MOSS_SERVER_ADDR=:3000"}, nil, ":3000"},
+		{"setting over env", "server:\n  addr: \":2000\"\n", []string{"MOSS_SERVER_ADDR=:3000"}, []string{"server.addr=:4000"}, ":4000"},
+		{"setting over file", "server:\n  addr: \":2000\"\n", nil, []string{"server.addr=:4000"}, ":4000"},
+		{"other env ignored", "", []string{"SERVER_ADDR=:3000", "MOSS_UNKNOWN=1"}, nil, ":1324"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, "mysql:\n  dsn: \""+testDSN+"\"\n"+tc.file)
+			cfg, err := LoadFrom(Source{Path: path, Env: tc.env, Settings: tc.settings})
+			if err != nil {
+				t.Fatalf("LoadFrom error: %v", err)
+			}
+			if cfg.Server.Addr != tc.want {
+				t.Fatalf("server.addr = %q, want %q", cfg.Server.Addr, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadValueKinds(t *testing.T) {
+	cfg, err := LoadFrom(Source{
+		Path: writeConfig(t, "recite:\n  review_intervals_days: [2, 5]\n"),
+		Env: []string{
+			"MOSS_MYSQL_DSN=" + testDSN,
+			"MOSS_TTS_ENABLED=true",
+			"MOSS_MYSQL_MAX_OPEN_CONNS=20",
+		},
+		Settings: []string{
+			"recite.review_intervals_days=[1,3,7]",
+			"tts.voices={en: en-gb-x-rp}",
+		},
+	})
+	if err != nil {
+		t.Fatalf("LoadFrom error: %v", err)
+	}
+	if cfg.MySQL.DSN != testDSN {
+		t.Errorf("mysql.dsn = %q, want it verbatim", cfg.MySQL.DSN)
+	}
+	if !cfg.TTS.Enabled || cfg.MySQL.MaxOpenConns != 20 {
+		t.Errorf("tts.enabled = %t, max_open_conns = %d", cfg.TTS.Enabled, cfg.MySQL.MaxOpenConns)
+	}
+	if got := cfg.Recite.ReviewIntervalsDays; len(got) != 3 || got[0] != 1 || got[2] != 7 {
+		t.Errorf("review_intervals_days = %v, want [1 3 7]", got)
+	}
+	if got := cfg.TTS.Voices; len(got) != 1 || got["en"] != "en-gb-x-rp" {
+		t.Errorf("tts.voices = %v, want the map replaced", got)
+	}
+}
+
+func TestLoadPath(t *testing.T) {
+	env := []string{"MOSS_MYSQL_DSN=" + testDSN}
+
+	// no file at the default path: environment alone configures moss
+	chdir(t, t.TempDir())
+	cfg, err := LoadFrom(Source{Env: env})
+	if err != nil {
+		t.Fatalf("LoadFrom without a default file error: %v", err)
+	}
+	if cfg.Server.Addr != ":1324" {
+		t.Errorf("server.addr = %q, want the default", cfg.Server.Addr)
+	}
+
+	// a missing explicit path is an error
+	_, err = LoadFrom(Source{Path: "missing.yaml", Env: env})
+	if err == nil || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFrom(missing.yaml) error = %v, want not exist", err)
+	}
+
+	// the default path is read when it exists
+	if err := os.MkdirAll(filepath.Dir(DefaultPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(DefaultPath, []byte("server:\n  addr: \":2000\"\n"), 0o644); err != nil {
+		t.Fatalf("write default config: %v", err)
+	}
+	cfg, err = LoadFrom(Source{Env: env})
+	if err != nil {
+		t.Fatalf("LoadFrom with a default file error: %v", err)
+	}
+	if cfg.Server.Addr != ":2000" {
+		t.Errorf("server.addr = %q, want the default file value", cfg.Server.Addr)
+	}
+
+	if _, err := LoadFrom(Source{Path: writeConfig(t, "server: [")}); err == nil || !strings.Contains(err.Error(), "parse config failed") {
+		t.Errorf("broken file error = %v, want a parse error", err)
+	}
+}
+
+// TestLoadProblems checks that every problem is reported at once.
+func TestLoadProblems(t *testing.T) {
+	_, err := LoadFrom(Source{
+		Path: writeConfig(t, "storage:\n  backend: s3\nlog:\n  rotate: weekly\n"),
+		Env:  []string{"MOSS_MYSQL_MAX_OPEN_CONNS=many"},
+		Settings: []string{
+			"server.addr",
+			"nope.key=1",
+			"recite.review_intervals_days=[0]",
+		},
+	})
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("LoadFrom error = %v, want a ValidationError", err)
+	}
+	want := []string{
+		"env MOSS_MYSQL_MAX_OPEN_CONNS: invalid int value",
+		`setting "server.addr": want key=value`,
+		"setting nope.key: unknown key",
+		"mysql.dsn: required",
+		"storage.s3.endpoint: required",
+		"storage.s3.bucket: required",
+		"recite.review_intervals_days: 0 is not a positive number of days",
+		`log.rotate: "weekly" is not one of`,
+	}
+	if len(validation.Problems) != len(want) {
+		t.Fatalf("problems = %q, want %d", validation.Problems, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(validation.Problems[i], prefix) {
+			t.Errorf("problem %d = %q, want prefix %q", i, validation.Problems[i], prefix)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys()
+	for _, key := range []string{"mysql.dsn", "storage.s3.secret_key", "recite.forgotten_policy.add_on_wrong", "log.levels"} {
+		found := false
+		for _, item := range keys {
+			found = found || item == key
+		}
+		if !found {
+			t.Errorf("Keys() lacks %q", key)
+		}
+	}
+	if got := EnvName("storage.s3.secret_key"); got != "MOSS_STORAGE_S3_SECRET_KEY" {
+		t.Errorf("EnvName = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package conf
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const secretMask = "******"
+
+// Masked returns a copy of cfg with credentials replaced, safe to print or
+// log: the S3 keys and the password inside the MySQL DSN.
+func (c *Config) Masked() *Config {
+	clone := *c
+	clone.MySQL.DSN = maskDSNPassword(c.MySQL.DSN)
+	if clone.Storage.S3.AccessKey != "" {
+		clone.Storage.S3.AccessKey = secretMask
+	}
+	if clone.Storage.S3.SecretKey != "" {
+		clone.Storage.S3.SecretKey = secretMask
+	}
+	return &clone
+}
+
+// YAML renders cfg in the config file format.
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maskDSNPassword hides the password of a go-sql-driver DSN,
+// "user:password@tcp(host:3306)/db?params". Like the driver it takes the last
+// "@" before the database name, so a password may itself contain "@".
+func maskDSNPassword(dsn string) string {
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		slash = len(dsn)
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+	return dsn[:colon+1] + secretMask + dsn[at:]
+}
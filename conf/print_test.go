@@ -0,0 +1,38 @@
+package conf
+
+import "testing"
+
+func TestMaskDSNPassword(t *testing.T) {
+	cases := []struct {
+		dsn, want string
+	}{
+		{"moss:secret@tcp(127.0.0.1:3306)/moss?parseTime=true", "moss:******@tcp(127.0.0.1:3306)/moss?parseTime=true"},
+		{"moss:p@ss@tcp(db:3306)/moss", "moss:******@tcp(db:3306)/moss"},
+		{"moss:p@ss:w/rd@tcp(db:3306)/moss", "moss:******@tcp(db:3306)/moss"},
+		{"moss@tcp(db:3306)/moss", "moss@tcp(db:3306)/moss"},
+		{"moss:secret@/moss", "moss:******@/moss"},
+		{"/moss", "/moss"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := maskDSNPassword(tc.dsn); got != tc.want {
+			t.Errorf("maskDSNPassword(%q) = %q, want %q", tc.dsn, got, tc.want)
+		}
+	}
+}
+
+func TestMasked(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.MySQL.DSN = testDSN
+	cfg.Storage.S3.AccessKey = "AKIA"
+	masked := cfg.Masked()
+	if masked.MySQL.DSN != "moss:******@tcp(127.0.0.1:3306)/moss" || masked.Storage.S3.AccessKey != secretMask {
+		t.Fatalf("masked = %+v / %+v", masked.MySQL, masked.Storage.S3)
+	}
+	if masked.Storage.S3.SecretKey != "" {
+		t.Errorf("an empty secret key must stay empty, got %q", masked.Storage.S3.SecretKey)
+	}
+	if cfg.MySQL.DSN != testDSN || cfg.Storage.S3.AccessKey != "AKIA" {
+		t.Errorf("Masked changed the original config")
+	}
+}
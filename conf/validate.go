@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ValidationError lists every problem found while loading a config, so they
+// can be fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// validate checks the layered values before fillDefault; empty values that
+// fillDefault fills in are accepted.
+func validate(cfg *Config) []string {
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			return
+		}
+		for _, item := range allowed {
+			if value == item {
+				return
+			}
+		}
+		addf("%s: %q is not one of %s", key, value, strings.Join(allowed, ", "))
+	}
+
+	if cfg.Server.Addr != "" {
+		if _, _, err := net.SplitHostPort(cfg.Server.Addr); err != nil {
+			addf("server.addr: %v", err)
+		}
+	}
+
+	if strings.TrimSpace(cfg.MySQL.DSN) == "" {
+		addf("mysql.dsn: required, set it in the config file or %s", EnvName("mysql.dsn"))
+	}
+	if cfg.MySQL.MaxOpenConns > 0 && cfg.MySQL.MaxIdleConns > cfg.MySQL.MaxOpenConns {
+		addf("mysql.max_idle_conns: %d is above max_open_conns %d", cfg.MySQL.MaxIdleConns, cfg.MySQL.MaxOpenConns)
+	}
+
+	oneOf("storage.backend", cfg.Storage.Backend, "local", "s3")
+	if strings.EqualFold(strings.TrimSpace(cfg.Storage.Backend), "s3") {
+		if cfg.Storage.S3.Endpoint == "" {
+			addf("storage.s3.endpoint: required by the s3 backend")
+		}
+		if cfg.Storage.S3.Bucket == "" {
+			addf("storage.s3.bucket: required by the s3 backend")
+		}
+		oneOf("storage.s3.url_mode", cfg.Storage.S3.URLMode, "proxy", "presign")
+	}
+
+	oneOf("recite.default_accent", cfg.Recite.DefaultAccent, "en", "am")
+	for _, days := range cfg.Recite.ReviewIntervalsDays {
+		if days <= 0 {
+			addf("recite.review_intervals_days: %d is not a positive number of days", days)
+		}
+	}
+
+	if cfg.TTS.Enabled && len(cfg.TTS.Command) == 0 {
+		addf("tts.command: required when tts is enabled")
+	}
+
+	logLevels := []string{"debug", "info", "warn", "warning", "error"}
+	oneOf("log.level", cfg.Log.Level, logLevels...)
+	for pkg, level := range cfg.Log.Levels {
+		oneOf("log.levels."+pkg, level, logLevels...)
+	}
+	oneOf("log.format", cfg.Log.Format, "json", "text")
+	oneOf("log.rotate", cfg.Log.Rotate, "daily", "hourly", "none")
+	return problems
+}
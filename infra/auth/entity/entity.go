@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// APIToken is an API credential. Only the SHA-256 of the token is stored;
+// TokenPrefix keeps its first characters so users can tell tokens apart.
+type APIToken struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TokenHash   string     `json:"-"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scope       string     `json:"scope"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,105 @@
+// Package memory holds an in-memory TokenRepository for running the auth
+// service in tests without a database.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wutianfang/moss/infra/auth/entity"
+)
+
+type TokenRepository struct {
+	mu     sync.Mutex
+	lastID int64
+	tokens []entity.APIToken
+	// Touches counts TouchLastUsed calls so tests can see the throttling.
+	Touches int
+}
+
+func NewTokenRepository() *TokenRepository {
+	return &TokenRepository{}
+}
+
+func (r *TokenRepository) Create(ctx context.Context, token *entity.APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	token.ID = r.lastID
+	token.CreatedAt = time.Now().Truncate(time.Second)
+	r.tokens = append(r.tokens, *token)
+	return nil
+}
+
+// List returns every token, revoked ones included, newest first.
+func (r *TokenRepository) List(ctx context.Context) ([]entity.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := make([]entity.APIToken, 0, len(r.tokens))
+	for i := len(r.tokens) - 1; i >= 0; i-- {
+		ret = append(ret, r.tokens[i])
+	}
+	return ret, nil
+}
+
+func (r *TokenRepository) GetByID(ctx context.Context, id int64) (*entity.APIToken, error) {
+	return r.find(func(token *entity.APIToken) bool { return token.ID == id }), nil
+}
+
+func (r *TokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.APIToken, error) {
+	return r.find(func(token *entity.APIToken) bool { return token.TokenHash == tokenHash }), nil
+}
+
+// Revoke marks a token revoked; revoking it again keeps the first time.
+func (r *TokenRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	r.update(id, func(token *entity.APIToken) {
+		if token.RevokedAt == nil {
+			at := at.Truncate(time.Second)
+			token.RevokedAt = &at
+		}
+	})
+	return nil
+}
+
+func (r *TokenRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	r.update(id, func(token *entity.APIToken) {
+		at := at.Truncate(time.Second)
+		token.LastUsedAt = &at
+	})
+	r.mu.Lock()
+	r.Touches++
+	r.mu.Unlock()
+	return nil
+}
+
+// SetLastUsed backdates the last use of a token.
+func (r *TokenRepository) SetLastUsed(id int64, at time.Time) {
+	r.update(id, func(token *entity.APIToken) {
+		token.LastUsedAt = &at
+	})
+}
+
+// find returns a copy of the first matching token, like a fresh row scan.
+func (r *TokenRepository) find(match func(*entity.APIToken) bool) *entity.APIToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.tokens {
+		if match(&r.tokens[i]) {
+			item := r.tokens[i]
+			return &item
+		}
+	}
+	return nil
+}
+
+func (r *TokenRepository) update(id int64, apply func(*entity.APIToken)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.tokens {
+		if r.tokens[i].ID == id {
+			apply(&r.tokens[i])
+			return
+		}
+	}
+}
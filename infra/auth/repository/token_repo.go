@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/wutianfang/moss/infra/auth/entity"
+)
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository(db *sql.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) Create(ctx context.Context, token *entity.APIToken) error {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_tokens(name, token_hash, token_prefix, scope)
+		VALUES(?, ?, ?, ?)
+	`, token.Name, token.TokenHash, token.TokenPrefix, token.Scope)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = id
+	token.CreatedAt = time.Now()
+	return nil
+}
+
+// List returns every token, revoked ones included, newest first.
+func (r *TokenRepository) List(ctx context.Context) ([]entity.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, token_hash, token_prefix, scope, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]entity.APIToken, 0)
+	for rows.Next() {
+		item, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, *item)
+	}
+	return ret, rows.Err()
+}
+
+func (r *TokenRepository) GetByID(ctx context.Context, id int64) (*entity.APIToken, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, name, token_hash, token_prefix, scope, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		WHERE id = ?
+		LIMIT 1
+	`, id)
+	item, err := scanToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return item, err
+}
+
+func (r *TokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.APIToken, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, name, token_hash, token_prefix, scope, last_used_at, revoked_at, created_at
+		FROM api_tokens
+		WHERE token_hash = ?
+		LIMIT 1
+	`, tokenHash)
+	item, err := scanToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return item, err
+}
+
+// Revoke marks a token revoked; revoking it again keeps the first time.
+func (r *TokenRepository) Revoke(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET revoked_at = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`, at, id)
+	return err
+}
+
+func (r *TokenRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET last_used_at = ?
+		WHERE id = ?
+	`, at, id)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanToken(s scanner) (*entity.APIToken, error) {
+	item := &entity.APIToken{}
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := s.Scan(
+		&item.ID,
+		&item.Name,
+		&item.TokenHash,
+		&item.TokenPrefix,
+		&item.Scope,
+		&lastUsedAt,
+		&revokedAt,
+		&item.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		item.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		item.RevokedAt = &revokedAt.Time
+	}
+	return item, nil
+}
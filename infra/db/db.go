@@ -1,15 +1,72 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/wutianfang/moss/conf"
+	"github.com/wutianfang/moss/util"
 )
 
 func InitMySQL(cfg *conf.ConfigMySQL) (*sql.DB, error) {
+	db, err := openMySQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("sql ping failed: %w", err)
+	}
+	return db, nil
+}
+
+// Backoff bounds of ConnectMySQL.
+const (
+	connectFirstBackoff = 500 * time.Millisecond
+	connectMaxBackoff   = 10 * time.Second
+)
+
+// ConnectMySQL is InitMySQL for the server: while MySQL is not reachable yet
+// it keeps pinging with exponential backoff, for up to
+// cfg.ConnectTimeoutSec or until ctx ends.
+func ConnectMySQL(ctx context.Context, cfg *conf.ConfigMySQL) (*sql.DB, error) {
+	db, err := openMySQL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(time.Duration(cfg.ConnectTimeoutSec) * time.Second)
+	backoff := connectFirstBackoff
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+		wait := backoff
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		if wait <= 0 {
+			break
+		}
+		util.Warnf("mysql ping failed, retrying in %s: attempt=%d err=%v", wait.Round(time.Millisecond), attempt, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			_ = db.Close()
+			return nil, fmt.Errorf("sql ping cancelled: %w", ctx.Err())
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+	_ = db.Close()
+	return nil, fmt.Errorf("sql ping failed after %ds: %w", cfg.ConnectTimeoutSec, err)
+}
+
+func openMySQL(cfg *conf.ConfigMySQL) (*sql.DB, error) {
 	db, err := sql.Open("mysql", cfg.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("sql open failed: %w", err)
@@ -17,8 +74,5 @@ func InitMySQL(cfg *conf.ConfigMySQL) (*sql.DB, error) {
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeSec) * time.Second)
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("sql ping failed: %w", err)
-	}
 	return db, nil
 }
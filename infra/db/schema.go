@@ -15,9 +15,18 @@ var ddlList = []string{
 		mean_tag VARCHAR(255) NOT NULL DEFAULT '',
 		parts_json LONGTEXT NOT NULL,
 		sentences_json LONGTEXT NOT NULL,
+		mnemonic VARCHAR(1024) NOT NULL DEFAULT '',
+		custom_parts_json LONGTEXT NULL,
+		custom_mode VARCHAR(16) NOT NULL DEFAULT '',
+		origin_json LONGTEXT NULL,
+		edited_at DATETIME NULL DEFAULT NULL,
+		fetched_at DATETIME NULL DEFAULT NULL,
+		synthetic_audio VARCHAR(16) NOT NULL DEFAULT '',
+		no_audio VARCHAR(16) NOT NULL DEFAULT '',
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-		UNIQUE KEY uq_word(word)
+		UNIQUE KEY uq_word(word),
+		FULLTEXT KEY ft_words_meaning(mean_tag, parts_json) WITH PARSER ngram
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	`CREATE TABLE IF NOT EXISTS recite_units (
 		id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -35,6 +44,7 @@ var ddlList = []string{
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE KEY uq_unit_word(unit_id, word_id),
 		KEY idx_unit_created(unit_id, created_at, id),
+		KEY idx_unit_words_word(word_id),
 		CONSTRAINT fk_unit_word_unit FOREIGN KEY (unit_id) REFERENCES recite_units(id),
 		CONSTRAINT fk_unit_word_word FOREIGN KEY (word_id) REFERENCES words(id)
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
@@ -43,7 +53,8 @@ var ddlList = []string{
 		remembered TINYINT NOT NULL DEFAULT 0,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		KEY idx_word(word),
-		KEY idx_remembered(remembered)
+		KEY idx_remembered(remembered),
+		KEY idx_word_remembered(word, remembered)
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	`CREATE TABLE IF NOT EXISTS quizzes (
 		id BIGINT PRIMARY KEY AUTO_INCREMENT,
@@ -66,6 +77,7 @@ var ddlList = []string{
 		status VARCHAR(16) NOT NULL DEFAULT '未测试',
 		input_answer VARCHAR(255) NOT NULL DEFAULT '',
 		result VARCHAR(16) NOT NULL DEFAULT '',
+		choices_json TEXT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 		UNIQUE KEY uq_quiz_order(quiz_id, order_no),
@@ -80,13 +92,18 @@ var ddlList = []string{
 		content LONGTEXT NOT NULL,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		deleted_at DATETIME NULL DEFAULT NULL,
 		KEY idx_note_created(created_at, id),
-		KEY idx_note_type_created(note_type, created_at, id)
+		KEY idx_note_deleted(deleted_at, id),
+		KEY idx_note_type_created(note_type, created_at, id),
+		KEY idx_note_updated(updated_at, id),
+		FULLTEXT KEY ft_notes_content(content) WITH PARSER ngram
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	`CREATE TABLE IF NOT EXISTS note_words (
 		id BIGINT PRIMARY KEY AUTO_INCREMENT,
 		note_id BIGINT NOT NULL,
 		word_id BIGINT NOT NULL,
+		from_ref TINYINT(1) NOT NULL DEFAULT 0,
 		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		UNIQUE KEY uq_note_word(note_id, word_id),
 		KEY idx_note_words_word(word_id, note_id),
@@ -94,6 +111,26 @@ var ddlList = []string{
 		CONSTRAINT fk_note_words_note FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
 		CONSTRAINT fk_note_words_word FOREIGN KEY (word_id) REFERENCES words(id)
 	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+	`CREATE TABLE IF NOT EXISTS word_forms (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT,
+		word_id BIGINT NOT NULL,
+		form VARCHAR(128) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_word_form(word_id, form),
+		KEY idx_word_forms_form(form),
+		CONSTRAINT fk_word_forms_word FOREIGN KEY (word_id) REFERENCES words(id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+	`CREATE TABLE IF NOT EXISTS api_tokens (
+		id BIGINT PRIMARY KEY AUTO_INCREMENT,
+		name VARCHAR(128) NOT NULL,
+		token_hash CHAR(64) NOT NULL,
+		token_prefix VARCHAR(16) NOT NULL,
+		scope VARCHAR(16) NOT NULL,
+		last_used_at DATETIME NULL DEFAULT NULL,
+		revoked_at DATETIME NULL DEFAULT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_token_hash(token_hash)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 }
 
 func AutoMigrate(db *sql.DB) error {
@@ -106,6 +143,30 @@ func AutoMigrate(db *sql.DB) error {
 	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN mean_tag VARCHAR(255) NOT NULL DEFAULT '' AFTER ph_am`); err != nil {
 		return fmt.Errorf("add words.mean_tag failed: %w", err)
 	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN mnemonic VARCHAR(1024) NOT NULL DEFAULT '' AFTER sentences_json`); err != nil {
+		return fmt.Errorf("add words.mnemonic failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN custom_parts_json LONGTEXT NULL AFTER mnemonic`); err != nil {
+		return fmt.Errorf("add words.custom_parts_json failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN custom_mode VARCHAR(16) NOT NULL DEFAULT '' AFTER custom_parts_json`); err != nil {
+		return fmt.Errorf("add words.custom_mode failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN origin_json LONGTEXT NULL AFTER custom_mode`); err != nil {
+		return fmt.Errorf("add words.origin_json failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN edited_at DATETIME NULL DEFAULT NULL AFTER origin_json`); err != nil {
+		return fmt.Errorf("add words.edited_at failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN fetched_at DATETIME NULL DEFAULT NULL AFTER edited_at`); err != nil {
+		return fmt.Errorf("add words.fetched_at failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN synthetic_audio VARCHAR(16) NOT NULL DEFAULT '' AFTER fetched_at`); err != nil {
+		return fmt.Errorf("add words.synthetic_audio failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE words ADD COLUMN no_audio VARCHAR(16) NOT NULL DEFAULT '' AFTER synthetic_audio`); err != nil {
+		return fmt.Errorf("add words.no_audio failed: %w", err)
+	}
 	if err := addColumnIfMissing(db, `ALTER TABLE recite_units ADD COLUMN sort_order BIGINT NOT NULL DEFAULT 0 AFTER name`); err != nil {
 		return fmt.Errorf("add recite_units.sort_order failed: %w", err)
 	}
@@ -130,6 +191,39 @@ func AutoMigrate(db *sql.DB) error {
 	if err := addColumnIfMissing(db, `ALTER TABLE quiz_words ADD COLUMN result VARCHAR(16) NOT NULL DEFAULT '' AFTER input_answer`); err != nil {
 		return fmt.Errorf("add quiz_words.result failed: %w", err)
 	}
+	// Indexes behind the word search. Substring search is LIKE alone, the
+	// ngram index on word could not match words containing stopwords.
+	if err := dropIndexIfExists(db, `ALTER TABLE words DROP INDEX ft_words_word`); err != nil {
+		return fmt.Errorf("drop words.ft_words_word failed: %w", err)
+	}
+	if err := addIndexIfMissing(db, `ALTER TABLE words ADD FULLTEXT INDEX ft_words_meaning(mean_tag, parts_json) WITH PARSER ngram`); err != nil {
+		return fmt.Errorf("add words.ft_words_meaning failed: %w", err)
+	}
+	if err := addIndexIfMissing(db, `ALTER TABLE recite_unit_words ADD INDEX idx_unit_words_word(word_id)`); err != nil {
+		return fmt.Errorf("add recite_unit_words.idx_unit_words_word failed: %w", err)
+	}
+	if err := addIndexIfMissing(db, `ALTER TABLE forgotten_words ADD INDEX idx_word_remembered(word, remembered)`); err != nil {
+		return fmt.Errorf("add forgotten_words.idx_word_remembered failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE notes ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL AFTER updated_at`); err != nil {
+		return fmt.Errorf("add notes.deleted_at failed: %w", err)
+	}
+	if err := addIndexIfMissing(db, `ALTER TABLE notes ADD INDEX idx_note_deleted(deleted_at, id)`); err != nil {
+		return fmt.Errorf("add notes.idx_note_deleted failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE quiz_words ADD COLUMN choices_json TEXT NULL AFTER result`); err != nil {
+		return fmt.Errorf("add quiz_words.choices_json failed: %w", err)
+	}
+	if err := addColumnIfMissing(db, `ALTER TABLE note_words ADD COLUMN from_ref TINYINT(1) NOT NULL DEFAULT 0 AFTER word_id`); err != nil {
+		return fmt.Errorf("add note_words.from_ref failed: %w", err)
+	}
+	// Indexes behind the notes search.
+	if err := addIndexIfMissing(db, `ALTER TABLE notes ADD FULLTEXT INDEX ft_notes_content(content) WITH PARSER ngram`); err != nil {
+		return fmt.Errorf("add notes.ft_notes_content failed: %w", err)
+	}
+	if err := addIndexIfMissing(db, `ALTER TABLE notes ADD INDEX idx_note_updated(updated_at, id)`); err != nil {
+		return fmt.Errorf("add notes.idx_note_updated failed: %w", err)
+	}
 	return nil
 }
 
@@ -167,3 +261,9 @@ func dropColumnIfExists(db *sql.DB, ddl string) error {
 	}
 	return err
 }
+
+// dropIndexIfExists runs an ALTER TABLE ... DROP INDEX; MySQL reports a missing
+// index like a missing column.
+func dropIndexIfExists(db *sql.DB, ddl string) error {
+	return dropColumnIfExists(db, ddl)
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that matched no route, so scanners probing
+// random paths do not create one series per path.
+const unmatchedRoute = "unmatched"
+
+// Middleware counts requests and observes their latency per echo route.
+func Middleware() echo.MiddlewareFunc {
+	var (
+		once   sync.Once
+		routes map[string]bool
+	)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let echo write the error response now so the status is known
+				c.Error(err)
+			}
+
+			// routes are all registered before the first request
+			once.Do(func() {
+				routes = make(map[string]bool)
+				for _, r := range c.Echo().Routes() {
+					routes[r.Path] = true
+				}
+			})
+			// echo leaves the raw request path in c.Path() when nothing matched
+			route := c.Path()
+			if !routes[route] {
+				route = unmatchedRoute
+			}
+			method := c.Request().Method
+			status := strconv.Itoa(c.Response().Status)
+			httpRequests.WithLabelValues(route, method, status).Inc()
+			httpDuration.WithLabelValues(route, method).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() echo.HandlerFunc {
+	return echo.WrapHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+}
@@ -0,0 +1,125 @@
+// Package metrics holds every Prometheus collector of the server and the
+// /metrics handler exposing them.
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "moss"
+
+// Result label values.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by echo route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by echo route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	dictFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dictionary_fetches_total",
+		Help:      "Dictionary page fetches by provider and result.",
+	}, []string{"provider", "result"})
+
+	dictFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dictionary_fetch_duration_seconds",
+		Help:      "Dictionary page fetch latency by provider.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8},
+	}, []string{"provider"})
+
+	audioDownloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audio_downloads_total",
+		Help:      "Audio files downloaded into the audio library by result.",
+	}, []string{"result"})
+
+	quizzesStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quizzes_started_total",
+		Help:      "Quizzes started by quiz type and source kind.",
+	}, []string{"type", "source_kind"})
+
+	quizzesFinished = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quizzes_finished_total",
+		Help:      "Quizzes finished.",
+	})
+
+	wordsAdded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unit_words_added_total",
+		Help:      "Words added to units.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dictFetches,
+		dictFetchDuration,
+		audioDownloads,
+		quizzesStarted,
+		quizzesFinished,
+		wordsAdded,
+	)
+}
+
+// RegisterDB exposes the connection pool stats of db, read from
+// sql.DB.Stats() on every scrape.
+func RegisterDB(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveDictionaryFetch records one dictionary page fetch that started at
+// start and ended with err.
+func ObserveDictionaryFetch(provider string, start time.Time, err error) {
+	dictFetchDuration.WithLabelValues(provider).Observe(time.Since(start).Seconds())
+	dictFetches.WithLabelValues(provider, result(err)).Inc()
+}
+
+// ObserveAudioDownload records one audio download ending with err.
+func ObserveAudioDownload(err error) {
+	audioDownloads.WithLabelValues(result(err)).Inc()
+}
+
+func QuizStarted(quizType, sourceKind string) {
+	quizzesStarted.WithLabelValues(quizType, sourceKind).Inc()
+}
+
+func QuizFinished() {
+	quizzesFinished.Inc()
+}
+
+func WordAdded() {
+	wordsAdded.Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/fetcher"
+)
+
+// CommandTTS synthesizes speech by running a local program such as espeak-ng.
+// The text is written to the program's stdin. Placeholders are replaced per
+// argument and no shell is involved; a command that still takes the text as
+// the {text} argument is refused text starting with "-", which the program
+// would read as an option.
+type CommandTTS struct {
+	command       []string
+	encodeCommand []string
+	voices        map[string]string
+	timeout       time.Duration
+}
+
+func NewCommandTTS(command, encodeCommand []string, voices map[string]string, timeout time.Duration) (*CommandTTS, error) {
+	if len(command) == 0 || strings.TrimSpace(command[0]) == "" {
+		return nil, errors.New("tts command is empty")
+	}
+	if timeout <= 0 {
+		timeout = 20 * time.Second
+	}
+	return &CommandTTS{
+		command:       append([]string{}, command...),
+		encodeCommand: append([]string{}, encodeCommand...),
+		voices:        voices,
+		timeout:       timeout,
+	}, nil
+}
+
+func (p *CommandTTS) Name() string {
+	return filepath.Base(p.command[0])
+}
+
+func (p *CommandTTS) Synthetic() bool {
+	return true
+}
+
+func (p *CommandTTS) Generate(ctx context.Context, text, accent string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("tts text is empty")
+	}
+	workDir, err := os.MkdirTemp("", "moss-tts-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	if strings.HasPrefix(text, "-") && textAsArgument(p.command) {
+		return nil, errors.New(`tts text starting with "-" cannot be passed as {text}, read it from stdin`)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	output := filepath.Join(workDir, "speech.mp3")
+	vars := strings.NewReplacer(
+		"{text}", text,
+		"{voice}", p.voice(accent),
+		"{wav}", filepath.Join(workDir, "speech.wav"),
+		"{output}", output,
+	)
+	if err := runCommand(ctx, p.command, vars, text); err != nil {
+		return nil, err
+	}
+	if len(p.encodeCommand) > 0 {
+		if err := runCommand(ctx, p.encodeCommand, vars, ""); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		return nil, fmt.Errorf("read tts output failed: %w", err)
+	}
+	if problem := fetcher.CheckAudioData(data); problem != "" {
+		return nil, fmt.Errorf("tts output is %s", problem)
+	}
+	return data, nil
+}
+
+func (p *CommandTTS) voice(accent string) string {
+	if voice := p.voices[accent]; voice != "" {
+		return voice
+	}
+	if accent == "am" {
+		return "en-us"
+	}
+	return "en-gb"
+}
+
+// textAsArgument reports whether an argument of command starts with the text.
+func textAsArgument(command []string) bool {
+	for _, arg := range command[1:] {
+		if strings.HasPrefix(arg, "{text}") {
+			return true
+		}
+	}
+	return false
+}
+
+func runCommand(ctx context.Context, tmpl []string, vars *strings.Replacer, stdin string) error {
+	args := make([]string, 0, len(tmpl))
+	for _, arg := range tmpl {
+		args = append(args, vars.Replace(arg))
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s failed: %w: %s", filepath.Base(args[0]), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
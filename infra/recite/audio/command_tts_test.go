@@ -0,0 +1,32 @@
+package audio
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandTTSRefusesOptionLikeTextArgument(t *testing.T) {
+	tts, err := NewCommandTTS([]string{"espeak-ng", "-w", "{wav}", "{text}"}, nil, nil, time.Second)
+	if err != nil {
+		t.Fatalf("NewCommandTTS: %v", err)
+	}
+	_, err = tts.Generate(context.Background(), "--version", "en")
+	if err == nil || !strings.Contains(err.Error(), "stdin") {
+		t.Fatalf("Generate(--version) error = %v, want the text refused", err)
+	}
+}
+
+func TestCommandTTSWritesTextToStdin(t *testing.T) {
+	// the "program" only succeeds when stdin holds the text; it writes no
+	// audio, so success shows as the missing output
+	tts, err := NewCommandTTS([]string{"sh", "-c", `test "$(cat)" = "-v leading dash"`}, nil, nil, 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewCommandTTS: %v", err)
+	}
+	_, err = tts.Generate(context.Background(), "-v leading dash", "en")
+	if err == nil || !strings.Contains(err.Error(), "read tts output failed") {
+		t.Fatalf("Generate error = %v, want the command to get the text", err)
+	}
+}
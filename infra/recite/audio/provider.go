@@ -0,0 +1,15 @@
+package audio
+
+import "context"
+
+// Provider produces pronunciation audio for a piece of English text. It is
+// independent of the dictionary fetcher so audio can come from a source that
+// works offline.
+type Provider interface {
+	// Name identifies the provider in logs.
+	Name() string
+	// Synthetic reports whether the audio is generated rather than recorded.
+	Synthetic() bool
+	// Generate returns an mp3 of text spoken with accent ("en" or "am").
+	Generate(ctx context.Context, text, accent string) ([]byte, error)
+}
@@ -23,6 +23,10 @@ type WordSentence struct {
 	TTSURL  string `json:"ttsUrl"`
 	TTSSize int    `json:"ttsSize"`
 	LikeNum int    `json:"likeNum"`
+	// LocalTTSURL is the copy of the sentence audio kept in the local library.
+	LocalTTSURL string `json:"localTtsUrl,omitempty"`
+	// Synthetic marks audio generated by the local TTS backend.
+	Synthetic bool `json:"synthetic,omitempty"`
 }
 
 type Word struct {
@@ -33,8 +37,29 @@ type Word struct {
 	MeanTag        string              `json:"mean_tag"`
 	Parts          []WordPart          `json:"parts"`
 	SentenceGroups []WordSentenceGroup `json:"sentence_groups"`
-	CreatedAt      time.Time           `json:"created_at"`
-	UpdatedAt      time.Time           `json:"updated_at"`
+	Mnemonic       string              `json:"mnemonic"`
+	CustomParts    []WordPart          `json:"custom_parts"`
+	CustomMode     string              `json:"custom_mode"`
+	Origin         *WordOrigin         `json:"origin"`
+	EditedAt       *time.Time          `json:"edited_at"`
+	FetchedAt      *time.Time          `json:"fetched_at"`
+	// SyntheticAccents lists the accents ("en", "am") whose word audio was
+	// generated by the local TTS backend instead of downloaded.
+	SyntheticAccents []string `json:"synthetic_accents"`
+	// NoAudioAccents lists the accents the dictionary has no audio for.
+	NoAudioAccents []string  `json:"no_audio_accents"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WordOrigin keeps the fetched dictionary content of a word before the first
+// user edit, so the edit can be reverted.
+type WordOrigin struct {
+	PhEn           string              `json:"ph_en"`
+	PhAm           string              `json:"ph_am"`
+	MeanTag        string              `json:"mean_tag"`
+	Parts          []WordPart          `json:"parts"`
+	SentenceGroups []WordSentenceGroup `json:"sentence_groups"`
 }
 
 type ReciteUnit struct {
@@ -65,16 +90,19 @@ type Quiz struct {
 	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
+// QuizWord is a word of a quiz. ChoiceWordIDs are the candidates offered for
+// it by quizzes where the answer is picked from a group.
 type QuizWord struct {
-	ID          int64     `json:"id"`
-	QuizID      int64     `json:"quiz_id"`
-	WordID      int64     `json:"word_id"`
-	OrderNo     int       `json:"order_no"`
-	Status      string    `json:"status"`
-	InputAnswer string    `json:"input_answer"`
-	Result      string    `json:"result"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            int64     `json:"id"`
+	QuizID        int64     `json:"quiz_id"`
+	WordID        int64     `json:"word_id"`
+	OrderNo       int       `json:"order_no"`
+	Status        string    `json:"status"`
+	InputAnswer   string    `json:"input_answer"`
+	Result        string    `json:"result"`
+	ChoiceWordIDs []int64   `json:"choice_word_ids"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type Note struct {
@@ -83,11 +111,16 @@ type Note struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the note is in the trash.
+	DeletedAt *time.Time `json:"deleted_at"`
 }
 
+// NoteWordRelation links a note to a word. FromRef marks links kept in sync
+// with the [[word]] references of the note content rather than chosen by hand.
 type NoteWordRelation struct {
 	ID        int64     `json:"id"`
 	NoteID    int64     `json:"note_id"`
 	WordID    int64     `json:"word_id"`
+	FromRef   bool      `json:"from_ref"`
 	CreatedAt time.Time `json:"created_at"`
 }
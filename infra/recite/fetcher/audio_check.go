@@ -0,0 +1,260 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/storage"
+)
+
+const (
+	AudioProblemMissing    = "missing"
+	AudioProblemEmpty      = "empty"
+	AudioProblemTruncated  = "truncated"
+	AudioProblemNotMP3     = "not_mp3"
+	AudioProblemOrphan     = "orphan"
+	AudioProblemUnreadable = "unreadable"
+)
+
+var audioAccents = []string{"en", "am"}
+
+// sentenceAudioDir is the top directory of sentence audio in the library.
+const sentenceAudioDir = "sentence"
+
+// AudioIssue is a problem found in the audio library. Word is empty for
+// orphan files that belong to no word, Accent for sentence audio.
+type AudioIssue struct {
+	Word    string
+	Accent  string
+	Key     string
+	Problem string
+}
+
+// AudioLibrary knows the layout of the audio library and checks it. Word audio
+// lives at {en,am}/{prefix}/{word}.mp3 and sentence audio at
+// sentence/{prefix}/{name}.mp3, in whatever blob store backs the library.
+type AudioLibrary struct {
+	store storage.Store
+}
+
+func NewAudioLibrary(store storage.Store) *AudioLibrary {
+	return &AudioLibrary{store: store}
+}
+
+// Scan checks the audio of every given word and of the sentences, which map
+// the key of each sentence audio file to its word, and reports stored files
+// that belong to none of them. Keys in absent are audio known not to exist, so
+// they are not reported missing, though a file stored there is still checked.
+func (s *AudioLibrary) Scan(ctx context.Context, words []string, sentences map[string]string, absent map[string]bool) ([]AudioIssue, error) {
+	issues := make([]AudioIssue, 0)
+	expected := make(map[string]struct{}, len(words)*len(audioAccents)+len(sentences))
+	check := func(word, accent, key string) {
+		expected[key] = struct{}{}
+		problem := s.Check(ctx, key)
+		if problem == AudioProblemMissing && absent[key] {
+			return
+		}
+		if problem != "" {
+			issues = append(issues, AudioIssue{Word: word, Accent: accent, Key: key, Problem: problem})
+		}
+	}
+	for _, word := range words {
+		for _, accent := range audioAccents {
+			check(word, accent, WordAudioKey(accent, word))
+		}
+	}
+	for key, word := range sentences {
+		check(word, "", key)
+	}
+
+	for _, dir := range append([]string{sentenceAudioDir}, audioAccents...) {
+		accent := dir
+		if dir == sentenceAudioDir {
+			accent = ""
+		}
+		err := s.store.Walk(ctx, dir, func(key string) error {
+			if _, ok := expected[key]; ok {
+				return nil
+			}
+			issues = append(issues, AudioIssue{Accent: accent, Key: key, Problem: AudioProblemOrphan})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Key < issues[j].Key
+	})
+	return issues, nil
+}
+
+// Check reads a stored audio file and returns its problem, or "" when it looks
+// like a complete MP3.
+func (s *AudioLibrary) Check(ctx context.Context, key string) string {
+	data, err := storage.ReadAll(ctx, s.store, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return AudioProblemMissing
+	}
+	if err != nil {
+		return AudioProblemUnreadable
+	}
+	return CheckAudioData(data)
+}
+
+// Ready reports whether a complete MP3 is stored under key. Unlike Check it
+// only reads the size and the headers, so it is cheap enough to run on every
+// query of a cached word.
+func (s *AudioLibrary) Ready(ctx context.Context, key string) bool {
+	info, err := s.store.Stat(ctx, key)
+	if err != nil {
+		return false
+	}
+	return checkAudio(info.Size, func(offset, length int64) ([]byte, error) {
+		return storage.ReadRange(ctx, s.store, key, offset, length)
+	}) == ""
+}
+
+// Put validates data as MP3 and stores it under key.
+func (s *AudioLibrary) Put(ctx context.Context, key string, data []byte) error {
+	if problem := CheckAudioData(data); problem != "" {
+		return fmt.Errorf("audio invalid: %s", problem)
+	}
+	return s.store.Put(ctx, key, data)
+}
+
+// Remove deletes a file of the audio library.
+func (s *AudioLibrary) Remove(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+// WordAudioKey returns the storage key of a word's audio in accent "en" or "am".
+func WordAudioKey(accent, word string) string {
+	return accent + "/" + AudioPrefix(word) + "/" + AudioFileStem(word) + ".mp3"
+}
+
+// SentenceAudioName names the audio of an example sentence: its iciba ID, or a
+// hash of the text for sentences added by the user.
+func SentenceAudioName(id int, text string) string {
+	if id > 0 {
+		return strconv.Itoa(id)
+	}
+	sum := sha1.Sum([]byte(strings.TrimSpace(text)))
+	return "t" + hex.EncodeToString(sum[:])[:16]
+}
+
+// SentenceAudioKey returns the storage key of a sentence audio file.
+func SentenceAudioKey(name string) string {
+	return sentenceAudioDir + "/" + buildPrefix(name) + "/" + name + ".mp3"
+}
+
+// IsSentenceAudioKey reports whether key is in the sentence audio directory.
+func IsSentenceAudioKey(key string) bool {
+	return strings.HasPrefix(key, sentenceAudioDir+"/")
+}
+
+// AudioURL returns the path an audio file is served from by the /word_mp3 route.
+func AudioURL(key string) string {
+	parts := strings.Split(key, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return "/word_mp3/" + strings.Join(parts, "/")
+}
+
+// CheckAudioData sniffs audio content and returns its problem, or "" when it
+// looks like a complete MP3.
+func CheckAudioData(data []byte) string {
+	return checkAudio(int64(len(data)), func(offset, length int64) ([]byte, error) {
+		return data[offset : offset+length], nil
+	})
+}
+
+// checkAudio checks an audio file of size bytes, reading only the ID3 header
+// and the first frame header through readAt.
+func checkAudio(size int64, readAt func(offset, length int64) ([]byte, error)) string {
+	if size == 0 {
+		return AudioProblemEmpty
+	}
+	if size < 10 {
+		return AudioProblemTruncated
+	}
+	header, err := readAt(0, 10)
+	if err != nil || len(header) < 10 {
+		return AudioProblemUnreadable
+	}
+	offset := int64(0)
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		tagSize := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+		offset = 10 + tagSize
+		if header[5]&0x10 != 0 {
+			offset += 10
+		}
+		if offset >= size {
+			return AudioProblemTruncated
+		}
+	}
+
+	if offset+4 > size {
+		return AudioProblemTruncated
+	}
+	frameHeader := header[:4]
+	if offset > 0 {
+		if frameHeader, err = readAt(offset, 4); err != nil || len(frameHeader) < 4 {
+			return AudioProblemUnreadable
+		}
+	}
+	frameLen, ok := mp3FrameLength(binary.BigEndian.Uint32(frameHeader))
+	if !ok {
+		return AudioProblemNotMP3
+	}
+	if offset+frameLen > size {
+		return AudioProblemTruncated
+	}
+	return ""
+}
+
+var (
+	mp3BitratesV1L3 = [16]int64{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2L3 = [16]int64{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3SampleRates  = map[uint32][3]int64{
+		3: {44100, 48000, 32000}, // MPEG 1
+		2: {22050, 24000, 16000}, // MPEG 2
+		0: {11025, 12000, 8000},  // MPEG 2.5
+	}
+)
+
+// mp3FrameLength validates an MPEG audio frame header and returns the frame
+// size in bytes. Layers other than III are accepted with a minimal length.
+func mp3FrameLength(header uint32) (int64, bool) {
+	if header&0xffe00000 != 0xffe00000 {
+		return 0, false
+	}
+	version := (header >> 19) & 0x3
+	layer := (header >> 17) & 0x3
+	bitrateIndex := (header >> 12) & 0xf
+	sampleIndex := (header >> 10) & 0x3
+	padding := int64((header >> 9) & 0x1)
+	rates, ok := mp3SampleRates[version]
+	if !ok || layer == 0 || bitrateIndex == 0xf || sampleIndex == 0x3 {
+		return 0, false
+	}
+	if layer != 1 || bitrateIndex == 0 {
+		return 4, true
+	}
+	sampleRate := rates[sampleIndex]
+	if version == 3 {
+		return 144*mp3BitratesV1L3[bitrateIndex]*1000/sampleRate + padding, true
+	}
+	return 72*mp3BitratesV2L3[bitrateIndex]*1000/sampleRate + padding, true
+}
@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/wutianfang/moss/infra/storage"
+)
+
+// rangeOnlyStore fails whole reads, so Ready has to make do with Stat and
+// ranged reads.
+type rangeOnlyStore struct {
+	*storage.LocalStore
+	t *testing.T
+}
+
+func (s rangeOnlyStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, *storage.Info, error) {
+	s.t.Errorf("Open(%s) reads the whole blob", key)
+	return s.LocalStore.Open(ctx, key)
+}
+
+func (s rangeOnlyStore) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	reader, _, err := s.LocalStore.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(reader, length), reader}, nil
+}
+
+func TestAudioLibraryReady(t *testing.T) {
+	ctx := context.Background()
+	store := rangeOnlyStore{LocalStore: storage.NewLocalStore(t.TempDir()), t: t}
+	library := NewAudioLibrary(store)
+
+	// an ID3v2 tag of 20 bytes ahead of the frame
+	tagged := append([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}, make([]byte, 20)...)
+	tagged = append(tagged, testMP3()...)
+	blobs := map[string][]byte{
+		"en/ok.mp3":        testMP3(),
+		"en/tagged.mp3":    tagged,
+		"en/truncated.mp3": testMP3()[:200],
+		"en/text.mp3":      bytes.Repeat([]byte("not audio "), 50),
+		"en/empty.mp3":     {},
+	}
+	for key, data := range blobs {
+		if err := store.Put(ctx, key, data); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+	cases := map[string]bool{
+		"en/ok.mp3":        true,
+		"en/tagged.mp3":    true,
+		"en/truncated.mp3": false,
+		"en/text.mp3":      false,
+		"en/empty.mp3":     false,
+		"en/missing.mp3":   false,
+	}
+	for key, want := range cases {
+		if got := library.Ready(ctx, key); got != want {
+			t.Errorf("Ready(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestCheckAudioData(t *testing.T) {
+	cases := map[string]struct {
+		data []byte
+		want string
+	}{
+		"mp3":       {testMP3(), ""},
+		"empty":     {nil, AudioProblemEmpty},
+		"short":     {[]byte("ID3"), AudioProblemTruncated},
+		"cut frame": {testMP3()[:100], AudioProblemTruncated},
+		"html":      {[]byte("<html><body>404</body></html>"), AudioProblemNotMP3},
+		"cut tag":   {[]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 1, 0, 0xff, 0xfb}, AudioProblemTruncated},
+	}
+	for name, tc := range cases {
+		if got := CheckAudioData(tc.data); got != tc.want {
+			t.Errorf("%s: CheckAudioData = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestAudioLibraryScan(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStore(t.TempDir())
+	library := NewAudioLibrary(store)
+
+	cached := SentenceAudioKey("2791054")
+	broken := SentenceAudioKey("2791055")
+	pending := SentenceAudioKey("2791101")
+	orphan := SentenceAudioKey("999")
+	blobs := map[string][]byte{
+		WordAudioKey("en", "abandon"): testMP3(),
+		WordAudioKey("am", "abandon"): testMP3(),
+		cached:                        testMP3(),
+		broken:                        testMP3()[:200],
+		orphan:                        testMP3(),
+		WordAudioKey("en", "gone"):    testMP3(),
+	}
+	for key, data := range blobs {
+		if err := store.Put(ctx, key, data); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+
+	sentences := map[string]string{cached: "abandon", broken: "abandon", pending: "abandon"}
+	issues, err := library.Scan(ctx, []string{"abandon"}, sentences, map[string]bool{pending: true})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	want := []AudioIssue{
+		{Accent: "en", Key: WordAudioKey("en", "gone"), Problem: AudioProblemOrphan},
+		{Word: "abandon", Key: broken, Problem: AudioProblemTruncated},
+		{Key: orphan, Problem: AudioProblemOrphan},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v, want %+v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+}
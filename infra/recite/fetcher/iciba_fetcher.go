@@ -2,43 +2,45 @@ package fetcher
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	htmllib "html"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/wutianfang/moss/infra/metrics"
 	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/util"
 )
 
 type WordFetcher interface {
 	FetchAndStore(ctx context.Context, word string) (*entity.Word, error)
 	EnsureAudioFiles(ctx context.Context, word string) error
+	// DownloadSentenceAudio copies the audio of an example sentence into the
+	// audio library under name, see SentenceAudioName.
+	DownloadSentenceAudio(ctx context.Context, source, name string) error
 }
 
 type IcibaFetcher struct {
-	wordMP3Dir string
-	client     *http.Client
+	library *AudioLibrary
+	client  *http.Client
+	pageURL string
 }
 
-func NewIcibaFetcher(wordMP3Dir string) *IcibaFetcher {
+func NewIcibaFetcher(library *AudioLibrary) *IcibaFetcher {
 	return &IcibaFetcher{
-		wordMP3Dir: wordMP3Dir,
+		library: library,
 		client: &http.Client{
 			Timeout: 8 * time.Second,
 		},
+		pageURL: icibaPageURL,
 	}
 }
 
 func (f *IcibaFetcher) FetchAndStore(ctx context.Context, rawWord string) (*entity.Word, error) {
-	word := strings.ToLower(strings.TrimSpace(rawWord))
+	word := NormalizeWord(rawWord)
 	if word == "" {
 		return nil, errors.New("empty word")
 	}
@@ -48,36 +50,40 @@ func (f *IcibaFetcher) FetchAndStore(ctx context.Context, rawWord string) (*enti
 		return nil, err
 	}
 
-	prefix := buildPrefix(word)
-
+	enKey, amKey := WordAudioKey("en", word), WordAudioKey("am", word)
 	if parsed.PhEnMP3 != "" {
-		enLocal := filepath.Join(f.wordMP3Dir, "en", prefix, word+".mp3")
-		_ = downloadToFile(ctx, f.client, parsed.PhEnMP3, enLocal)
+		if err := f.downloadAudio(ctx, parsed.PhEnMP3, enKey); err != nil {
+			util.Errorf("download en audio failed: word=%s err=%v", word, err)
+		}
 	}
 	if parsed.PhAmMP3 != "" {
-		amLocal := filepath.Join(f.wordMP3Dir, "am", prefix, word+".mp3")
-		_ = downloadToFile(ctx, f.client, parsed.PhAmMP3, amLocal)
+		if err := f.downloadAudio(ctx, parsed.PhAmMP3, amKey); err != nil {
+			util.Errorf("download am audio failed: word=%s err=%v", word, err)
+		}
 	}
 
-	return &entity.Word{
-		Word:           word,
-		PhEn:           parsed.PhEn,
-		PhAm:           parsed.PhAm,
-		MeanTag:        parsed.MeanTag,
-		Parts:          parsed.Parts,
-		SentenceGroups: parsed.SentenceGroups,
-	}, nil
+	return parsed.Word(word), nil
+}
+
+// NoAudioError is returned by EnsureAudioFiles when the only audio still
+// missing is audio the dictionary does not have.
+type NoAudioError struct {
+	Accents []string
+}
+
+func (e *NoAudioError) Error() string {
+	return "the dictionary has no audio for " + strings.Join(e.Accents, ", ")
 }
 
 func (f *IcibaFetcher) EnsureAudioFiles(ctx context.Context, rawWord string) error {
-	word := strings.ToLower(strings.TrimSpace(rawWord))
+	word := NormalizeWord(rawWord)
 	if word == "" {
 		return errors.New("empty word")
 	}
 
-	enPath, amPath := f.audioLocalPaths(word)
-	enReady := fileExists(enPath)
-	amReady := fileExists(amPath)
+	enKey, amKey := WordAudioKey("en", word), WordAudioKey("am", word)
+	enReady := f.library.Ready(ctx, enKey)
+	amReady := f.library.Ready(ctx, amKey)
 	if enReady && amReady {
 		return nil
 	}
@@ -86,35 +92,49 @@ func (f *IcibaFetcher) EnsureAudioFiles(ctx context.Context, rawWord string) err
 	if err != nil {
 		return err
 	}
+	// keep best-effort behavior, retry path can still recover the other file.
 	if !enReady && parsed.PhEnMP3 != "" {
-		if err := downloadToFile(ctx, f.client, parsed.PhEnMP3, enPath); err != nil {
-			// keep best-effort behavior, retry path can still recover the other file.
+		if err := f.downloadAudio(ctx, parsed.PhEnMP3, enKey); err != nil {
+			util.Errorf("download en audio failed: word=%s err=%v", word, err)
 		}
 	}
 	if !amReady && parsed.PhAmMP3 != "" {
-		if err := downloadToFile(ctx, f.client, parsed.PhAmMP3, amPath); err != nil {
-			// keep best-effort behavior, retry path can still recover the other file.
+		if err := f.downloadAudio(ctx, parsed.PhAmMP3, amKey); err != nil {
+			util.Errorf("download am audio failed: word=%s err=%v", word, err)
 		}
 	}
 
-	if fileExists(enPath) && fileExists(amPath) {
+	enReady = f.library.Ready(ctx, enKey)
+	amReady = f.library.Ready(ctx, amKey)
+	if enReady && amReady {
 		return nil
 	}
+	if (enReady || parsed.PhEnMP3 == "") && (amReady || parsed.PhAmMP3 == "") {
+		return &NoAudioError{Accents: parsed.noAudioAccents()}
+	}
 	return errors.New("audio file still missing")
 }
 
-type icibaResult struct {
-	PhEn           string
-	PhAm           string
-	PhEnMP3        string
-	PhAmMP3        string
-	MeanTag        string
-	Parts          []entity.WordPart
-	SentenceGroups []entity.WordSentenceGroup
+func (f *IcibaFetcher) DownloadSentenceAudio(ctx context.Context, source, name string) error {
+	if name == "" {
+		return errors.New("empty sentence audio name")
+	}
+	key := SentenceAudioKey(name)
+	if f.library.Ready(ctx, key) {
+		return nil
+	}
+	return f.downloadAudio(ctx, source, key)
 }
 
-func (f *IcibaFetcher) fetchFromIciba(ctx context.Context, word string) (*icibaResult, error) {
-	reqURL := "https://www.iciba.com/word?w=" + url.QueryEscape(word)
+// icibaPageURL is the iciba word page, queried with ?w=<word>.
+const icibaPageURL = "https://www.iciba.com/word"
+
+// icibaProvider is the provider label of iciba in the fetch metrics.
+const icibaProvider = "iciba"
+
+// FetchIcibaPage downloads the raw HTML of the iciba page of word.
+func (f *IcibaFetcher) FetchIcibaPage(ctx context.Context, word string) ([]byte, error) {
+	reqURL := f.pageURL + "?w=" + url.QueryEscape(word)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
 		return nil, err
@@ -130,89 +150,43 @@ func (f *IcibaFetcher) fetchFromIciba(ctx context.Context, word string) (*icibaR
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("iciba status code: %d", resp.StatusCode)
 	}
+	return io.ReadAll(resp.Body)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	meanTag := extractMeanTagFromHTML(string(body))
-
-	re := regexp.MustCompile(`(?si)<script\b[^>]*?\bid\s*=\s*["']__NEXT_DATA__["'][^>]*?>(.*?)</script>`)
-	matches := re.FindStringSubmatch(string(body))
-	if len(matches) < 2 {
-		return nil, errors.New("cannot find iciba data")
-	}
+func (f *IcibaFetcher) fetchFromIciba(ctx context.Context, word string) (entry *IcibaEntry, err error) {
+	start := time.Now()
+	defer func() {
+		// an unknown word is a valid answer, not a provider failure
+		fetchErr := err
+		if errors.Is(err, ErrWordNotFound) {
+			fetchErr = nil
+		}
+		metrics.ObserveDictionaryFetch(icibaProvider, start, fetchErr)
+	}()
 
-	payload := icibaNextData{}
-	if err := json.Unmarshal([]byte(matches[1]), &payload); err != nil {
+	page, err := f.FetchIcibaPage(ctx, word)
+	if err != nil {
 		return nil, err
 	}
+	return ParseIcibaPage(word, page)
+}
 
-	info := payload.Props.PageProps.InitialReduxState.Word.WordInfo
-	if len(info.BaesInfo.Symbols) == 0 {
-		return nil, errors.New("word not found")
-	}
-
-	symbol := info.BaesInfo.Symbols[0]
-	if symbol.PhAmMP3 == "" {
-		symbol.PhAmMP3 = symbol.PhTtsMP3
-	}
-	if symbol.PhEnMP3 == "" {
-		symbol.PhEnMP3 = symbol.PhTtsMP3
-	}
-
-	result := &icibaResult{
-		PhEn:           symbol.PhEn,
-		PhAm:           symbol.PhAm,
-		PhEnMP3:        symbol.PhEnMP3,
-		PhAmMP3:        symbol.PhAmMP3,
-		MeanTag:        meanTag,
-		Parts:          make([]entity.WordPart, 0),
-		SentenceGroups: make([]entity.WordSentenceGroup, 0),
-	}
-
-	for _, item := range symbol.Parts {
-		result.Parts = append(result.Parts, entity.WordPart{
-			Part:  item.Part,
-			Means: item.Means,
-		})
-	}
-
-	for _, group := range info.NewSentence {
-		sentences := make([]entity.WordSentence, 0, len(group.Sentences))
-		for _, sentence := range group.Sentences {
-			sentences = append(sentences, entity.WordSentence{
-				ID:      sentence.ID,
-				Type:    sentence.Type,
-				CN:      sentence.Cn,
-				EN:      sentence.En,
-				From:    sentence.From,
-				TTSURL:  sentence.TtsURL,
-				TTSSize: sentence.TtsSize,
-				LikeNum: sentence.LikeNum,
-			})
-		}
-		result.SentenceGroups = append(result.SentenceGroups, entity.WordSentenceGroup{
-			Tag:       group.Tag,
-			Word:      group.Word,
-			Meaning:   group.Meaning,
-			Sentences: sentences,
-		})
-	}
+// NormalizeWord lowercases a word or phrase and collapses inner whitespace,
+// so "Give  Up" and "give up" share one entry.
+func NormalizeWord(raw string) string {
+	return strings.Join(strings.Fields(strings.ToLower(raw)), " ")
+}
 
-	if result.PhEn == "" && result.PhAm == "" && len(result.Parts) == 0 {
-		return nil, errors.New("word content empty")
-	}
-	return result, nil
+// AudioFileStem returns the file name (without extension) used to store the
+// audio of a word. Phrases keep one file by joining tokens with "_", which
+// never appears in a valid word.
+func AudioFileStem(word string) string {
+	return strings.ReplaceAll(NormalizeWord(word), " ", "_")
 }
 
-func extractMeanTagFromHTML(rawHTML string) string {
-	re := regexp.MustCompile(`(?s)<p class="Mean_tag[^"]*">(.*?)</p>`)
-	matched := re.FindStringSubmatch(rawHTML)
-	if len(matched) < 2 {
-		return ""
-	}
-	return strings.TrimSpace(htmllib.UnescapeString(matched[1]))
+// AudioPrefix returns the sub directory of a word's audio file.
+func AudioPrefix(word string) string {
+	return buildPrefix(AudioFileStem(word))
 }
 
 func buildPrefix(word string) string {
@@ -223,37 +197,21 @@ func buildPrefix(word string) string {
 	return string(runes[:2])
 }
 
-func (f *IcibaFetcher) audioLocalPaths(word string) (string, string) {
-	prefix := buildPrefix(word)
-	enLocal := filepath.Join(f.wordMP3Dir, "en", prefix, word+".mp3")
-	amLocal := filepath.Join(f.wordMP3Dir, "am", prefix, word+".mp3")
-	return enLocal, amLocal
-}
+// maxAudioBytes bounds a downloaded audio file; word and sentence audio are a
+// few dozen KB.
+const maxAudioBytes = 8 << 20
 
-func fileExists(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-	if info.IsDir() {
-		return false
-	}
-	return info.Size() > 0
-}
-
-func downloadToFile(ctx context.Context, client *http.Client, source, target string) error {
+func (f *IcibaFetcher) downloadAudio(ctx context.Context, source, key string) (err error) {
+	defer func() { metrics.ObserveAudioDownload(err) }()
 	if source == "" {
 		return errors.New("empty source")
 	}
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-		return err
-	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
 	if err != nil {
 		return err
 	}
-	resp, err := client.Do(req)
+	resp, err := f.client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -262,58 +220,18 @@ func downloadToFile(ctx context.Context, client *http.Client, source, target str
 		return fmt.Errorf("download failed, status=%d", resp.StatusCode)
 	}
 
-	tmp := target + ".tmp"
-	file, err := os.Create(tmp)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioBytes+1))
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(file, resp.Body); err != nil {
-		_ = file.Close()
-		return err
+	if len(data) > maxAudioBytes {
+		return fmt.Errorf("download too large, over %d bytes", maxAudioBytes)
 	}
-	if err := file.Close(); err != nil {
-		return err
+	if resp.ContentLength > 0 && int64(len(data)) != resp.ContentLength {
+		return fmt.Errorf("download truncated, got %d of %d bytes", len(data), resp.ContentLength)
 	}
-	return os.Rename(tmp, target)
-}
-
-type icibaNextData struct {
-	Props struct {
-		PageProps struct {
-			InitialReduxState struct {
-				Word struct {
-					WordInfo struct {
-						BaesInfo struct {
-							Symbols []struct {
-								PhEn     string `json:"ph_en"`
-								PhAm     string `json:"ph_am"`
-								PhEnMP3  string `json:"ph_en_mp3"`
-								PhAmMP3  string `json:"ph_am_mp3"`
-								PhTtsMP3 string `json:"ph_tts_mp3"`
-								Parts    []struct {
-									Part  string   `json:"part"`
-									Means []string `json:"means"`
-								} `json:"parts"`
-							} `json:"symbols"`
-						} `json:"baesInfo"`
-						NewSentence []struct {
-							Tag       string `json:"tag"`
-							Word      string `json:"word"`
-							Meaning   string `json:"meaning"`
-							Sentences []struct {
-								ID      int    `json:"id"`
-								Type    int    `json:"type"`
-								Cn      string `json:"cn"`
-								En      string `json:"en"`
-								From    string `json:"from"`
-								TtsURL  string `json:"ttsUrl"`
-								TtsSize int    `json:"ttsSize"`
-								LikeNum int    `json:"likeNum"`
-							} `json:"sentences"`
-						} `json:"new_sentence"`
-					} `json:"wordInfo"`
-				} `json:"word"`
-			} `json:"initialReduxState"`
-		} `json:"pageProps"`
-	} `json:"props"`
+	if err := f.library.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("store downloaded audio failed: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,223 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const (
+	abandonEnAudioPath = "/resource/amp3/oxford/0/28/a2/28a24294fd5b9cf8ba1e3fa92b2d4e4d.mp3"
+	abandonAmAudioPath = "/resource/amp3/1/0/8e/a1/8ea1c8d8b8d1fe1c8a2b0ae6f6a1a5ec.mp3"
+)
+
+func TestFetchAndStore(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, library := stub.newFetcher(t)
+	ctx := context.Background()
+
+	word, err := f.FetchAndStore(ctx, " Abandon ")
+	if err != nil {
+		t.Fatalf("FetchAndStore error: %v", err)
+	}
+	if word.Word != "abandon" || word.PhEn != "əˈbændən" || len(word.Parts) != 2 || len(word.SentenceGroups) != 2 {
+		t.Fatalf("unexpected word %+v", word)
+	}
+	for _, accent := range []string{"en", "am"} {
+		if !library.Ready(ctx, WordAudioKey(accent, "abandon")) {
+			t.Errorf("%s audio not stored", accent)
+		}
+	}
+	if got := stub.requestCount("/resource/"); got != 2 {
+		t.Errorf("audio requests = %d, want 2", got)
+	}
+
+	// audio already stored, nothing to download
+	if err := f.EnsureAudioFiles(ctx, "abandon"); err != nil {
+		t.Fatalf("EnsureAudioFiles error: %v", err)
+	}
+	if got := stub.requestCount("/word"); got != 1 {
+		t.Errorf("page requests = %d, want 1", got)
+	}
+}
+
+func TestFetchAndStoreTTSFallback(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, library := stub.newFetcher(t)
+	ctx := context.Background()
+
+	if _, err := f.FetchAndStore(ctx, "run"); err != nil {
+		t.Fatalf("FetchAndStore error: %v", err)
+	}
+	for _, accent := range []string{"en", "am"} {
+		if !library.Ready(ctx, WordAudioKey(accent, "run")) {
+			t.Errorf("%s audio not stored from the tts recording", accent)
+		}
+	}
+}
+
+func TestFetchAndStorePhraseAudioFileName(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, _ := stub.newFetcher(t)
+	ctx := context.Background()
+
+	word, err := f.FetchAndStore(ctx, "give  up")
+	if err != nil {
+		t.Fatalf("FetchAndStore error: %v", err)
+	}
+	if word.Word != "give up" || len(word.Parts) != 1 {
+		t.Fatalf("unexpected word %+v", word)
+	}
+	if got := WordAudioKey("en", word.Word); got != "en/gi/give_up.mp3" {
+		t.Fatalf("audio key = %q", got)
+	}
+
+	// the page has no audio for the phrase
+	if got := strings.Join(word.NoAudioAccents, ","); got != "en,am" {
+		t.Fatalf("no audio accents = %q, want en,am", got)
+	}
+	var noAudio *NoAudioError
+	if err := f.EnsureAudioFiles(ctx, "give up"); !errors.As(err, &noAudio) {
+		t.Fatalf("EnsureAudioFiles error = %v, want NoAudioError", err)
+	}
+}
+
+func TestFetchAndStoreAudioFailure(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, library := stub.newFetcher(t)
+	ctx := context.Background()
+	enKey := WordAudioKey("en", "abandon")
+
+	stub.fail(abandonEnAudioPath, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	})
+	if _, err := f.FetchAndStore(ctx, "abandon"); err != nil {
+		t.Fatalf("a failed audio download must not fail the fetch: %v", err)
+	}
+	if library.Ready(ctx, enKey) || !library.Ready(ctx, WordAudioKey("am", "abandon")) {
+		t.Fatalf("want only am audio stored")
+	}
+	if err := f.EnsureAudioFiles(ctx, "abandon"); err == nil {
+		t.Fatalf("EnsureAudioFiles must report the still missing audio")
+	}
+
+	stub.clear(abandonEnAudioPath)
+	if err := f.EnsureAudioFiles(ctx, "abandon"); err != nil {
+		t.Fatalf("EnsureAudioFiles retry error: %v", err)
+	}
+	if !library.Ready(ctx, enKey) {
+		t.Fatalf("en audio not repaired")
+	}
+	if got := stub.requestCount(abandonAmAudioPath); got != 1 {
+		t.Errorf("am audio requests = %d, want 1", got)
+	}
+}
+
+func TestDownloadAudioRejectsBadContent(t *testing.T) {
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "not mp3",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("<html>rate limited</html>"))
+			},
+			wantErr: AudioProblemNotMP3,
+		},
+		{
+			name: "empty",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			wantErr: AudioProblemEmpty,
+		},
+		{
+			name: "cut short",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "4096")
+				_, _ = w.Write(testMP3())
+			},
+		},
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+			wantErr: "status=404",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := newIcibaStub(t)
+			f, library := stub.newFetcher(t)
+			ctx := context.Background()
+			stub.fail("/tts_sentence/1.mp3", tc.handler)
+
+			err := f.DownloadSentenceAudio(ctx, stub.server.URL+"/tts_sentence/1.mp3", "1")
+			if err == nil {
+				t.Fatalf("DownloadSentenceAudio succeeded, want error")
+			}
+			if tc.wantErr != "" && !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tc.wantErr)
+			}
+			if problem := library.Check(ctx, SentenceAudioKey("1")); problem != AudioProblemMissing {
+				t.Fatalf("stored audio problem = %q, want nothing stored", problem)
+			}
+		})
+	}
+}
+
+func TestDownloadSentenceAudio(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, library := stub.newFetcher(t)
+	ctx := context.Background()
+	source := stub.server.URL + "/tts_sentence/2791054.mp3"
+
+	for i := 0; i < 2; i++ {
+		if err := f.DownloadSentenceAudio(ctx, source, "2791054"); err != nil {
+			t.Fatalf("DownloadSentenceAudio error: %v", err)
+		}
+	}
+	if !library.Ready(ctx, SentenceAudioKey("2791054")) {
+		t.Fatalf("sentence audio not stored")
+	}
+	if got := stub.requestCount("/tts_sentence/"); got != 1 {
+		t.Fatalf("sentence audio requests = %d, want 1", got)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	stub := newIcibaStub(t)
+	f, _ := stub.newFetcher(t)
+	ctx := context.Background()
+
+	if _, err := f.FetchAndStore(ctx, "qwxzv"); !errors.Is(err, ErrWordNotFound) {
+		t.Errorf("unknown word error = %v, want ErrWordNotFound", err)
+	}
+	if _, err := f.FetchAndStore(ctx, "   "); err == nil {
+		t.Errorf("empty word must fail")
+	}
+
+	stub.fail("/word", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked", http.StatusForbidden)
+	})
+	if _, err := f.FetchAndStore(ctx, "abandon"); err == nil || !strings.Contains(err.Error(), "status code: 403") {
+		t.Errorf("blocked page error = %v, want status code 403", err)
+	}
+
+	stub.fail("/word", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>请完成安全验证</body></html>"))
+	})
+	if _, err := f.FetchAndStore(ctx, "abandon"); !errors.Is(err, ErrPageFormat) {
+		t.Errorf("captcha page error = %v, want ErrPageFormat", err)
+	}
+
+	stub.server.Close()
+	if _, err := f.FetchAndStore(ctx, "abandon"); err == nil {
+		t.Errorf("fetch from a closed server must fail")
+	}
+}
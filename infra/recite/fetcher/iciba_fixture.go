@@ -0,0 +1,99 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IcibaFixture is an iciba page together with what ParseIcibaPage made of it.
+// Parser tests replay the page and expect the same result, so for a recorded
+// page a format change on iciba shows up as a fixture diff.
+type IcibaFixture struct {
+	Word  string      `json:"word"`
+	Entry *IcibaEntry `json:"entry,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// IcibaFixturePaths returns the page and expectation files of word in dir.
+func IcibaFixturePaths(dir, word string) (string, string) {
+	stem := filepath.Join(dir, AudioFileStem(word))
+	return stem + ".html", stem + ".json"
+}
+
+// RecordIcibaFixture downloads the current iciba page of word and saves it as
+// a fixture in dir.
+func (f *IcibaFetcher) RecordIcibaFixture(ctx context.Context, dir, rawWord string) (*IcibaFixture, error) {
+	word := NormalizeWord(rawWord)
+	if word == "" {
+		return nil, fmt.Errorf("empty word")
+	}
+	page, err := f.FetchIcibaPage(ctx, word)
+	if err != nil {
+		return nil, err
+	}
+	return WriteIcibaFixture(dir, word, page)
+}
+
+// WriteIcibaFixture parses page and writes it with the parse result to dir.
+// A page that fails to parse is still recorded, with the error expected.
+func WriteIcibaFixture(dir, word string, page []byte) (*IcibaFixture, error) {
+	fixture := &IcibaFixture{Word: word}
+	entry, err := ParseIcibaPage(word, page)
+	if err != nil {
+		fixture.Error = err.Error()
+	} else {
+		fixture.Entry = entry
+	}
+	expected := &bytes.Buffer{}
+	encoder := json.NewEncoder(expected)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(fixture); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	pagePath, expectedPath := IcibaFixturePaths(dir, word)
+	if err := os.WriteFile(pagePath, page, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(expectedPath, expected.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+// ListIcibaFixtures reads every fixture expectation in dir, sorted by word.
+func ListIcibaFixtures(dir string) ([]IcibaFixture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]IcibaFixture, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fixture := IcibaFixture{}
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("read fixture %s failed: %w", path, err)
+		}
+		if strings.TrimSpace(fixture.Word) == "" {
+			return nil, fmt.Errorf("fixture %s has no word", path)
+		}
+		ret = append(ret, fixture)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Word < ret[j].Word
+	})
+	return ret, nil
+}
@@ -0,0 +1,196 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmllib "html"
+	"regexp"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+)
+
+var (
+	// ErrWordNotFound is returned when iciba has no entry for the word.
+	ErrWordNotFound = errors.New("word not found")
+	// ErrPageFormat is returned when a page does not look like an iciba word page.
+	ErrPageFormat = errors.New("cannot find iciba data")
+)
+
+var (
+	icibaNextDataPattern = regexp.MustCompile(`(?si)<script\b[^>]*?\bid\s*=\s*["']__NEXT_DATA__["'][^>]*?>(.*?)</script>`)
+	icibaMeanTagPattern  = regexp.MustCompile(`(?s)<p class="Mean_tag[^"]*">(.*?)</p>`)
+)
+
+// IcibaEntry is the dictionary content parsed from an iciba word page.
+type IcibaEntry struct {
+	PhEn           string                     `json:"ph_en"`
+	PhAm           string                     `json:"ph_am"`
+	PhEnMP3        string                     `json:"ph_en_mp3"`
+	PhAmMP3        string                     `json:"ph_am_mp3"`
+	MeanTag        string                     `json:"mean_tag"`
+	Parts          []entity.WordPart          `json:"parts"`
+	SentenceGroups []entity.WordSentenceGroup `json:"sentence_groups"`
+}
+
+// noAudioAccents lists the accents the entry has no audio url for.
+func (e *IcibaEntry) noAudioAccents() []string {
+	var ret []string
+	if e.PhEnMP3 == "" {
+		ret = append(ret, "en")
+	}
+	if e.PhAmMP3 == "" {
+		ret = append(ret, "am")
+	}
+	return ret
+}
+
+// Word returns the entry as the stored row of word.
+func (e *IcibaEntry) Word(word string) *entity.Word {
+	return &entity.Word{
+		Word:           word,
+		PhEn:           e.PhEn,
+		PhAm:           e.PhAm,
+		MeanTag:        e.MeanTag,
+		Parts:          e.Parts,
+		SentenceGroups: e.SentenceGroups,
+		NoAudioAccents: e.noAudioAccents(),
+	}
+}
+
+// ParseIcibaPage extracts the entry of word from the HTML of its iciba page.
+// The content comes from the __NEXT_DATA__ payload, the exam tags from the
+// rendered page.
+func ParseIcibaPage(word string, page []byte) (*IcibaEntry, error) {
+	body := string(page)
+	meanTag := extractMeanTagFromHTML(body)
+
+	matches := icibaNextDataPattern.FindStringSubmatch(body)
+	if len(matches) < 2 {
+		return nil, ErrPageFormat
+	}
+
+	payload := icibaNextData{}
+	if err := json.Unmarshal([]byte(matches[1]), &payload); err != nil {
+		return nil, fmt.Errorf("decode iciba data failed: %w", err)
+	}
+
+	info := payload.Props.PageProps.InitialReduxState.Word.WordInfo
+	if len(info.BaesInfo.Symbols) == 0 {
+		// phrases missing from the dictionary only come with a machine translation
+		translated := strings.TrimSpace(info.BaesInfo.TranslateResult)
+		if strings.Contains(word, " ") && translated != "" {
+			return &IcibaEntry{
+				MeanTag:        meanTag,
+				Parts:          []entity.WordPart{{Means: []string{translated}}},
+				SentenceGroups: make([]entity.WordSentenceGroup, 0),
+			}, nil
+		}
+		return nil, ErrWordNotFound
+	}
+
+	symbol := info.BaesInfo.Symbols[0]
+	if symbol.PhAmMP3 == "" {
+		symbol.PhAmMP3 = symbol.PhTtsMP3
+	}
+	if symbol.PhEnMP3 == "" {
+		symbol.PhEnMP3 = symbol.PhTtsMP3
+	}
+
+	result := &IcibaEntry{
+		PhEn:           symbol.PhEn,
+		PhAm:           symbol.PhAm,
+		PhEnMP3:        symbol.PhEnMP3,
+		PhAmMP3:        symbol.PhAmMP3,
+		MeanTag:        meanTag,
+		Parts:          make([]entity.WordPart, 0),
+		SentenceGroups: make([]entity.WordSentenceGroup, 0),
+	}
+
+	for _, item := range symbol.Parts {
+		result.Parts = append(result.Parts, entity.WordPart{
+			Part:  item.Part,
+			Means: item.Means,
+		})
+	}
+
+	for _, group := range info.NewSentence {
+		sentences := make([]entity.WordSentence, 0, len(group.Sentences))
+		for _, sentence := range group.Sentences {
+			sentences = append(sentences, entity.WordSentence{
+				ID:      sentence.ID,
+				Type:    sentence.Type,
+				CN:      sentence.Cn,
+				EN:      sentence.En,
+				From:    sentence.From,
+				TTSURL:  sentence.TtsURL,
+				TTSSize: sentence.TtsSize,
+				LikeNum: sentence.LikeNum,
+			})
+		}
+		result.SentenceGroups = append(result.SentenceGroups, entity.WordSentenceGroup{
+			Tag:       group.Tag,
+			Word:      group.Word,
+			Meaning:   group.Meaning,
+			Sentences: sentences,
+		})
+	}
+
+	if result.PhEn == "" && result.PhAm == "" && len(result.Parts) == 0 {
+		return nil, errors.New("word content empty")
+	}
+	return result, nil
+}
+
+func extractMeanTagFromHTML(rawHTML string) string {
+	matched := icibaMeanTagPattern.FindStringSubmatch(rawHTML)
+	if len(matched) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(htmllib.UnescapeString(matched[1]))
+}
+
+// icibaNextData mirrors the part of the iciba __NEXT_DATA__ payload we read.
+// "baesInfo" is misspelled by iciba itself.
+type icibaNextData struct {
+	Props struct {
+		PageProps struct {
+			InitialReduxState struct {
+				Word struct {
+					WordInfo struct {
+						BaesInfo struct {
+							Symbols []struct {
+								PhEn     string `json:"ph_en"`
+								PhAm     string `json:"ph_am"`
+								PhEnMP3  string `json:"ph_en_mp3"`
+								PhAmMP3  string `json:"ph_am_mp3"`
+								PhTtsMP3 string `json:"ph_tts_mp3"`
+								Parts    []struct {
+									Part  string   `json:"part"`
+									Means []string `json:"means"`
+								} `json:"parts"`
+							} `json:"symbols"`
+							TranslateResult string `json:"translate_result"`
+						} `json:"baesInfo"`
+						NewSentence []struct {
+							Tag       string `json:"tag"`
+							Word      string `json:"word"`
+							Meaning   string `json:"meaning"`
+							Sentences []struct {
+								ID      int    `json:"id"`
+								Type    int    `json:"type"`
+								Cn      string `json:"cn"`
+								En      string `json:"en"`
+								From    string `json:"from"`
+								TtsURL  string `json:"ttsUrl"`
+								TtsSize int    `json:"ttsSize"`
+								LikeNum int    `json:"likeNum"`
+							} `json:"sentences"`
+						} `json:"new_sentence"`
+					} `json:"wordInfo"`
+				} `json:"word"`
+			} `json:"initialReduxState"`
+		} `json:"pageProps"`
+	} `json:"props"`
+}
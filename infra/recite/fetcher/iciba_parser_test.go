@@ -0,0 +1,157 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	// icibaSampleDir holds hand-made pages shaped like iciba's, trimmed to the
+	// fields the parser reads. They pin the parser down but cannot notice a
+	// format change on iciba. The service tests look words up in them too.
+	icibaSampleDir = "testdata/iciba_sample"
+	// icibaRecordedDir holds real pages saved by `moss fixtures update`.
+	icibaRecordedDir = "testdata/iciba_recorded"
+)
+
+func TestParseIcibaSamples(t *testing.T) {
+	fixtures, err := ListIcibaFixtures(icibaSampleDir)
+	if err != nil {
+		t.Fatalf("ListIcibaFixtures error: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no samples in %s", icibaSampleDir)
+	}
+	replayIcibaFixtures(t, icibaSampleDir, fixtures)
+}
+
+func TestParseIcibaRecorded(t *testing.T) {
+	fixtures, err := ListIcibaFixtures(icibaRecordedDir)
+	if err != nil {
+		t.Fatalf("ListIcibaFixtures error: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Skipf("no pages in %s, record some with `moss fixtures update -dir %s word...`", icibaRecordedDir, icibaRecordedDir)
+	}
+	replayIcibaFixtures(t, icibaRecordedDir, fixtures)
+}
+
+func replayIcibaFixtures(t *testing.T, dir string, fixtures []IcibaFixture) {
+	for _, fixture := range fixtures {
+		t.Run(fixture.Word, func(t *testing.T) {
+			pagePath, _ := IcibaFixturePaths(dir, fixture.Word)
+			page, err := os.ReadFile(pagePath)
+			if err != nil {
+				t.Fatalf("read page: %v", err)
+			}
+			entry, err := ParseIcibaPage(fixture.Word, page)
+			if fixture.Error != "" {
+				if err == nil || err.Error() != fixture.Error {
+					t.Fatalf("error = %v, want %q", err, fixture.Error)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseIcibaPage error: %v", err)
+			}
+			if got, want := mustJSON(t, entry), mustJSON(t, fixture.Entry); got != want {
+				t.Fatalf("entry differs from expectation\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseIcibaPageDetails(t *testing.T) {
+	entry := parseSample(t, "abandon")
+	if entry.MeanTag != "CET4 CET6 考研 TOEFL & IELTS" {
+		t.Errorf("mean tag = %q, want html unescaped", entry.MeanTag)
+	}
+	if len(entry.SentenceGroups) != 2 || len(entry.SentenceGroups[0].Sentences) != 2 {
+		t.Errorf("sentence groups = %+v, want 2 groups", entry.SentenceGroups)
+	}
+	if entry.SentenceGroups[0].Sentences[0].TTSURL == "" {
+		t.Errorf("sentence tts url missing")
+	}
+
+	// run only has a generic TTS recording, used for both accents
+	entry = parseSample(t, "run")
+	if entry.PhEnMP3 == "" || entry.PhEnMP3 != entry.PhAmMP3 {
+		t.Errorf("audio = %q / %q, want tts fallback for both", entry.PhEnMP3, entry.PhAmMP3)
+	}
+
+	// phrases missing from the dictionary fall back to the translation
+	entry = parseSample(t, "give up")
+	if len(entry.Parts) != 1 || entry.Parts[0].Means[0] != "放弃；投降" {
+		t.Errorf("parts = %+v, want the machine translation", entry.Parts)
+	}
+}
+
+func TestParseIcibaPageErrors(t *testing.T) {
+	page := readSamplePage(t, "qwxzv")
+	if _, err := ParseIcibaPage("qwxzv", page); !errors.Is(err, ErrWordNotFound) {
+		t.Errorf("unknown word error = %v, want ErrWordNotFound", err)
+	}
+
+	captcha := []byte(`<html><body><p>请完成安全验证</p></body></html>`)
+	if _, err := ParseIcibaPage("abandon", captcha); !errors.Is(err, ErrPageFormat) {
+		t.Errorf("page without data error = %v, want ErrPageFormat", err)
+	}
+
+	broken := []byte(`<script id="__NEXT_DATA__" type="application/json">{"props":</script>`)
+	if _, err := ParseIcibaPage("abandon", broken); err == nil || !strings.Contains(err.Error(), "decode iciba data") {
+		t.Errorf("broken payload error = %v, want decode error", err)
+	}
+}
+
+func TestWriteIcibaFixture(t *testing.T) {
+	dir := t.TempDir()
+	fixture, err := WriteIcibaFixture(dir, "give up", readSamplePage(t, "give up"))
+	if err != nil {
+		t.Fatalf("WriteIcibaFixture error: %v", err)
+	}
+	if fixture.Entry == nil || fixture.Error != "" {
+		t.Fatalf("fixture = %+v, want a parsed entry", fixture)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "give_up.html")); err != nil {
+		t.Fatalf("page not written: %v", err)
+	}
+	listed, err := ListIcibaFixtures(dir)
+	if err != nil {
+		t.Fatalf("ListIcibaFixtures error: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Word != "give up" {
+		t.Fatalf("listed = %+v, want the written fixture", listed)
+	}
+}
+
+func readSamplePage(t *testing.T, word string) []byte {
+	t.Helper()
+	pagePath, _ := IcibaFixturePaths(icibaSampleDir, word)
+	page, err := os.ReadFile(pagePath)
+	if err != nil {
+		t.Fatalf("read sample page: %v", err)
+	}
+	return page
+}
+
+func parseSample(t *testing.T, word string) *IcibaEntry {
+	t.Helper()
+	entry, err := ParseIcibaPage(word, readSamplePage(t, word))
+	if err != nil {
+		t.Fatalf("ParseIcibaPage(%q) error: %v", word, err)
+	}
+	return entry
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,103 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wutianfang/moss/infra/storage"
+)
+
+// icibaStub is a local stand-in for iciba. It serves the hand-made sample
+// pages with their audio links pointed back at itself, and a valid MP3 for
+// every audio path unless a path is told to fail.
+type icibaStub struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []string
+	failures map[string]http.HandlerFunc
+}
+
+func newIcibaStub(t *testing.T) *icibaStub {
+	t.Helper()
+	stub := &icibaStub{failures: make(map[string]http.HandlerFunc)}
+	stub.server = httptest.NewServer(http.HandlerFunc(stub.serve))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// newFetcher returns a fetcher that talks to the stub and stores audio in a
+// temporary local library.
+func (s *icibaStub) newFetcher(t *testing.T) (*IcibaFetcher, *AudioLibrary) {
+	t.Helper()
+	library := NewAudioLibrary(storage.NewLocalStore(t.TempDir()))
+	f := NewIcibaFetcher(library)
+	f.client = s.server.Client()
+	f.pageURL = s.server.URL + "/word"
+	return f, library
+}
+
+// fail makes requests to path answer with handler until cleared.
+func (s *icibaStub) fail(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = handler
+}
+
+func (s *icibaStub) clear(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, path)
+}
+
+func (s *icibaStub) requestCount(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, path := range s.requests {
+		if strings.HasPrefix(path, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *icibaStub) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	failure := s.failures[r.URL.Path]
+	s.mu.Unlock()
+	if failure != nil {
+		failure(w, r)
+		return
+	}
+
+	if r.URL.Path != "/word" {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write(testMP3())
+		return
+	}
+	pagePath, _ := IcibaFixturePaths(icibaSampleDir, r.URL.Query().Get("w"))
+	page, err := os.ReadFile(pagePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	body := strings.NewReplacer(
+		"https://res.iciba.com", s.server.URL,
+		"https://res-tts.iciba.com", s.server.URL,
+	).Replace(string(page))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+// testMP3 returns one silent MPEG-1 Layer III frame, 128 kbps at 44.1 kHz.
+func testMP3() []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x64})
+	return frame
+}
@@ -0,0 +1,300 @@
+package inflection
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed irregular.txt
+var irregularData string
+
+var (
+	// irregularLemma maps an irregular form to its lemma.
+	irregularLemma map[string]string
+	// irregularForms maps a lemma to its irregular forms.
+	irregularForms map[string][]string
+)
+
+// invariant lists words that end like an inflection but are not one, which the
+// suffix rules would otherwise strip (series -> sery, news -> new).
+var invariant = map[string]struct{}{
+	"always":      {},
+	"athletics":   {},
+	"economics":   {},
+	"ethics":      {},
+	"gymnastics":  {},
+	"lens":        {},
+	"linguistics": {},
+	"mathematics": {},
+	"news":        {},
+	"perhaps":     {},
+	"physics":     {},
+	"politics":    {},
+	"series":      {},
+	"species":     {},
+}
+
+func init() {
+	irregularLemma = make(map[string]string)
+	irregularForms = make(map[string][]string)
+	for _, line := range strings.Split(irregularData, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		lemma := fields[0]
+		for _, form := range fields[1:] {
+			if form == lemma {
+				continue
+			}
+			irregularForms[lemma] = append(irregularForms[lemma], form)
+			if _, ok := irregularLemma[form]; !ok {
+				irregularLemma[form] = lemma
+			}
+		}
+		if _, ok := irregularForms[lemma]; !ok {
+			irregularForms[lemma] = []string{}
+		}
+	}
+}
+
+// IrregularLemma returns the lemma of an irregular form listed in the bundled table.
+func IrregularLemma(form string) (string, bool) {
+	lemma, ok := irregularLemma[form]
+	return lemma, ok
+}
+
+// Candidates returns the possible lemmas of a surface form, most likely first.
+// The form itself is never included; an empty result means the word does not
+// look inflected. Phrases are handled on their first token ("gave up" -> "give up").
+func Candidates(form string) []string {
+	head, tail := splitPhrase(form)
+	if head == "" {
+		return []string{}
+	}
+	ret := make([]string, 0, 3)
+	seen := map[string]struct{}{head: {}}
+	add := func(lemma string) {
+		if len(lemma) < 2 {
+			return
+		}
+		if _, ok := seen[lemma]; ok {
+			return
+		}
+		seen[lemma] = struct{}{}
+		ret = append(ret, lemma+tail)
+	}
+
+	if lemma, ok := irregularLemma[head]; ok {
+		add(lemma)
+		return ret
+	}
+	if _, ok := irregularForms[head]; ok {
+		// a lemma of the table, e.g. "read" or "cost"
+		return ret
+	}
+	if _, ok := invariant[head]; ok {
+		return ret
+	}
+
+	switch {
+	case strings.HasSuffix(head, "ies") && len(head) > 4:
+		add(strings.TrimSuffix(head, "ies") + "y")
+	case strings.HasSuffix(head, "ied") && len(head) > 4:
+		add(strings.TrimSuffix(head, "ied") + "y")
+	case strings.HasSuffix(head, "iest") && len(head) > 5:
+		add(strings.TrimSuffix(head, "iest") + "y")
+	case strings.HasSuffix(head, "ier") && len(head) > 4:
+		add(strings.TrimSuffix(head, "ier") + "y")
+	case strings.HasSuffix(head, "ying") && len(head) > 4:
+		stem := strings.TrimSuffix(head, "ying")
+		add(stem + "y")
+		add(stem + "ie")
+	case strings.HasSuffix(head, "ing") && len(head) > 4:
+		addStem(strings.TrimSuffix(head, "ing"), add)
+	case strings.HasSuffix(head, "ed") && len(head) > 3:
+		stem := strings.TrimSuffix(head, "ed")
+		if strings.HasSuffix(stem, "e") {
+			// agreed, freed
+			add(strings.TrimSuffix(head, "d"))
+			break
+		}
+		addStem(stem, add)
+	case strings.HasSuffix(head, "est") && len(head) > 4:
+		addStem(strings.TrimSuffix(head, "est"), add)
+	case strings.HasSuffix(head, "er") && len(head) > 4:
+		addStem(strings.TrimSuffix(head, "er"), add)
+	case strings.HasSuffix(head, "es") && len(head) > 3:
+		stem := strings.TrimSuffix(head, "es")
+		if hasSibilantEnd(stem) || strings.HasSuffix(stem, "o") {
+			add(stem)
+		}
+		add(stem + "e")
+	case strings.HasSuffix(head, "s") && len(head) > 3 &&
+		!strings.HasSuffix(head, "ss") && !strings.HasSuffix(head, "us") && !strings.HasSuffix(head, "is"):
+		add(strings.TrimSuffix(head, "s"))
+	}
+	return ret
+}
+
+// addStem adds the lemmas of a stem left after removing -ing/-ed/-er/-est.
+func addStem(stem string, add func(string)) {
+	n := len(stem)
+	if n >= 2 && stem[n-1] == stem[n-2] && isConsonant(stem[n-1]) && !strings.ContainsRune("lsz", rune(stem[n-1])) {
+		// running -> run, stopped -> stop
+		add(stem[:n-1])
+		add(stem)
+		return
+	}
+	if isShortCVC(stem) || needsSilentE(stem) {
+		// making -> make, hoped -> hope, charged -> charge
+		add(stem + "e")
+		add(stem)
+		return
+	}
+	add(stem)
+	add(stem + "e")
+}
+
+// Forms returns the regular and irregular inflections of a lemma. Phrases are
+// inflected on their first token. The lemma itself is not included.
+func Forms(lemma string) []string {
+	head, tail := splitPhrase(lemma)
+	if head == "" {
+		return []string{}
+	}
+	if _, ok := invariant[head]; ok {
+		return []string{}
+	}
+	ret := make([]string, 0, 8)
+	seen := map[string]struct{}{head: {}}
+	add := func(form string) {
+		if _, ok := seen[form]; ok {
+			return
+		}
+		seen[form] = struct{}{}
+		ret = append(ret, form+tail)
+	}
+
+	for _, form := range irregularForms[head] {
+		add(form)
+	}
+
+	n := len(head)
+	last := head[n-1]
+	consonantY := n >= 2 && last == 'y' && isConsonant(head[n-2])
+	switch {
+	case consonantY:
+		add(head[:n-1] + "ies")
+	case hasSibilantEnd(head) || last == 'o':
+		add(head + "es")
+	default:
+		add(head + "s")
+	}
+
+	stem := head
+	switch {
+	case strings.HasSuffix(head, "ie"):
+		add(head[:n-2] + "ying")
+		add(head + "d")
+		return ret
+	case strings.HasSuffix(head, "ee"):
+		// agree -> agreeing, agreed
+		add(head + "ing")
+		add(head + "d")
+		add(head + "r")
+		add(head + "st")
+		return ret
+	case last == 'e':
+		stem = head[:n-1]
+	case consonantY:
+		add(head + "ing")
+		add(head[:n-1] + "ied")
+		add(head[:n-1] + "ier")
+		add(head[:n-1] + "iest")
+		return ret
+	case isShortCVC(head):
+		doubled := head + string(last)
+		add(doubled + "ing")
+		add(doubled + "ed")
+		add(doubled + "er")
+		add(doubled + "est")
+		return ret
+	}
+	add(stem + "ing")
+	add(stem + "ed")
+	add(stem + "er")
+	add(stem + "est")
+	return ret
+}
+
+// IsFormOf reports whether form is the lemma itself or one of its inflections.
+func IsFormOf(form, lemma string) bool {
+	if form == "" || lemma == "" {
+		return false
+	}
+	if form == lemma {
+		return true
+	}
+	for _, item := range Forms(lemma) {
+		if item == form {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPhrase(text string) (string, string) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if idx := strings.IndexByte(text, ' '); idx > 0 {
+		return text[:idx], text[idx:]
+	}
+	return text, ""
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
+
+func isConsonant(c byte) bool {
+	return c >= 'a' && c <= 'z' && !isVowel(c)
+}
+
+func hasSibilantEnd(word string) bool {
+	return strings.HasSuffix(word, "s") || strings.HasSuffix(word, "x") || strings.HasSuffix(word, "z") ||
+		strings.HasSuffix(word, "ch") || strings.HasSuffix(word, "sh")
+}
+
+// needsSilentE reports stems that are not English words without a final "e".
+func needsSilentE(stem string) bool {
+	for _, suffix := range []string{"v", "u", "c", "dg", "rg"} {
+		if strings.HasSuffix(stem, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isShortCVC reports a one-syllable word ending consonant-vowel-consonant,
+// the shape whose final consonant doubles before -ing/-ed (hop -> hopping).
+func isShortCVC(word string) bool {
+	n := len(word)
+	if n < 3 {
+		return false
+	}
+	a, b, c := word[n-3], word[n-2], word[n-1]
+	if !isConsonant(a) || !isVowel(b) || !isConsonant(c) || strings.IndexByte("wxy", c) >= 0 {
+		return false
+	}
+	groups := 0
+	inVowel := false
+	for i := 0; i < n; i++ {
+		v := isVowel(word[i])
+		if v && !inVowel {
+			groups++
+		}
+		inVowel = v
+	}
+	return groups == 1
+}
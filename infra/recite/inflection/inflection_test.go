@@ -0,0 +1,148 @@
+package inflection
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	cases := []struct {
+		form string
+		want []string
+	}{
+		{"studies", []string{"study"}},
+		{"tried", []string{"try"}},
+		{"happiest", []string{"happy"}},
+		{"dying", []string{"dy", "die"}},
+		{"running", []string{"run"}},
+		{"stopped", []string{"stop", "stopp"}},
+		{"making", []string{"make"}},
+		{"hoped", []string{"hope", "hop"}},
+		{"charged", []string{"charge", "charg"}},
+		{"agreed", []string{"agree"}},
+		{"played", []string{"play", "playe"}},
+		{"falling", []string{"fall", "falle"}},
+		{"larger", []string{"large", "larg"}},
+		{"quickest", []string{"quick", "quicke"}},
+		{"watches", []string{"watch", "watche"}},
+		{"heroes", []string{"hero", "heroe"}},
+		{"makes", []string{"make"}},
+		{"cats", []string{"cat"}},
+		{"Cats", []string{"cat"}},
+		// irregular table
+		{"went", []string{"go"}},
+		{"is", []string{"be"}},
+		{"better", []string{"good"}},
+		{"children", []string{"child"}},
+		{"gave up", []string{"give up"}},
+		// lemmas and words that only look inflected
+		{"read", []string{}},
+		{"cost", []string{}},
+		{"glass", []string{}},
+		{"bus", []string{}},
+		{"analysis", []string{}},
+		{"series", []string{}},
+		{"species", []string{}},
+		{"news", []string{}},
+		{"physics", []string{}},
+		{"a", []string{}},
+		{"", []string{}},
+	}
+	for _, tc := range cases {
+		if got := Candidates(tc.form); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("Candidates(%q) = %q, want %q", tc.form, got, tc.want)
+		}
+	}
+}
+
+func TestForms(t *testing.T) {
+	cases := []struct {
+		lemma string
+		want  []string
+	}{
+		{"play", []string{"plays", "playing", "played", "player", "playest"}},
+		{"box", []string{"boxes", "boxing", "boxed", "boxer", "boxest"}},
+		{"study", []string{"studies", "studying", "studied", "studier", "studiest"}},
+		{"die", []string{"dies", "dying", "died"}},
+		{"make", []string{"made", "making", "makes", "maked", "maker", "makest"}},
+		{"agree", []string{"agrees", "agreeing", "agreed", "agreer", "agreest"}},
+		{"hop", []string{"hops", "hopping", "hopped", "hopper", "hoppest"}},
+		{"visit", []string{"visits", "visiting", "visited", "visiter", "visitest"}},
+		{"give up", []string{"gave up", "given up", "giving up", "gives up", "gived up", "giver up", "givest up"}},
+		{"news", []string{}},
+		{"", []string{}},
+	}
+	for _, tc := range cases {
+		if got := Forms(tc.lemma); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("Forms(%q) = %q, want %q", tc.lemma, got, tc.want)
+		}
+	}
+}
+
+func TestIsFormOf(t *testing.T) {
+	cases := []struct {
+		form, lemma string
+		want        bool
+	}{
+		{"run", "run", true},
+		{"ran", "run", true},
+		{"running", "run", true},
+		{"studied", "study", true},
+		{"gave up", "give up", true},
+		{"runs", "ran", false},
+		{"series", "series", true},
+		{"seriess", "series", false},
+		{"", "run", false},
+		{"run", "", false},
+	}
+	for _, tc := range cases {
+		if got := IsFormOf(tc.form, tc.lemma); got != tc.want {
+			t.Errorf("IsFormOf(%q, %q) = %t, want %t", tc.form, tc.lemma, got, tc.want)
+		}
+	}
+}
+
+// TestIrregularTable checks every line of irregular.txt: each form resolves to
+// a lemma of the table and is a form of its own lemma.
+func TestIrregularTable(t *testing.T) {
+	lines := 0
+	for _, line := range strings.Split(irregularData, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+		fields := strings.Fields(line)
+		lemma := fields[0]
+		if _, ok := irregularForms[lemma]; !ok {
+			t.Errorf("lemma %q not loaded", lemma)
+		}
+		// "lay" is a lemma and also a form of "lie"
+		if _, isForm := irregularLemma[lemma]; !isForm && len(Candidates(lemma)) != 0 {
+			t.Errorf("Candidates(%q) = %q, a lemma of the table has none", lemma, Candidates(lemma))
+		}
+		for _, form := range fields[1:] {
+			if form == lemma {
+				continue
+			}
+			if !IsFormOf(form, lemma) {
+				t.Errorf("IsFormOf(%q, %q) = false", form, lemma)
+			}
+			got, ok := IrregularLemma(form)
+			if !ok {
+				t.Errorf("IrregularLemma(%q) not found", form)
+				continue
+			}
+			// a shared form ("better") resolves to the first lemma listing it
+			if _, isLemma := irregularForms[got]; !isLemma {
+				t.Errorf("IrregularLemma(%q) = %q, not a lemma of the table", form, got)
+			}
+			if candidates := Candidates(form); len(candidates) != 1 || candidates[0] != got {
+				t.Errorf("Candidates(%q) = %q, want [%q]", form, candidates, got)
+			}
+		}
+	}
+	if lines == 0 {
+		t.Fatalf("irregular.txt is empty")
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type ForgottenWordRepository struct {
@@ -56,3 +57,19 @@ func (r *ForgottenWordRepository) MarkRememberedByWord(ctx context.Context, word
 	`, word)
 	return err
 }
+
+func (r *ForgottenWordRepository) GetLatestUnrememberedAt(ctx context.Context, word string) (*time.Time, error) {
+	var latest sql.NullTime
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(created_at)
+		FROM forgotten_words
+		WHERE word = ? AND remembered = 0
+	`, word).Scan(&latest); err != nil {
+		return nil, err
+	}
+	if !latest.Valid {
+		return nil, nil
+	}
+	t := latest.Time
+	return &t, nil
+}
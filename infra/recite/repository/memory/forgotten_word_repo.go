@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type ForgottenWordRepository struct {
+	store *Store
+}
+
+func NewForgottenWordRepository(store *Store) *ForgottenWordRepository {
+	return &ForgottenWordRepository{store: store}
+}
+
+func (r *ForgottenWordRepository) Add(ctx context.Context, word string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.forgotten = append(r.store.forgotten, forgottenRow{
+		ID:        r.store.nextID("forgotten_words"),
+		Word:      word,
+		CreatedAt: r.store.now(),
+	})
+	return nil
+}
+
+// ListUnrememberedDistinct orders words by their latest unremembered record.
+func (r *ForgottenWordRepository) ListUnrememberedDistinct(ctx context.Context) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	latest := make(map[string]time.Time)
+	for _, row := range r.store.forgotten {
+		if row.Remembered {
+			continue
+		}
+		if at, ok := latest[row.Word]; !ok || row.CreatedAt.After(at) {
+			latest[row.Word] = row.CreatedAt
+		}
+	}
+	ret := make([]string, 0, len(latest))
+	for word := range latest {
+		ret = append(ret, word)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !latest[ret[i]].Equal(latest[ret[j]]) {
+			return latest[ret[i]].After(latest[ret[j]])
+		}
+		return ret[i] < ret[j]
+	})
+	return ret, nil
+}
+
+func (r *ForgottenWordRepository) MarkRememberedByWord(ctx context.Context, word string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.forgotten {
+		if r.store.forgotten[i].Word == word {
+			r.store.forgotten[i].Remembered = true
+		}
+	}
+	return nil
+}
+
+func (r *ForgottenWordRepository) GetLatestUnrememberedAt(ctx context.Context, word string) (*time.Time, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var latest *time.Time
+	for _, row := range r.store.forgotten {
+		if row.Word != word || row.Remembered {
+			continue
+		}
+		if latest == nil || row.CreatedAt.After(*latest) {
+			t := row.CreatedAt
+			latest = &t
+		}
+	}
+	return latest, nil
+}
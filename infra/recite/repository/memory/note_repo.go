@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/repository"
+)
+
+type NoteRepository struct {
+	store *Store
+}
+
+func NewNoteRepository(store *Store) *NoteRepository {
+	return &NoteRepository{store: store}
+}
+
+func (r *NoteRepository) Create(ctx context.Context, noteType, content string, wordIDs, refWordIDs []int64) (*entity.Note, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	now := r.store.now()
+	item := entity.Note{
+		ID:        r.store.nextID("notes"),
+		NoteType:  noteType,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.store.notes[item.ID] = item
+	r.addWordRelations(item.ID, wordIDs, false)
+	r.addWordRelations(item.ID, refWordIDs, true)
+	return &item, nil
+}
+
+// Update replaces the note content and all of its word relations.
+func (r *NoteRepository) Update(ctx context.Context, noteID int64, noteType, content string, wordIDs, refWordIDs []int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if item, ok := r.store.notes[noteID]; ok {
+		item.NoteType = noteType
+		item.Content = content
+		item.UpdatedAt = r.store.now()
+		r.store.notes[noteID] = item
+	}
+	r.removeWordRelations(func(rel entity.NoteWordRelation) bool { return rel.NoteID == noteID })
+	r.addWordRelations(noteID, wordIDs, false)
+	r.addWordRelations(noteID, refWordIDs, true)
+	return nil
+}
+
+func (r *NoteRepository) GetByID(ctx context.Context, noteID int64) (*entity.Note, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.notes[noteID]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (r *NoteRepository) List(ctx context.Context, limit, offset int) ([]repository.NoteListRow, int64, error) {
+	return r.list(false, limit, offset)
+}
+
+func (r *NoteRepository) ListTrashed(ctx context.Context, limit, offset int) ([]repository.NoteListRow, int64, error) {
+	return r.list(true, limit, offset)
+}
+
+func (r *NoteRepository) list(trashed bool, limit, offset int) ([]repository.NoteListRow, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	wordCounts := make(map[int64]int)
+	for _, rel := range r.store.noteWords {
+		wordCounts[rel.NoteID]++
+	}
+	notes := make([]entity.Note, 0, len(r.store.notes))
+	for _, note := range r.sortedNotes() {
+		if (note.DeletedAt != nil) == trashed {
+			notes = append(notes, note)
+		}
+	}
+	if trashed {
+		sort.SliceStable(notes, func(i, j int) bool {
+			return notes[i].DeletedAt.After(*notes[j].DeletedAt)
+		})
+	}
+	ret := make([]repository.NoteListRow, 0, limit)
+	for i := offset; i < len(notes) && len(ret) < limit; i++ {
+		ret = append(ret, repository.NoteListRow{Note: notes[i], WordCount: wordCounts[notes[i].ID]})
+	}
+	return ret, int64(len(notes)), nil
+}
+
+func (r *NoteRepository) SoftDelete(ctx context.Context, noteID int64, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if item, ok := r.store.notes[noteID]; ok && item.DeletedAt == nil {
+		at = at.Truncate(time.Second)
+		item.DeletedAt = &at
+		r.store.notes[noteID] = item
+	}
+	return nil
+}
+
+func (r *NoteRepository) Restore(ctx context.Context, noteID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if item, ok := r.store.notes[noteID]; ok {
+		item.DeletedAt = nil
+		r.store.notes[noteID] = item
+	}
+	return nil
+}
+
+func (r *NoteRepository) Delete(ctx context.Context, noteID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.notes, noteID)
+	r.removeWordRelations(func(rel entity.NoteWordRelation) bool { return rel.NoteID == noteID })
+	return nil
+}
+
+func (r *NoteRepository) UnlinkWord(ctx context.Context, noteID, wordID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.removeWordRelations(func(rel entity.NoteWordRelation) bool { return rel.NoteID == noteID && rel.WordID == wordID })
+	if item, ok := r.store.notes[noteID]; ok {
+		item.UpdatedAt = r.store.now()
+		r.store.notes[noteID] = item
+	}
+	return nil
+}
+
+func (r *NoteRepository) ListWordRelationsByNoteID(ctx context.Context, noteID int64) ([]entity.NoteWordRelation, error) {
+	return r.ListWordRelationsByNoteIDs(ctx, []int64{noteID})
+}
+
+func (r *NoteRepository) ListWordRelationsByNoteIDs(ctx context.Context, noteIDs []int64) ([]entity.NoteWordRelation, error) {
+	wanted := make(map[int64]struct{}, len(noteIDs))
+	for _, id := range noteIDs {
+		wanted[id] = struct{}{}
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make([]entity.NoteWordRelation, 0)
+	for _, rel := range r.store.noteWords {
+		if _, ok := wanted[rel.NoteID]; ok {
+			ret = append(ret, rel)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].NoteID != ret[j].NoteID {
+			return ret[i].NoteID < ret[j].NoteID
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+func (r *NoteRepository) ListByType(ctx context.Context, noteType string) ([]entity.Note, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make([]entity.Note, 0)
+	for _, note := range r.sortedNotes() {
+		if note.NoteType == noteType && note.DeletedAt == nil {
+			ret = append(ret, note)
+		}
+	}
+	return ret, nil
+}
+
+// ListByWordIDs returns the id and type of the notes linked to each word,
+// newest first.
+func (r *NoteRepository) ListByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]entity.Note, error) {
+	wanted := make(map[int64]struct{}, len(wordIDs))
+	for _, id := range wordIDs {
+		wanted[id] = struct{}{}
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make(map[int64][]entity.Note)
+	for _, note := range r.sortedNotes() {
+		if note.DeletedAt != nil {
+			continue
+		}
+		for _, rel := range r.store.noteWords {
+			if rel.NoteID != note.ID {
+				continue
+			}
+			if _, ok := wanted[rel.WordID]; ok {
+				ret[rel.WordID] = append(ret[rel.WordID], entity.Note{ID: note.ID, NoteType: note.NoteType})
+			}
+		}
+	}
+	return ret, nil
+}
+
+// removeWordRelations drops the word links matching remove. Callers hold mu.
+func (r *NoteRepository) removeWordRelations(remove func(rel entity.NoteWordRelation) bool) {
+	kept := r.store.noteWords[:0]
+	for _, rel := range r.store.noteWords {
+		if !remove(rel) {
+			kept = append(kept, rel)
+		}
+	}
+	r.store.noteWords = kept
+}
+
+// addWordRelations links wordIDs to a note. Callers hold mu.
+func (r *NoteRepository) addWordRelations(noteID int64, wordIDs []int64, fromRef bool) {
+	now := r.store.now()
+	for _, wordID := range wordIDs {
+		r.store.noteWords = append(r.store.noteWords, entity.NoteWordRelation{
+			ID:        r.store.nextID("note_words"),
+			NoteID:    noteID,
+			WordID:    wordID,
+			FromRef:   fromRef,
+			CreatedAt: now,
+		})
+	}
+}
+
+// Search matches terms case-insensitively; the score is the number of term
+// occurrences, standing in for full-text relevance.
+func (r *NoteRepository) Search(ctx context.Context, filter repository.NoteSearchFilter, limit, offset int) ([]repository.NoteSearchRow, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	matched := make([]repository.NoteSearchRow, 0)
+	for _, note := range r.store.notes {
+		if note.DeletedAt != nil {
+			continue
+		}
+		if filter.NoteType != "" && note.NoteType != filter.NoteType {
+			continue
+		}
+		if filter.WordID > 0 && !r.linked(note.ID, filter.WordID) {
+			continue
+		}
+		content := strings.ToLower(note.Content)
+		score := 0
+		for _, term := range filter.Terms {
+			count := strings.Count(content, strings.ToLower(term))
+			if count == 0 {
+				score = -1
+				break
+			}
+			score += count
+		}
+		if score < 0 {
+			continue
+		}
+		matched = append(matched, repository.NoteSearchRow{Note: note, Score: float64(score)})
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if filter.Sort == repository.NoteSearchRelevance && a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.Note.UpdatedAt.Equal(b.Note.UpdatedAt) {
+			return a.Note.UpdatedAt.After(b.Note.UpdatedAt)
+		}
+		return a.Note.ID > b.Note.ID
+	})
+	ret := make([]repository.NoteSearchRow, 0, limit)
+	for i := offset; i < len(matched) && len(ret) < limit; i++ {
+		ret = append(ret, matched[i])
+	}
+	return ret, int64(len(matched)), nil
+}
+
+func (r *NoteRepository) linked(noteID, wordID int64) bool {
+	for _, rel := range r.store.noteWords {
+		if rel.NoteID == noteID && rel.WordID == wordID {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedNotes returns notes ordered by created_at DESC, id DESC. Callers hold mu.
+func (r *NoteRepository) sortedNotes() []entity.Note {
+	ret := make([]entity.Note, 0, len(r.store.notes))
+	for _, item := range r.store.notes {
+		ret = append(ret, item)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].CreatedAt.Equal(ret[j].CreatedAt) {
+			return ret[i].CreatedAt.After(ret[j].CreatedAt)
+		}
+		return ret[i].ID > ret[j].ID
+	})
+	return ret
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/repository"
+)
+
+type QuizRepository struct {
+	store *Store
+}
+
+func NewQuizRepository(store *Store) *QuizRepository {
+	return &QuizRepository{store: store}
+}
+
+func (r *QuizRepository) Create(ctx context.Context, quiz *entity.Quiz, wordIDs []int64, choices [][]int64) (*entity.Quiz, error) {
+	if quiz == nil {
+		return nil, fmt.Errorf("quiz is nil")
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	now := r.store.now()
+	item := *quiz
+	item.ID = r.store.nextID("quizzes")
+	item.SourceReviewDate = datePtr(quiz.SourceReviewDate)
+	item.CreatedAt = now
+	item.UpdatedAt = now
+	r.store.quizzes[item.ID] = item
+	for i, wordID := range wordIDs {
+		qw := entity.QuizWord{
+			ID:        r.store.nextID("quiz_words"),
+			QuizID:    item.ID,
+			WordID:    wordID,
+			OrderNo:   i + 1,
+			Status:    "未测试",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if i < len(choices) && len(choices[i]) > 0 {
+			qw.ChoiceWordIDs = append([]int64{}, choices[i]...)
+		}
+		r.store.quizWords = append(r.store.quizWords, qw)
+	}
+	return &item, nil
+}
+
+func (r *QuizRepository) GetByID(ctx context.Context, quizID int64) (*entity.Quiz, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.quizzes[quizID]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (r *QuizRepository) List(ctx context.Context, limit, offset int) ([]repository.QuizListRow, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stats := make(map[int64]*repository.QuizListRow, len(r.store.quizzes))
+	for _, qw := range r.store.quizWords {
+		stat, ok := stats[qw.QuizID]
+		if !ok {
+			stat = &repository.QuizListRow{}
+			stats[qw.QuizID] = stat
+		}
+		stat.TotalWords++
+		if qw.Status == "已测试" {
+			stat.TestedWords++
+		}
+		switch qw.Result {
+		case "正确":
+			stat.CorrectCount++
+		case "错误":
+			stat.WrongCount++
+		case "忘记":
+			stat.ForgottenCount++
+		}
+	}
+
+	quizzes := make([]entity.Quiz, 0, len(r.store.quizzes))
+	for _, item := range r.store.quizzes {
+		quizzes = append(quizzes, item)
+	}
+	sort.Slice(quizzes, func(i, j int) bool {
+		if !quizzes[i].CreatedAt.Equal(quizzes[j].CreatedAt) {
+			return quizzes[i].CreatedAt.After(quizzes[j].CreatedAt)
+		}
+		return quizzes[i].ID > quizzes[j].ID
+	})
+
+	ret := make([]repository.QuizListRow, 0, limit)
+	for i := offset; i < len(quizzes) && len(ret) < limit; i++ {
+		row := repository.QuizListRow{}
+		if stat, ok := stats[quizzes[i].ID]; ok {
+			row = *stat
+		}
+		row.Quiz = quizzes[i]
+		ret = append(ret, row)
+	}
+	return ret, int64(len(quizzes)), nil
+}
+
+func (r *QuizRepository) HasRunning(ctx context.Context) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, item := range r.store.quizzes {
+		if item.Status == "进行中" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *QuizRepository) ListWords(ctx context.Context, quizID int64) ([]entity.QuizWord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make([]entity.QuizWord, 0)
+	for _, qw := range r.store.quizWords {
+		if qw.QuizID == quizID {
+			ret = append(ret, qw)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].OrderNo < ret[j].OrderNo
+	})
+	return ret, nil
+}
+
+func (r *QuizRepository) GetWordByOrder(ctx context.Context, quizID int64, orderNo int) (*entity.QuizWord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, qw := range r.store.quizWords {
+		if qw.QuizID == quizID && qw.OrderNo == orderNo {
+			return &qw, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *QuizRepository) ListRecentResultsByWord(
+	ctx context.Context,
+	wordID int64,
+	sourceKind string,
+	since time.Time,
+	limit int,
+) ([]string, error) {
+	if limit <= 0 {
+		return []string{}, nil
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	matched := make([]entity.QuizWord, 0)
+	for _, qw := range r.store.quizWords {
+		if qw.WordID != wordID || qw.Status != "已测试" || qw.UpdatedAt.Before(since) {
+			continue
+		}
+		if r.store.quizzes[qw.QuizID].SourceKind != sourceKind {
+			continue
+		}
+		matched = append(matched, qw)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].UpdatedAt.Equal(matched[j].UpdatedAt) {
+			return matched[i].UpdatedAt.After(matched[j].UpdatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+	ret := make([]string, 0, limit)
+	for _, qw := range matched {
+		if len(ret) >= limit {
+			break
+		}
+		ret = append(ret, qw.Result)
+	}
+	return ret, nil
+}
+
+// UpdateWordResult returns sql.ErrNoRows when no quiz word matches, like the
+// SQL repository does for zero affected rows.
+func (r *QuizRepository) UpdateWordResult(
+	ctx context.Context,
+	quizID int64,
+	orderNo int,
+	inputAnswer string,
+	result string,
+) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for i := range r.store.quizWords {
+		qw := &r.store.quizWords[i]
+		if qw.QuizID == quizID && qw.OrderNo == orderNo {
+			qw.Status = "已测试"
+			qw.InputAnswer = inputAnswer
+			qw.Result = result
+			qw.UpdatedAt = r.store.now()
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *QuizRepository) Finish(ctx context.Context, quizID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.quizzes[quizID]
+	if !ok {
+		return nil
+	}
+	item.Status = "已完结"
+	item.UpdatedAt = r.store.now()
+	r.store.quizzes[quizID] = item
+	return nil
+}
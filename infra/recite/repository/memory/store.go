@@ -0,0 +1,70 @@
+// Package memory holds in-memory implementations of the recite repositories.
+// They mirror the ordering and edge cases of the MySQL repositories closely
+// enough to run the service in tests without a database.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+)
+
+// Store is the shared state of the in-memory repositories, the equivalent of
+// one database. Now stamps created_at and updated_at and may be replaced to
+// control time.
+type Store struct {
+	Now func() time.Time
+
+	mu        sync.Mutex
+	lastIDs   map[string]int64
+	words     map[int64]*entity.Word
+	units     map[int64]entity.ReciteUnit
+	unitWords []entity.UnitWordRelation
+	forgotten []forgottenRow
+	quizzes   map[int64]entity.Quiz
+	quizWords []entity.QuizWord
+	notes     map[int64]entity.Note
+	noteWords []entity.NoteWordRelation
+	wordForms []wordFormRow
+}
+
+type forgottenRow struct {
+	ID         int64
+	Word       string
+	Remembered bool
+	CreatedAt  time.Time
+}
+
+type wordFormRow struct {
+	ID     int64
+	WordID int64
+	Form   string
+}
+
+func NewStore() *Store {
+	return &Store{
+		Now:     time.Now,
+		lastIDs: make(map[string]int64),
+		words:   make(map[int64]*entity.Word),
+		units:   make(map[int64]entity.ReciteUnit),
+		quizzes: make(map[int64]entity.Quiz),
+		notes:   make(map[int64]entity.Note),
+	}
+}
+
+// nextID returns the next auto-increment id of table. Callers hold mu.
+func (s *Store) nextID(table string) int64 {
+	s.lastIDs[table]++
+	return s.lastIDs[table]
+}
+
+// now returns the current time truncated to seconds like a DATETIME column.
+func (s *Store) now() time.Time {
+	return s.Now().Truncate(time.Second)
+}
+
+// dateOnly drops the clock part of t like a DATE column.
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
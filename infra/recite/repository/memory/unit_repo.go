@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+)
+
+type UnitRepository struct {
+	store *Store
+}
+
+func NewUnitRepository(store *Store) *UnitRepository {
+	return &UnitRepository{store: store}
+}
+
+func (r *UnitRepository) List(ctx context.Context) ([]entity.ReciteUnit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.sortedUnits(func(entity.ReciteUnit) bool { return true }), nil
+}
+
+func (r *UnitRepository) GetByID(ctx context.Context, id int64) (*entity.ReciteUnit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.units[id]
+	if !ok {
+		return nil, nil
+	}
+	return &item, nil
+}
+
+func (r *UnitRepository) Create(ctx context.Context, name string, reciteDate *time.Time) (*entity.ReciteUnit, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	maxSort := int64(0)
+	for _, item := range r.store.units {
+		if item.SortOrder > maxSort {
+			maxSort = item.SortOrder
+		}
+	}
+	now := r.store.now()
+	item := entity.ReciteUnit{
+		ID:         r.store.nextID("recite_units"),
+		Name:       name,
+		ReciteDate: datePtr(reciteDate),
+		SortOrder:  maxSort + 1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	r.store.units[item.ID] = item
+	return &item, nil
+}
+
+func (r *UnitRepository) Rename(ctx context.Context, id int64, name string, reciteDate *time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.units[id]
+	if !ok {
+		return nil
+	}
+	item.Name = name
+	item.ReciteDate = datePtr(reciteDate)
+	item.UpdatedAt = r.store.now()
+	r.store.units[id] = item
+	return nil
+}
+
+func (r *UnitRepository) Count(ctx context.Context) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return int64(len(r.store.units)), nil
+}
+
+func (r *UnitRepository) Reorder(ctx context.Context, unitIDs []int64) error {
+	if len(unitIDs) == 0 {
+		return nil
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	found := make(map[int64]struct{}, len(unitIDs))
+	for _, id := range unitIDs {
+		if _, ok := r.store.units[id]; ok {
+			found[id] = struct{}{}
+		}
+	}
+	if len(found) != len(unitIDs) {
+		return fmt.Errorf("unit list contains unknown id")
+	}
+	base := int64(len(unitIDs))
+	for idx, id := range unitIDs {
+		item := r.store.units[id]
+		item.SortOrder = base - int64(idx)
+		r.store.units[id] = item
+	}
+	return nil
+}
+
+func (r *UnitRepository) Delete(ctx context.Context, id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	kept := r.store.unitWords[:0]
+	for _, rel := range r.store.unitWords {
+		if rel.UnitID != id {
+			kept = append(kept, rel)
+		}
+	}
+	r.store.unitWords = kept
+	delete(r.store.units, id)
+	return nil
+}
+
+// ListReviewByDate compares calendar days like DATEDIFF(targetDate, recite_date).
+func (r *UnitRepository) ListReviewByDate(ctx context.Context, targetDate time.Time, intervals []int) ([]entity.ReciteUnit, error) {
+	if len(intervals) == 0 {
+		return []entity.ReciteUnit{}, nil
+	}
+	wanted := make(map[int]struct{}, len(intervals))
+	for _, d := range intervals {
+		wanted[d] = struct{}{}
+	}
+	target := dateOnly(targetDate)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.sortedUnits(func(item entity.ReciteUnit) bool {
+		if item.ReciteDate == nil {
+			return false
+		}
+		_, ok := wanted[daysBetween(*item.ReciteDate, target)]
+		return ok
+	}), nil
+}
+
+// sortedUnits returns the units accepted by keep ordered by sort_order DESC,
+// id DESC. Callers hold mu.
+func (r *UnitRepository) sortedUnits(keep func(entity.ReciteUnit) bool) []entity.ReciteUnit {
+	ret := make([]entity.ReciteUnit, 0)
+	for _, item := range r.store.units {
+		if keep(item) {
+			ret = append(ret, item)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].SortOrder != ret[j].SortOrder {
+			return ret[i].SortOrder > ret[j].SortOrder
+		}
+		return ret[i].ID > ret[j].ID
+	})
+	return ret
+}
+
+func datePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	d := dateOnly(*t)
+	return &d
+}
+
+// daysBetween counts calendar days from one date to another.
+func daysBetween(from, to time.Time) int {
+	fromUTC := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toUTC := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toUTC.Sub(fromUTC).Hours() / 24)
+}
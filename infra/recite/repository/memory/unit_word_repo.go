@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+)
+
+type UnitWordRepository struct {
+	store *Store
+}
+
+func NewUnitWordRepository(store *Store) *UnitWordRepository {
+	return &UnitWordRepository{store: store}
+}
+
+// Add ignores a word that is already in the unit, like the unique key does.
+func (r *UnitWordRepository) Add(ctx context.Context, unitID, wordID int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, rel := range r.store.unitWords {
+		if rel.UnitID == unitID && rel.WordID == wordID {
+			return nil
+		}
+	}
+	r.store.unitWords = append(r.store.unitWords, entity.UnitWordRelation{
+		ID:        r.store.nextID("recite_unit_words"),
+		UnitID:    unitID,
+		WordID:    wordID,
+		CreatedAt: r.store.now(),
+	})
+	return nil
+}
+
+func (r *UnitWordRepository) ListByUnitID(ctx context.Context, unitID int64) ([]entity.UnitWordRelation, error) {
+	return r.ListByUnitIDs(ctx, []int64{unitID})
+}
+
+func (r *UnitWordRepository) ListByUnitIDs(ctx context.Context, unitIDs []int64) ([]entity.UnitWordRelation, error) {
+	wanted := make(map[int64]struct{}, len(unitIDs))
+	for _, id := range unitIDs {
+		wanted[id] = struct{}{}
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make([]entity.UnitWordRelation, 0)
+	for _, rel := range r.store.unitWords {
+		if _, ok := wanted[rel.UnitID]; ok {
+			ret = append(ret, rel)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].CreatedAt.Equal(ret[j].CreatedAt) {
+			return ret[i].CreatedAt.After(ret[j].CreatedAt)
+		}
+		return ret[i].ID > ret[j].ID
+	})
+	return ret, nil
+}
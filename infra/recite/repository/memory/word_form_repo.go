@@ -0,0 +1,60 @@
+package memory
+
+import "context"
+
+type WordFormRepository struct {
+	store *Store
+}
+
+func NewWordFormRepository(store *Store) *WordFormRepository {
+	return &WordFormRepository{store: store}
+}
+
+func (r *WordFormRepository) Add(ctx context.Context, wordID int64, form string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, row := range r.store.wordForms {
+		if row.WordID == wordID && row.Form == form {
+			return nil
+		}
+	}
+	r.store.wordForms = append(r.store.wordForms, wordFormRow{
+		ID:     r.store.nextID("word_forms"),
+		WordID: wordID,
+		Form:   form,
+	})
+	return nil
+}
+
+// GetWordIDsByForms returns the latest word recorded for each form.
+func (r *WordFormRepository) GetWordIDsByForms(ctx context.Context, forms []string) (map[string]int64, error) {
+	wanted := make(map[string]struct{}, len(forms))
+	for _, form := range forms {
+		wanted[form] = struct{}{}
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make(map[string]int64)
+	for _, row := range r.store.wordForms {
+		if _, ok := wanted[row.Form]; ok {
+			ret[row.Form] = row.WordID
+		}
+	}
+	return ret, nil
+}
+
+func (r *WordFormRepository) ListByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]string, error) {
+	wanted := make(map[int64]struct{}, len(wordIDs))
+	for _, id := range wordIDs {
+		wanted[id] = struct{}{}
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make(map[int64][]string)
+	for _, row := range r.store.wordForms {
+		if _, ok := wanted[row.WordID]; ok {
+			ret[row.WordID] = append(ret[row.WordID], row.Form)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,364 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wutianfang/moss/infra/recite/entity"
+	"github.com/wutianfang/moss/infra/recite/repository"
+)
+
+type WordRepository struct {
+	store *Store
+}
+
+func NewWordRepository(store *Store) *WordRepository {
+	return &WordRepository{store: store}
+}
+
+func (r *WordRepository) GetByWord(ctx context.Context, word string) (*entity.Word, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, item := range r.store.words {
+		if item.Word == word {
+			return cloneWord(item)
+		}
+	}
+	return nil, nil
+}
+
+func (r *WordRepository) GetByID(ctx context.Context, id int64) (*entity.Word, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	item, ok := r.store.words[id]
+	if !ok {
+		return nil, nil
+	}
+	return cloneWord(item)
+}
+
+func (r *WordRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*entity.Word, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make(map[int64]*entity.Word, len(ids))
+	for _, id := range ids {
+		item, ok := r.store.words[id]
+		if !ok {
+			continue
+		}
+		cloned, err := cloneWord(item)
+		if err != nil {
+			return nil, err
+		}
+		ret[id] = cloned
+	}
+	return ret, nil
+}
+
+func (r *WordRepository) GetByWords(ctx context.Context, words []string) (map[string]*entity.Word, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	wanted := make(map[string]bool, len(words))
+	for _, word := range words {
+		wanted[word] = true
+	}
+	ret := make(map[string]*entity.Word, len(words))
+	for _, item := range r.store.words {
+		if !wanted[item.Word] {
+			continue
+		}
+		cloned, err := cloneWord(item)
+		if err != nil {
+			return nil, err
+		}
+		ret[item.Word] = cloned
+	}
+	return ret, nil
+}
+
+// Create stores the fetched columns of word only, like the SQL insert.
+func (r *WordRepository) Create(ctx context.Context, word *entity.Word) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, item := range r.store.words {
+		if item.Word == word.Word {
+			return fmt.Errorf("duplicate word %q", word.Word)
+		}
+	}
+	now := r.store.now()
+	item, err := cloneWord(&entity.Word{
+		Word:           word.Word,
+		PhEn:           word.PhEn,
+		PhAm:           word.PhAm,
+		MeanTag:        word.MeanTag,
+		Parts:          word.Parts,
+		SentenceGroups: word.SentenceGroups,
+		FetchedAt:      &now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		return err
+	}
+	item.ID = r.store.nextID("words")
+	r.store.words[item.ID] = item
+	word.ID = item.ID
+	return nil
+}
+
+func (r *WordRepository) Update(ctx context.Context, word *entity.Word) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	current, ok := r.store.words[word.ID]
+	if !ok {
+		return nil
+	}
+	item, err := cloneWord(word)
+	if err != nil {
+		return err
+	}
+	item.Word = current.Word
+	item.CreatedAt = current.CreatedAt
+	item.UpdatedAt = r.store.now()
+	r.store.words[word.ID] = item
+	return nil
+}
+
+func (r *WordRepository) SetSentenceLocalAudio(ctx context.Context, id int64, localURLs map[string]string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	current, ok := r.store.words[id]
+	if !ok {
+		return nil
+	}
+	if setSentenceLocalAudio(current.SentenceGroups, localURLs) {
+		current.UpdatedAt = r.store.now()
+	}
+	return nil
+}
+
+// setSentenceLocalAudio applies localURLs to the sentences without a local
+// url and reports whether any changed.
+func setSentenceLocalAudio(groups []entity.WordSentenceGroup, localURLs map[string]string) bool {
+	changed := false
+	for gi := range groups {
+		sentences := groups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			if local, ok := localURLs[sentence.TTSURL]; ok && sentence.TTSURL != "" && sentence.LocalTTSURL == "" {
+				sentence.LocalTTSURL = local
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (r *WordRepository) ListAudioWords(ctx context.Context) ([]repository.AudioWord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	ret := make([]repository.AudioWord, 0, len(r.store.words))
+	for _, item := range r.sortedWords() {
+		copied, err := cloneWord(item)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, repository.AudioWord{Word: item.Word, NoAudioAccents: copied.NoAudioAccents, SentenceGroups: copied.SentenceGroups})
+	}
+	return ret, nil
+}
+
+func (r *WordRepository) ListForRefresh(ctx context.Context, filter repository.WordRefreshFilter, afterID int64, limit int) ([]*entity.Word, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	anyFilter := filter.MissingMeanTag || filter.NoSentences || filter.FetchedBefore != nil
+	ret := make([]*entity.Word, 0)
+	for _, item := range r.sortedWords() {
+		if len(ret) >= limit {
+			break
+		}
+		if item.ID <= afterID {
+			continue
+		}
+		if anyFilter && !matchRefreshFilter(item, filter) {
+			continue
+		}
+		cloned, err := cloneWord(item)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cloned)
+	}
+	return ret, nil
+}
+
+func matchRefreshFilter(item *entity.Word, filter repository.WordRefreshFilter) bool {
+	if filter.MissingMeanTag && item.MeanTag == "" {
+		return true
+	}
+	if filter.NoSentences && len(item.SentenceGroups) == 0 {
+		return true
+	}
+	if filter.FetchedBefore != nil {
+		fetchedAt := item.CreatedAt
+		if item.FetchedAt != nil {
+			fetchedAt = *item.FetchedAt
+		}
+		if fetchedAt.Before(*filter.FetchedBefore) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedWords returns the stored words in id order. Callers hold mu.
+// Search matches like the SQL repository, with plain string search in place
+// of the full-text indexes; meaning matches are ordered by word length.
+func (r *WordRepository) Search(ctx context.Context, filter repository.WordSearchFilter) ([]*entity.Word, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	matched := make([]*entity.Word, 0)
+	for _, item := range r.store.words {
+		ok, err := r.matchSearch(item, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, item)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].Word, matched[j].Word
+		if filter.Mode == repository.WordSearchSubstring {
+			if ai, bi := strings.Index(a, filter.Query), strings.Index(b, filter.Query); ai != bi {
+				return ai < bi
+			}
+		}
+		if filter.Mode != repository.WordSearchPrefix && len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	if len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	ret := make([]*entity.Word, 0, len(matched))
+	for _, item := range matched {
+		cloned, err := cloneWord(item)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, cloned)
+	}
+	return ret, nil
+}
+
+func (r *WordRepository) matchSearch(item *entity.Word, filter repository.WordSearchFilter) (bool, error) {
+	switch filter.Mode {
+	case repository.WordSearchPrefix:
+		if !strings.HasPrefix(item.Word, filter.Query) {
+			return false, nil
+		}
+	case repository.WordSearchSubstring:
+		if !strings.Contains(item.Word, filter.Query) {
+			return false, nil
+		}
+	case repository.WordSearchMeaning:
+		if !strings.Contains(item.MeanTag, filter.Query) && !partsContain(item.Parts, filter.Query) {
+			return false, nil
+		}
+	default:
+		return false, fmt.Errorf("unknown word search mode %q", filter.Mode)
+	}
+	if filter.InUnit && !r.inUnit(item.ID) {
+		return false, nil
+	}
+	if filter.InForgotten && !r.inForgotten(item.Word) {
+		return false, nil
+	}
+	if filter.HasNotes && !r.hasNotes(item.ID) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *WordRepository) inUnit(wordID int64) bool {
+	for _, rel := range r.store.unitWords {
+		if rel.WordID == wordID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WordRepository) inForgotten(word string) bool {
+	for _, row := range r.store.forgotten {
+		if row.Word == word && !row.Remembered {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WordRepository) hasNotes(wordID int64) bool {
+	for _, rel := range r.store.noteWords {
+		if rel.WordID == wordID && r.store.notes[rel.NoteID].DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func partsContain(parts []entity.WordPart, query string) bool {
+	for _, part := range parts {
+		for _, mean := range part.Means {
+			if strings.Contains(mean, query) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *WordRepository) sortedWords() []*entity.Word {
+	ret := make([]*entity.Word, 0, len(r.store.words))
+	for _, item := range r.store.words {
+		ret = append(ret, item)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID < ret[j].ID
+	})
+	return ret
+}
+
+// cloneWord deep-copies a word through JSON, the same way the SQL repository
+// stores its nested fields, and fills empty slices like scanWord.
+func cloneWord(word *entity.Word) (*entity.Word, error) {
+	data, err := json.Marshal(word)
+	if err != nil {
+		return nil, fmt.Errorf("marshal word failed: %w", err)
+	}
+	item := &entity.Word{}
+	if err := json.Unmarshal(data, item); err != nil {
+		return nil, fmt.Errorf("unmarshal word failed: %w", err)
+	}
+	if item.Parts == nil {
+		item.Parts = make([]entity.WordPart, 0)
+	}
+	if item.CustomParts == nil {
+		item.CustomParts = make([]entity.WordPart, 0)
+	}
+	if item.SentenceGroups == nil {
+		item.SentenceGroups = make([]entity.WordSentenceGroup, 0)
+	}
+	return item, nil
+}
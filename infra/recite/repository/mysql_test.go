@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wutianfang/moss/infra/db"
+	"github.com/wutianfang/moss/infra/recite/entity"
+)
+
+// testDSNEnv names the DSN of a scratch MySQL database for the repository
+// tests, e.g. "root:root@tcp(127.0.0.1:3306)/moss_test?parseTime=True&loc=Local".
+// The tests empty its tables. The in-memory repositories cannot stand in here:
+// what these tests cover is how MySQL itself matches.
+const testDSNEnv = "MOSS_TEST_MYSQL_DSN"
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	conn, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("open mysql: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if err := db.AutoMigrate(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, table := range []string{"note_words", "notes", "word_forms", "words"} {
+		if _, err := conn.Exec("DELETE FROM " + table); err != nil {
+			t.Fatalf("empty %s: %v", table, err)
+		}
+	}
+	return conn
+}
+
+func createTestWords(t *testing.T, repo *WordRepository, words ...entity.Word) map[string]int64 {
+	t.Helper()
+	ids := make(map[string]int64, len(words))
+	for i := range words {
+		if err := repo.Create(context.Background(), &words[i]); err != nil {
+			t.Fatalf("create word %q: %v", words[i].Word, err)
+		}
+		ids[words[i].Word] = words[i].ID
+	}
+	return ids
+}
+
+func wordNames(words []*entity.Word) []string {
+	ret := make([]string, 0, len(words))
+	for _, word := range words {
+		ret = append(ret, word.Word)
+	}
+	return ret
+}
+
+// TestWordSearchMySQL covers words made of InnoDB stopwords and the ngrams
+// containing them, which the ngram full-text index cannot match.
+func TestWordSearchMySQL(t *testing.T) {
+	repo := NewWordRepository(openTestDB(t))
+	ctx := context.Background()
+	createTestWords(t, repo,
+		entity.Word{Word: "abandon", MeanTag: "CET4", Parts: []entity.WordPart{{Part: "vt.", Means: []string{"放弃", "抛弃"}}}},
+		entity.Word{Word: "bandage", Parts: []entity.WordPart{{Part: "n.", Means: []string{"绷带"}}}},
+		entity.Word{Word: "ship", Parts: []entity.WordPart{{Part: "n.", Means: []string{"船"}}}},
+		entity.Word{Word: "island", Parts: []entity.WordPart{{Part: "n.", Means: []string{"岛"}}}},
+	)
+
+	cases := []struct {
+		query, mode string
+		want        []string
+	}{
+		{"band", WordSearchSubstring, []string{"bandage", "abandon"}},
+		{"an", WordSearchSubstring, []string{"bandage", "abandon", "island"}},
+		{"is", WordSearchSubstring, []string{"island"}},
+		{"hi", WordSearchSubstring, []string{"ship"}},
+		{"a", WordSearchSubstring, []string{"abandon", "bandage", "island"}},
+		{"aban", WordSearchPrefix, []string{"abandon"}},
+		{"放弃", WordSearchMeaning, []string{"abandon"}},
+		{"cet", WordSearchMeaning, []string{"abandon"}},
+	}
+	for _, tc := range cases {
+		got, err := repo.Search(ctx, WordSearchFilter{Query: tc.query, Mode: tc.mode})
+		if err != nil {
+			t.Fatalf("Search(%q, %s): %v", tc.query, tc.mode, err)
+		}
+		if names := wordNames(got); !equalStrings(names, tc.want) {
+			t.Errorf("Search(%q, %s) = %v, want %v", tc.query, tc.mode, names, tc.want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestNoteSearchMySQL covers terms whose ngrams contain InnoDB stopwords,
+// which the ngram full-text index drops.
+func TestNoteSearchMySQL(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+	ids := createTestWords(t, NewWordRepository(conn), entity.Word{Word: "abandon"}, entity.Word{Word: "ship"})
+	repo := NewNoteRepository(conn)
+	notes := []struct {
+		noteType, content string
+		wordID            int64
+	}{
+		{"synonym", "abandon a ship in a storm", ids["ship"]},
+		{"usage", "They had to abandon the car.", ids["abandon"]},
+		{"usage", "放弃 is what abandon means", ids["abandon"]},
+	}
+	noteIDs := make([]int64, 0, len(notes))
+	for _, item := range notes {
+		note, err := repo.Create(ctx, item.noteType, item.content, []int64{item.wordID}, nil)
+		if err != nil {
+			t.Fatalf("create note: %v", err)
+		}
+		noteIDs = append(noteIDs, note.ID)
+	}
+	if err := repo.SoftDelete(ctx, noteIDs[2], time.Now()); err != nil {
+		t.Fatalf("trash note: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		filter NoteSearchFilter
+		want   []int64
+	}{
+		{"stopword ngrams", NoteSearchFilter{Terms: []string{"abandon"}}, []int64{noteIDs[1], noteIDs[0]}},
+		{"every term", NoteSearchFilter{Terms: []string{"abandon", "ship"}}, []int64{noteIDs[0]}},
+		{"short term", NoteSearchFilter{Terms: []string{"a", "car"}}, []int64{noteIDs[1]}},
+		{"type", NoteSearchFilter{Terms: []string{"abandon"}, NoteType: "synonym"}, []int64{noteIDs[0]}},
+		{"word", NoteSearchFilter{Terms: []string{"abandon"}, WordID: ids["abandon"]}, []int64{noteIDs[1]}},
+		{"trash", NoteSearchFilter{Terms: []string{"放弃"}}, nil},
+	}
+	for _, tc := range cases {
+		rows, total, err := repo.Search(ctx, tc.filter, 20, 0)
+		if err != nil {
+			t.Fatalf("%s: Search: %v", tc.name, err)
+		}
+		got := make([]int64, 0, len(rows))
+		for _, row := range rows {
+			got = append(got, row.Note.ID)
+		}
+		if total != int64(len(tc.want)) || len(got) != len(tc.want) {
+			t.Errorf("%s: Search = %v (total %d), want %v", tc.name, got, total, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: Search = %v, want %v", tc.name, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestWordFormsMySQL(t *testing.T) {
+	conn := openTestDB(t)
+	ctx := context.Background()
+	ids := createTestWords(t, NewWordRepository(conn), entity.Word{Word: "lie"}, entity.Word{Word: "lay"})
+	repo := NewWordFormRepository(conn)
+	for _, add := range []struct {
+		word, form string
+	}{{"lie", "lay"}, {"lay", "laid"}, {"lay", "lay"}} {
+		if err := repo.Add(ctx, ids[add.word], add.form); err != nil {
+			t.Fatalf("Add(%s, %s) error: %v", add.word, add.form, err)
+		}
+	}
+
+	got, err := repo.GetWordIDsByForms(ctx, []string{"lay", "laid", "lain"})
+	if err != nil {
+		t.Fatalf("GetWordIDsByForms error: %v", err)
+	}
+	// "lay" was last recorded for the word lay
+	if len(got) != 2 || got["lay"] != ids["lay"] || got["laid"] != ids["lay"] {
+		t.Fatalf("word ids = %v, want lay and laid of %d", got, ids["lay"])
+	}
+}
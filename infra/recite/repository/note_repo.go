@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/wutianfang/moss/infra/recite/entity"
 )
@@ -21,7 +23,9 @@ func NewNoteRepository(db *sql.DB) *NoteRepository {
 	return &NoteRepository{db: db}
 }
 
-func (r *NoteRepository) Create(ctx context.Context, noteType, content string, wordIDs []int64) (*entity.Note, error) {
+// Create inserts a note linked to wordIDs, chosen by hand, and refWordIDs,
+// referenced from the content; the two lists must not overlap.
+func (r *NoteRepository) Create(ctx context.Context, noteType, content string, wordIDs, refWordIDs []int64) (*entity.Note, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -41,13 +45,8 @@ func (r *NoteRepository) Create(ctx context.Context, noteType, content string, w
 	if err != nil {
 		return nil, err
 	}
-	for _, wordID := range wordIDs {
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO note_words(note_id, word_id)
-			VALUES(?, ?)
-		`, noteID, wordID); err != nil {
-			return nil, err
-		}
+	if err := insertNoteWords(ctx, tx, noteID, wordIDs, refWordIDs); err != nil {
+		return nil, err
 	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
@@ -55,7 +54,8 @@ func (r *NoteRepository) Create(ctx context.Context, noteType, content string, w
 	return r.GetByID(ctx, noteID)
 }
 
-func (r *NoteRepository) Update(ctx context.Context, noteID int64, noteType, content string, wordIDs []int64) error {
+// Update replaces the note content and all of its word links.
+func (r *NoteRepository) Update(ctx context.Context, noteID int64, noteType, content string, wordIDs, refWordIDs []int64) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -74,55 +74,89 @@ func (r *NoteRepository) Update(ctx context.Context, noteID int64, noteType, con
 	if _, err := tx.ExecContext(ctx, `DELETE FROM note_words WHERE note_id = ?`, noteID); err != nil {
 		return err
 	}
-	for _, wordID := range wordIDs {
-		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO note_words(note_id, word_id)
-			VALUES(?, ?)
-		`, noteID, wordID); err != nil {
-			return err
-		}
+	if err := insertNoteWords(ctx, tx, noteID, wordIDs, refWordIDs); err != nil {
+		return err
 	}
 	return tx.Commit()
 }
 
+func insertNoteWords(ctx context.Context, tx *sql.Tx, noteID int64, wordIDs, refWordIDs []int64) error {
+	for _, link := range []struct {
+		wordIDs []int64
+		fromRef bool
+	}{{wordIDs, false}, {refWordIDs, true}} {
+		for _, wordID := range link.wordIDs {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO note_words(note_id, word_id, from_ref)
+				VALUES(?, ?, ?)
+			`, noteID, wordID, link.fromRef); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetByID returns the note, trashed or not.
 func (r *NoteRepository) GetByID(ctx context.Context, noteID int64) (*entity.Note, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, note_type, content, created_at, updated_at
+		SELECT id, note_type, content, created_at, updated_at, deleted_at
 		FROM notes
 		WHERE id = ?
 		LIMIT 1
 	`, noteID)
 	item := entity.Note{}
-	if err := row.Scan(&item.ID, &item.NoteType, &item.Content, &item.CreatedAt, &item.UpdatedAt); err != nil {
+	var deletedAt sql.NullTime
+	if err := row.Scan(&item.ID, &item.NoteType, &item.Content, &item.CreatedAt, &item.UpdatedAt, &deletedAt); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if deletedAt.Valid {
+		item.DeletedAt = &deletedAt.Time
+	}
 	return &item, nil
 }
 
+// List pages the notes outside the trash, newest first.
 func (r *NoteRepository) List(ctx context.Context, limit, offset int) ([]NoteListRow, int64, error) {
+	return r.list(ctx, false, limit, offset)
+}
+
+// ListTrashed pages the trashed notes, most recently trashed first.
+func (r *NoteRepository) ListTrashed(ctx context.Context, limit, offset int) ([]NoteListRow, int64, error) {
+	return r.list(ctx, true, limit, offset)
+}
+
+func (r *NoteRepository) list(ctx context.Context, trashed bool, limit, offset int) ([]NoteListRow, int64, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 	if offset < 0 {
 		offset = 0
 	}
+	where := `n.deleted_at IS NULL`
+	order := `n.created_at DESC, n.id DESC`
+	if trashed {
+		where = `n.deleted_at IS NOT NULL`
+		order = `n.deleted_at DESC, n.id DESC`
+	}
 	var total int64
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM notes`).Scan(&total); err != nil {
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM notes n WHERE `+where).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT n.id, n.note_type, n.content, n.created_at, n.updated_at, COALESCE(stat.word_count, 0) AS word_count
+		SELECT n.id, n.note_type, n.content, n.created_at, n.updated_at, n.deleted_at, COALESCE(stat.word_count, 0) AS word_count
 		FROM notes n
 		LEFT JOIN (
 			SELECT note_id, COUNT(1) AS word_count
 			FROM note_words
 			GROUP BY note_id
 		) stat ON stat.note_id = n.id
-		ORDER BY n.created_at DESC, n.id DESC
+		WHERE `+where+`
+		ORDER BY `+order+`
 		LIMIT ? OFFSET ?
 	`, limit, offset)
 	if err != nil {
@@ -133,16 +167,21 @@ func (r *NoteRepository) List(ctx context.Context, limit, offset int) ([]NoteLis
 	ret := make([]NoteListRow, 0, limit)
 	for rows.Next() {
 		item := NoteListRow{}
+		var deletedAt sql.NullTime
 		if err := rows.Scan(
 			&item.Note.ID,
 			&item.Note.NoteType,
 			&item.Note.Content,
 			&item.Note.CreatedAt,
 			&item.Note.UpdatedAt,
+			&deletedAt,
 			&item.WordCount,
 		); err != nil {
 			return nil, 0, err
 		}
+		if deletedAt.Valid {
+			item.Note.DeletedAt = &deletedAt.Time
+		}
 		ret = append(ret, item)
 	}
 	if err := rows.Err(); err != nil {
@@ -151,9 +190,67 @@ func (r *NoteRepository) List(ctx context.Context, limit, offset int) ([]NoteLis
 	return ret, total, nil
 }
 
+// SoftDelete moves a note to the trash, keeping its word links.
+func (r *NoteRepository) SoftDelete(ctx context.Context, noteID int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notes
+		SET deleted_at = ?, updated_at = updated_at
+		WHERE id = ? AND deleted_at IS NULL
+	`, at, noteID)
+	return err
+}
+
+// Restore takes a note out of the trash.
+func (r *NoteRepository) Restore(ctx context.Context, noteID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notes
+		SET deleted_at = NULL, updated_at = updated_at
+		WHERE id = ?
+	`, noteID)
+	return err
+}
+
+// Delete removes a note and its word links for good.
+func (r *NoteRepository) Delete(ctx context.Context, noteID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_words WHERE note_id = ?`, noteID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM notes WHERE id = ?`, noteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnlinkWord removes one word link of a note and touches the note.
+func (r *NoteRepository) UnlinkWord(ctx context.Context, noteID, wordID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_words WHERE note_id = ? AND word_id = ?`, noteID, wordID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE notes SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, noteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (r *NoteRepository) ListWordRelationsByNoteID(ctx context.Context, noteID int64) ([]entity.NoteWordRelation, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, note_id, word_id, created_at
+		SELECT id, note_id, word_id, from_ref, created_at
 		FROM note_words
 		WHERE note_id = ?
 		ORDER BY id ASC
@@ -166,7 +263,7 @@ func (r *NoteRepository) ListWordRelationsByNoteID(ctx context.Context, noteID i
 	ret := make([]entity.NoteWordRelation, 0)
 	for rows.Next() {
 		item := entity.NoteWordRelation{}
-		if err := rows.Scan(&item.ID, &item.NoteID, &item.WordID, &item.CreatedAt); err != nil {
+		if err := rows.Scan(&item.ID, &item.NoteID, &item.WordID, &item.FromRef, &item.CreatedAt); err != nil {
 			return nil, err
 		}
 		ret = append(ret, item)
@@ -187,7 +284,7 @@ func (r *NoteRepository) ListWordRelationsByNoteIDs(ctx context.Context, noteIDs
 		args = append(args, id)
 	}
 	query := `
-		SELECT id, note_id, word_id, created_at
+		SELECT id, note_id, word_id, from_ref, created_at
 		FROM note_words
 		WHERE note_id IN (` + placeholders + `)
 		ORDER BY note_id ASC, id ASC
@@ -201,7 +298,34 @@ func (r *NoteRepository) ListWordRelationsByNoteIDs(ctx context.Context, noteIDs
 	ret := make([]entity.NoteWordRelation, 0)
 	for rows.Next() {
 		item := entity.NoteWordRelation{}
-		if err := rows.Scan(&item.ID, &item.NoteID, &item.WordID, &item.CreatedAt); err != nil {
+		if err := rows.Scan(&item.ID, &item.NoteID, &item.WordID, &item.FromRef, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		ret = append(ret, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// ListByType returns the notes of a type outside the trash, newest first.
+func (r *NoteRepository) ListByType(ctx context.Context, noteType string) ([]entity.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, note_type, content, created_at, updated_at
+		FROM notes
+		WHERE note_type = ? AND deleted_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`, noteType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]entity.Note, 0)
+	for rows.Next() {
+		item := entity.Note{}
+		if err := rows.Scan(&item.ID, &item.NoteType, &item.Content, &item.CreatedAt, &item.UpdatedAt); err != nil {
 			return nil, err
 		}
 		ret = append(ret, item)
@@ -226,7 +350,7 @@ func (r *NoteRepository) ListByWordIDs(ctx context.Context, wordIDs []int64) (ma
 		SELECT nw.word_id, n.id, n.note_type
 		FROM note_words nw
 		JOIN notes n ON n.id = nw.note_id
-		WHERE nw.word_id IN (` + placeholders + `)
+		WHERE nw.word_id IN (` + placeholders + `) AND n.deleted_at IS NULL
 		ORDER BY nw.word_id ASC, n.created_at DESC, n.id DESC
 	`
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -247,3 +371,104 @@ func (r *NoteRepository) ListByWordIDs(ctx context.Context, wordIDs []int64) (ma
 	}
 	return ret, nil
 }
+
+// Note search orders.
+const (
+	NoteSearchRelevance = "relevance"
+	NoteSearchUpdated   = "updated"
+)
+
+// NoteSearchFilter selects notes for NoteRepository.Search. Every term of
+// Terms must occur in the content; NoteType and WordID, when set, keep notes
+// of that type or linked to that word.
+type NoteSearchFilter struct {
+	Terms    []string
+	NoteType string
+	WordID   int64
+	Sort     string
+}
+
+type NoteSearchRow struct {
+	Note  entity.Note
+	Score float64
+}
+
+// Search pages the notes outside the trash matching filter. Every term is
+// matched with LIKE; terms of at least ftMinQueryRunes also give the relevance
+// score through the ngram full-text index, which cannot decide the match on
+// its own (see ftMinQueryRunes).
+func (r *NoteRepository) Search(ctx context.Context, filter NoteSearchFilter, limit, offset int) ([]NoteSearchRow, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	conds := []string{`n.deleted_at IS NULL`}
+	args := make([]any, 0, len(filter.Terms)+2)
+	phrases := make([]string, 0, len(filter.Terms))
+	for _, term := range filter.Terms {
+		conds = append(conds, `n.content LIKE ?`)
+		args = append(args, "%"+escapeLike(term)+"%")
+		if utf8.RuneCountInString(term) >= ftMinQueryRunes {
+			phrases = append(phrases, fullTextPhrase(term))
+		}
+	}
+	score := `0`
+	var scoreArgs []any
+	if len(phrases) > 0 {
+		score = `MATCH(n.content) AGAINST(? IN BOOLEAN MODE)`
+		scoreArgs = []any{strings.Join(phrases, " ")}
+	}
+	if filter.NoteType != "" {
+		conds = append(conds, `n.note_type = ?`)
+		args = append(args, filter.NoteType)
+	}
+	if filter.WordID > 0 {
+		conds = append(conds, `EXISTS (SELECT 1 FROM note_words nw WHERE nw.note_id = n.id AND nw.word_id = ?)`)
+		args = append(args, filter.WordID)
+	}
+	where := strings.Join(conds, " AND ")
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM notes n WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := `n.updated_at DESC, n.id DESC`
+	if filter.Sort == NoteSearchRelevance {
+		order = `score DESC, ` + order
+	}
+	query := `
+		SELECT n.id, n.note_type, n.content, n.created_at, n.updated_at, ` + score + ` AS score
+		FROM notes n
+		WHERE ` + where + `
+		ORDER BY ` + order + `
+		LIMIT ? OFFSET ?`
+	queryArgs := append(append(scoreArgs, args...), limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	ret := make([]NoteSearchRow, 0, limit)
+	for rows.Next() {
+		item := NoteSearchRow{}
+		if err := rows.Scan(
+			&item.Note.ID,
+			&item.Note.NoteType,
+			&item.Note.Content,
+			&item.Note.CreatedAt,
+			&item.Note.UpdatedAt,
+			&item.Score,
+		); err != nil {
+			return nil, 0, err
+		}
+		ret = append(ret, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return ret, total, nil
+}
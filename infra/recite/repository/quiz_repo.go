@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -26,7 +27,9 @@ func NewQuizRepository(db *sql.DB) *QuizRepository {
 	return &QuizRepository{db: db}
 }
 
-func (r *QuizRepository) Create(ctx context.Context, quiz *entity.Quiz, wordIDs []int64) (*entity.Quiz, error) {
+// Create inserts a quiz over wordIDs in order. choices, when not nil, holds
+// the candidate word ids offered for each word.
+func (r *QuizRepository) Create(ctx context.Context, quiz *entity.Quiz, wordIDs []int64, choices [][]int64) (*entity.Quiz, error) {
 	if quiz == nil {
 		return nil, fmt.Errorf("quiz is nil")
 	}
@@ -55,10 +58,18 @@ func (r *QuizRepository) Create(ctx context.Context, quiz *entity.Quiz, wordIDs
 	}
 
 	for i, wordID := range wordIDs {
+		var choicesArg any
+		if i < len(choices) && len(choices[i]) > 0 {
+			choicesJSON, err := json.Marshal(choices[i])
+			if err != nil {
+				return nil, err
+			}
+			choicesArg = string(choicesJSON)
+		}
 		if _, err := tx.ExecContext(ctx, `
-			INSERT INTO quiz_words(quiz_id, word_id, order_no, status)
-			VALUES(?, ?, ?, '未测试')
-		`, quizID, wordID, i+1); err != nil {
+			INSERT INTO quiz_words(quiz_id, word_id, order_no, status, choices_json)
+			VALUES(?, ?, ?, '未测试', ?)
+		`, quizID, wordID, i+1, choicesArg); err != nil {
 			return nil, err
 		}
 	}
@@ -162,7 +173,7 @@ func (r *QuizRepository) HasRunning(ctx context.Context) (bool, error) {
 
 func (r *QuizRepository) ListWords(ctx context.Context, quizID int64) ([]entity.QuizWord, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, quiz_id, word_id, order_no, status, input_answer, result, created_at, updated_at
+		SELECT id, quiz_id, word_id, order_no, status, input_answer, result, choices_json, created_at, updated_at
 		FROM quiz_words
 		WHERE quiz_id = ?
 		ORDER BY order_no ASC
@@ -174,18 +185,8 @@ func (r *QuizRepository) ListWords(ctx context.Context, quizID int64) ([]entity.
 
 	ret := make([]entity.QuizWord, 0)
 	for rows.Next() {
-		item := entity.QuizWord{}
-		if err := rows.Scan(
-			&item.ID,
-			&item.QuizID,
-			&item.WordID,
-			&item.OrderNo,
-			&item.Status,
-			&item.InputAnswer,
-			&item.Result,
-			&item.CreatedAt,
-			&item.UpdatedAt,
-		); err != nil {
+		item, err := scanQuizWord(rows)
+		if err != nil {
 			return nil, err
 		}
 		ret = append(ret, item)
@@ -196,6 +197,65 @@ func (r *QuizRepository) ListWords(ctx context.Context, quizID int64) ([]entity.
 	return ret, nil
 }
 
+func (r *QuizRepository) GetWordByOrder(ctx context.Context, quizID int64, orderNo int) (*entity.QuizWord, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT id, quiz_id, word_id, order_no, status, input_answer, result, choices_json, created_at, updated_at
+		FROM quiz_words
+		WHERE quiz_id = ? AND order_no = ?
+		LIMIT 1
+	`, quizID, orderNo)
+	item, err := scanQuizWord(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListRecentResultsByWord returns the latest tested results of a word in quizzes
+// of the given source kind, newest first, only counting answers made after since.
+func (r *QuizRepository) ListRecentResultsByWord(
+	ctx context.Context,
+	wordID int64,
+	sourceKind string,
+	since time.Time,
+	limit int,
+) ([]string, error) {
+	if limit <= 0 {
+		return []string{}, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT qw.result
+		FROM quiz_words qw
+		JOIN quizzes q ON q.id = qw.quiz_id
+		WHERE qw.word_id = ?
+		  AND q.source_kind = ?
+		  AND qw.status = '已测试'
+		  AND qw.updated_at >= ?
+		ORDER BY qw.updated_at DESC, qw.id DESC
+		LIMIT ?
+	`, wordID, sourceKind, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]string, 0, limit)
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		ret = append(ret, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 func (r *QuizRepository) UpdateWordResult(
 	ctx context.Context,
 	quizID int64,
@@ -281,3 +341,28 @@ func scanQuizWithStats(s quizScanner, statsDest ...any) (entity.Quiz, error) {
 	}
 	return item, nil
 }
+
+func scanQuizWord(s quizScanner) (entity.QuizWord, error) {
+	item := entity.QuizWord{}
+	var choicesJSON sql.NullString
+	if err := s.Scan(
+		&item.ID,
+		&item.QuizID,
+		&item.WordID,
+		&item.OrderNo,
+		&item.Status,
+		&item.InputAnswer,
+		&item.Result,
+		&choicesJSON,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	); err != nil {
+		return entity.QuizWord{}, err
+	}
+	if choicesJSON.Valid && choicesJSON.String != "" {
+		if err := json.Unmarshal([]byte(choicesJSON.String), &item.ChoiceWordIDs); err != nil {
+			return entity.QuizWord{}, err
+		}
+	}
+	return item, nil
+}
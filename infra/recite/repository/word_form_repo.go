@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// WordFormRepository stores the inflected surface forms seen for a lemma word.
+type WordFormRepository struct {
+	db *sql.DB
+}
+
+func NewWordFormRepository(db *sql.DB) *WordFormRepository {
+	return &WordFormRepository{db: db}
+}
+
+func (r *WordFormRepository) Add(ctx context.Context, wordID int64, form string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO word_forms(word_id, form)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE id = id
+	`, wordID, form)
+	return err
+}
+
+// GetWordIDsByForms returns the latest word recorded for each form, keyed by
+// form.
+func (r *WordFormRepository) GetWordIDsByForms(ctx context.Context, forms []string) (map[string]int64, error) {
+	ret := make(map[string]int64)
+	if len(forms) == 0 {
+		return ret, nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(forms)), ",")
+	args := make([]any, 0, len(forms))
+	for _, form := range forms {
+		args = append(args, form)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT form, word_id
+		FROM word_forms
+		WHERE form IN (`+placeholders+`)
+		ORDER BY id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var form string
+		var wordID int64
+		if err := rows.Scan(&form, &wordID); err != nil {
+			return nil, err
+		}
+		// later rows win, leaving the latest word of each form
+		ret[form] = wordID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (r *WordFormRepository) ListByWordIDs(ctx context.Context, wordIDs []int64) (map[int64][]string, error) {
+	ret := make(map[int64][]string)
+	if len(wordIDs) == 0 {
+		return ret, nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(wordIDs)), ",")
+	args := make([]any, 0, len(wordIDs))
+	for _, id := range wordIDs {
+		args = append(args, id)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT word_id, form
+		FROM word_forms
+		WHERE word_id IN (`+placeholders+`)
+		ORDER BY word_id ASC, id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var wordID int64
+		var form string
+		if err := rows.Scan(&wordID, &form); err != nil {
+			return nil, err
+		}
+		ret[wordID] = append(ret[wordID], form)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
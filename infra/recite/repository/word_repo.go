@@ -4,13 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/wutianfang/moss/infra/recite/entity"
 	"github.com/wutianfang/moss/util"
 )
 
+const wordColumns = `id, word, ph_en, ph_am, mean_tag, parts_json, sentences_json,
+		mnemonic, custom_parts_json, custom_mode, origin_json, edited_at, fetched_at, synthetic_audio, no_audio, created_at, updated_at`
+
 type WordRepository struct {
 	db *sql.DB
 }
@@ -22,7 +28,7 @@ func NewWordRepository(db *sql.DB) *WordRepository {
 func (r *WordRepository) GetByWord(ctx context.Context, word string) (*entity.Word, error) {
 	start := time.Now()
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, word, ph_en, ph_am, mean_tag, parts_json, sentences_json, created_at, updated_at
+		SELECT `+wordColumns+`
 		FROM words WHERE word = ? LIMIT 1`, word)
 
 	raw, err := scanWordRaw(row)
@@ -45,27 +51,61 @@ func (r *WordRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*
 	if len(ids) == 0 {
 		return ret, nil
 	}
-
-	query := `SELECT id, word, ph_en, ph_am, mean_tag, parts_json, sentences_json, created_at, updated_at FROM words WHERE id IN (?`
 	args := make([]any, 0, len(ids))
-	args = append(args, ids[0])
-	for i := 1; i < len(ids); i++ {
-		query += ",?"
-		args = append(args, ids[i])
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	items, err := r.listIn(ctx, "get_by_ids", "id", args)
+	if err != nil {
+		return nil, err
 	}
-	query += `)`
-	util.InfofWithRequest(ctx, "repo.word.get_by_ids.sql", "query=%s args=%v", query, args)
+	for _, item := range items {
+		ret[item.ID] = item
+	}
+	return ret, nil
+}
+
+// GetByWords returns the cached words among words, keyed by word.
+func (r *WordRepository) GetByWords(ctx context.Context, words []string) (map[string]*entity.Word, error) {
+	ret := make(map[string]*entity.Word, len(words))
+	if len(words) == 0 {
+		return ret, nil
+	}
+	args := make([]any, 0, len(words))
+	for _, word := range words {
+		args = append(args, word)
+	}
+	items, err := r.listIn(ctx, "get_by_words", "word", args)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		ret[item.Word] = item
+	}
+	return ret, nil
+}
+
+// listIn loads the words whose column is one of args; op names the caller in
+// the logs.
+func (r *WordRepository) listIn(ctx context.Context, op, column string, args []any) ([]*entity.Word, error) {
+	query := `SELECT ` + wordColumns + ` FROM words WHERE ` + column + ` IN (?` + strings.Repeat(",?", len(args)-1) + `)`
+	return r.list(ctx, op, query, args)
+}
+
+// list runs a query selecting wordColumns and parses the rows.
+func (r *WordRepository) list(ctx context.Context, op, query string, args []any) ([]*entity.Word, error) {
+	util.DebugfWithRequest(ctx, "repo.word."+op+".sql", "query=%s args=%v", query, args)
 
 	queryStart := time.Now()
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	queryMS := time.Since(queryStart).Milliseconds()
 	if err != nil {
-		util.ErrorfWithRequest(ctx, "repo.word.get_by_ids.query_failed", "query_ms=%d err=%v", queryMS, err)
+		util.ErrorfWithRequest(ctx, "repo.word."+op+".query_failed", "query_ms=%d err=%v", queryMS, err)
 		return nil, err
 	}
-	util.InfofWithRequest(ctx, "repo.word.get_by_ids.query_done", "query_ms=%d", queryMS)
+	util.DebugfWithRequest(ctx, "repo.word."+op+".query_done", "query_ms=%d", queryMS)
 	readStart := time.Now()
-	rawRows := make([]*wordRawRow, 0, len(ids))
+	rawRows := make([]*wordRawRow, 0, len(args))
 	for rows.Next() {
 		raw, err := scanWordRaw(rows)
 		if err != nil {
@@ -81,17 +121,18 @@ func (r *WordRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]*
 	if err := rows.Close(); err != nil {
 		return nil, err
 	}
-	util.InfofWithRequest(ctx, "repo.word.get_by_ids.rows_loaded", "row_count=%d read_ms=%d", len(rawRows), time.Since(readStart).Milliseconds())
+	util.DebugfWithRequest(ctx, "repo.word."+op+".rows_loaded", "row_count=%d read_ms=%d", len(rawRows), time.Since(readStart).Milliseconds())
 
 	parseStart := time.Now()
+	ret := make([]*entity.Word, 0, len(rawRows))
 	for _, raw := range rawRows {
 		item, err := scanWord(ctx, raw)
 		if err != nil {
 			return nil, err
 		}
-		ret[item.ID] = item
+		ret = append(ret, item)
 	}
-	util.InfofWithRequest(ctx, "repo.word.get_by_ids.parse_done", "row_count=%d parse_ms=%d", len(rawRows), time.Since(parseStart).Milliseconds())
+	util.DebugfWithRequest(ctx, "repo.word."+op+".parse_done", "row_count=%d parse_ms=%d", len(rawRows), time.Since(parseStart).Milliseconds())
 
 	return ret, nil
 }
@@ -107,9 +148,10 @@ func (r *WordRepository) Create(ctx context.Context, word *entity.Word) error {
 	}
 
 	res, err := r.db.ExecContext(ctx, `
-		INSERT INTO words (word, ph_en, ph_am, mean_tag, parts_json, sentences_json)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, word.Word, word.PhEn, word.PhAm, word.MeanTag, string(partsJSON), string(sentencesJSON))
+		INSERT INTO words (word, ph_en, ph_am, mean_tag, parts_json, sentences_json, no_audio, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())
+	`, word.Word, word.PhEn, word.PhAm, word.MeanTag, string(partsJSON), string(sentencesJSON),
+		strings.Join(word.NoAudioAccents, ","))
 	if err != nil {
 		return err
 	}
@@ -121,20 +163,249 @@ func (r *WordRepository) Create(ctx context.Context, word *entity.Word) error {
 	return nil
 }
 
+func (r *WordRepository) GetByID(ctx context.Context, id int64) (*entity.Word, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+wordColumns+`
+		FROM words WHERE id = ? LIMIT 1`, id)
+
+	raw, err := scanWordRaw(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return scanWord(ctx, raw)
+}
+
+// Update writes back every editable column of a word, including the user
+// edits and the fetched origin kept for revert.
+func (r *WordRepository) Update(ctx context.Context, word *entity.Word) error {
+	partsJSON, err := json.Marshal(word.Parts)
+	if err != nil {
+		return fmt.Errorf("marshal parts failed: %w", err)
+	}
+	sentencesJSON, err := json.Marshal(word.SentenceGroups)
+	if err != nil {
+		return fmt.Errorf("marshal sentences failed: %w", err)
+	}
+	var customPartsArg any
+	if len(word.CustomParts) > 0 {
+		customPartsJSON, err := json.Marshal(word.CustomParts)
+		if err != nil {
+			return fmt.Errorf("marshal custom parts failed: %w", err)
+		}
+		customPartsArg = string(customPartsJSON)
+	}
+	var originArg any
+	if word.Origin != nil {
+		originJSON, err := json.Marshal(word.Origin)
+		if err != nil {
+			return fmt.Errorf("marshal origin failed: %w", err)
+		}
+		originArg = string(originJSON)
+	}
+	var editedAtArg any
+	if word.EditedAt != nil {
+		editedAtArg = *word.EditedAt
+	}
+	var fetchedAtArg any
+	if word.FetchedAt != nil {
+		fetchedAtArg = *word.FetchedAt
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE words
+		SET ph_en = ?, ph_am = ?, mean_tag = ?, parts_json = ?, sentences_json = ?,
+			mnemonic = ?, custom_parts_json = ?, custom_mode = ?, origin_json = ?, edited_at = ?, fetched_at = ?,
+			synthetic_audio = ?, no_audio = ?
+		WHERE id = ?
+	`, word.PhEn, word.PhAm, word.MeanTag, string(partsJSON), string(sentencesJSON),
+		word.Mnemonic, customPartsArg, word.CustomMode, originArg, editedAtArg, fetchedAtArg,
+		strings.Join(word.SyntheticAccents, ","), strings.Join(word.NoAudioAccents, ","), word.ID)
+	return err
+}
+
+// SetSentenceLocalAudio points sentences at their copy in the local audio
+// library. localURLs maps a dictionary TTS url to the local url; sentences
+// already pointing somewhere are left alone. The sentences are read and
+// written in one locked transaction, so edits made since the caller loaded
+// the word are kept.
+func (r *WordRepository) SetSentenceLocalAudio(ctx context.Context, id int64, localURLs map[string]string) error {
+	if len(localURLs) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var raw string
+	err = tx.QueryRowContext(ctx, `SELECT sentences_json FROM words WHERE id = ? FOR UPDATE`, id).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	groups := make([]entity.WordSentenceGroup, 0)
+	if err := json.Unmarshal([]byte(raw), &groups); err != nil {
+		return fmt.Errorf("unmarshal sentences failed: %w", err)
+	}
+	if !setSentenceLocalAudio(groups, localURLs) {
+		return nil
+	}
+	sentencesJSON, err := json.Marshal(groups)
+	if err != nil {
+		return fmt.Errorf("marshal sentences failed: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE words SET sentences_json = ? WHERE id = ?`, string(sentencesJSON), id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setSentenceLocalAudio applies localURLs to the sentences without a local
+// url and reports whether any changed.
+func setSentenceLocalAudio(groups []entity.WordSentenceGroup, localURLs map[string]string) bool {
+	changed := false
+	for gi := range groups {
+		sentences := groups[gi].Sentences
+		for si := range sentences {
+			sentence := &sentences[si]
+			if local, ok := localURLs[sentence.TTSURL]; ok && sentence.TTSURL != "" && sentence.LocalTTSURL == "" {
+				sentence.LocalTTSURL = local
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// AudioWord is a cached word as the audio check sees it.
+type AudioWord struct {
+	Word           string
+	NoAudioAccents []string
+	SentenceGroups []entity.WordSentenceGroup
+}
+
+// ListAudioWords returns every cached word in id order.
+func (r *WordRepository) ListAudioWords(ctx context.Context) ([]AudioWord, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT word, no_audio, sentences_json FROM words ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ret := make([]AudioWord, 0)
+	for rows.Next() {
+		var word, noAudio, sentencesJSON string
+		if err := rows.Scan(&word, &noAudio, &sentencesJSON); err != nil {
+			return nil, err
+		}
+		item := AudioWord{Word: word, NoAudioAccents: splitAccents(noAudio)}
+		if sentencesJSON != "" {
+			if err := json.Unmarshal([]byte(sentencesJSON), &item.SentenceGroups); err != nil {
+				return nil, fmt.Errorf("unmarshal sentences of %s failed: %w", word, err)
+			}
+		}
+		ret = append(ret, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// WordRefreshFilter selects cached words whose dictionary content looks stale.
+// Conditions are OR-ed; FetchedBefore compares with the last fetch time.
+type WordRefreshFilter struct {
+	MissingMeanTag bool
+	NoSentences    bool
+	FetchedBefore  *time.Time
+}
+
+// ListForRefresh pages words matching the filter in id order, starting after afterID.
+func (r *WordRepository) ListForRefresh(ctx context.Context, filter WordRefreshFilter, afterID int64, limit int) ([]*entity.Word, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	conds := make([]string, 0, 3)
+	args := []any{afterID}
+	if filter.MissingMeanTag {
+		conds = append(conds, `mean_tag = ''`)
+	}
+	if filter.NoSentences {
+		conds = append(conds, `sentences_json IN ('', '[]', 'null')`)
+	}
+	if filter.FetchedBefore != nil {
+		conds = append(conds, `COALESCE(fetched_at, created_at) < ?`)
+		args = append(args, *filter.FetchedBefore)
+	}
+	query := `SELECT ` + wordColumns + ` FROM words WHERE id > ?`
+	if len(conds) > 0 {
+		query += ` AND (` + strings.Join(conds, " OR ") + `)`
+	}
+	query += ` ORDER BY id ASC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	rawRows := make([]*wordRawRow, 0, limit)
+	for rows.Next() {
+		raw, err := scanWordRaw(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		rawRows = append(rawRows, raw)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*entity.Word, 0, len(rawRows))
+	for _, raw := range rawRows {
+		item, err := scanWord(ctx, raw)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
 type scanner interface {
 	Scan(dest ...any) error
 }
 
 type wordRawRow struct {
-	ID            int64
-	Word          string
-	PhEn          string
-	PhAm          string
-	MeanTag       string
-	PartsJSON     string
-	SentencesJSON string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID              int64
+	Word            string
+	PhEn            string
+	PhAm            string
+	MeanTag         string
+	PartsJSON       string
+	SentencesJSON   string
+	Mnemonic        string
+	CustomPartsJSON sql.NullString
+	CustomMode      string
+	OriginJSON      sql.NullString
+	EditedAt        sql.NullTime
+	FetchedAt       sql.NullTime
+	SyntheticAudio  string
+	NoAudio         string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 func scanWordRaw(s scanner) (*wordRawRow, error) {
@@ -147,6 +418,14 @@ func scanWordRaw(s scanner) (*wordRawRow, error) {
 		&raw.MeanTag,
 		&raw.PartsJSON,
 		&raw.SentencesJSON,
+		&raw.Mnemonic,
+		&raw.CustomPartsJSON,
+		&raw.CustomMode,
+		&raw.OriginJSON,
+		&raw.EditedAt,
+		&raw.FetchedAt,
+		&raw.SyntheticAudio,
+		&raw.NoAudio,
 		&raw.CreatedAt,
 		&raw.UpdatedAt,
 	); err != nil {
@@ -167,6 +446,8 @@ func scanWord(ctx context.Context, raw *wordRawRow) (*entity.Word, error) {
 	item.PhEn = raw.PhEn
 	item.PhAm = raw.PhAm
 	item.MeanTag = raw.MeanTag
+	item.Mnemonic = raw.Mnemonic
+	item.CustomMode = raw.CustomMode
 	item.CreatedAt = raw.CreatedAt
 	item.UpdatedAt = raw.UpdatedAt
 
@@ -182,11 +463,148 @@ func scanWord(ctx context.Context, raw *wordRawRow) (*entity.Word, error) {
 			return nil, fmt.Errorf("unmarshal sentence groups failed: %w", err)
 		}
 	}
+	if raw.CustomPartsJSON.Valid && raw.CustomPartsJSON.String != "" {
+		if err := json.Unmarshal([]byte(raw.CustomPartsJSON.String), &item.CustomParts); err != nil {
+			util.ErrorfWithRequest(ctx, "repo.word.scan_word.custom_parts_unmarshal_failed", "self_ms=%d word=%s err=%v", time.Since(start).Milliseconds(), item.Word, err)
+			return nil, fmt.Errorf("unmarshal custom parts failed: %w", err)
+		}
+	}
+	if raw.OriginJSON.Valid && raw.OriginJSON.String != "" {
+		origin := &entity.WordOrigin{}
+		if err := json.Unmarshal([]byte(raw.OriginJSON.String), origin); err != nil {
+			util.ErrorfWithRequest(ctx, "repo.word.scan_word.origin_unmarshal_failed", "self_ms=%d word=%s err=%v", time.Since(start).Milliseconds(), item.Word, err)
+			return nil, fmt.Errorf("unmarshal origin failed: %w", err)
+		}
+		item.Origin = origin
+	}
+	if raw.EditedAt.Valid {
+		t := raw.EditedAt.Time
+		item.EditedAt = &t
+	}
+	if raw.FetchedAt.Valid {
+		t := raw.FetchedAt.Time
+		item.FetchedAt = &t
+	}
+	item.SyntheticAccents = splitAccents(raw.SyntheticAudio)
+	item.NoAudioAccents = splitAccents(raw.NoAudio)
 	if item.Parts == nil {
 		item.Parts = make([]entity.WordPart, 0)
 	}
+	if item.CustomParts == nil {
+		item.CustomParts = make([]entity.WordPart, 0)
+	}
 	if item.SentenceGroups == nil {
 		item.SentenceGroups = make([]entity.WordSentenceGroup, 0)
 	}
 	return item, nil
 }
+
+// Word search modes.
+const (
+	WordSearchPrefix    = "prefix"
+	WordSearchSubstring = "substring"
+	WordSearchMeaning   = "meaning"
+)
+
+// ftMinQueryRunes is the ngram token size of the full-text indexes; shorter
+// queries cannot be scored by them.
+//
+// The indexes only rank matches, LIKE decides them: the ngram parser drops
+// every token containing an InnoDB stopword such as "a" or "i", so MATCH
+// misses most English text ("abandon" keeps only "nd" and "do").
+const ftMinQueryRunes = 2
+
+// WordSearchFilter selects cached words. Mode "prefix" and "substring" match
+// the word, "meaning" matches mean_tag and the fetched parts. InUnit,
+// InForgotten and HasNotes keep only words in some unit, in the forgotten list
+// or linked to a note outside the trash.
+type WordSearchFilter struct {
+	Query       string
+	Mode        string
+	InUnit      bool
+	InForgotten bool
+	HasNotes    bool
+	Limit       int
+}
+
+// Search returns up to filter.Limit words matching the filter. Prefix matches
+// come in word order, substring matches by match position then length, meaning
+// matches by full-text relevance then length.
+func (r *WordRepository) Search(ctx context.Context, filter WordSearchFilter) ([]*entity.Word, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	conds := make([]string, 0, 5)
+	args := make([]any, 0, 6)
+	var order string
+	var orderArgs []any
+	pattern := "%" + escapeLike(filter.Query) + "%"
+	useFullText := utf8.RuneCountInString(filter.Query) >= ftMinQueryRunes
+	switch filter.Mode {
+	case WordSearchPrefix:
+		conds = append(conds, `w.word LIKE ?`)
+		args = append(args, escapeLike(filter.Query)+"%")
+		order = `w.word`
+	case WordSearchSubstring:
+		conds = append(conds, `w.word LIKE ?`)
+		args = append(args, pattern)
+		order = `LOCATE(?, w.word), CHAR_LENGTH(w.word), w.word`
+		orderArgs = []any{filter.Query}
+	case WordSearchMeaning:
+		conds = append(conds, `(w.mean_tag LIKE ? OR w.parts_json LIKE ?)`)
+		args = append(args, pattern, pattern)
+		order = `CHAR_LENGTH(w.word), w.word`
+		if useFullText {
+			order = `MATCH(w.mean_tag, w.parts_json) AGAINST(? IN BOOLEAN MODE) DESC, ` + order
+			orderArgs = []any{fullTextPhrase(filter.Query)}
+		}
+	default:
+		return nil, fmt.Errorf("unknown word search mode %q", filter.Mode)
+	}
+	if filter.InUnit {
+		conds = append(conds, `EXISTS (SELECT 1 FROM recite_unit_words uw WHERE uw.word_id = w.id)`)
+	}
+	if filter.InForgotten {
+		conds = append(conds, `EXISTS (SELECT 1 FROM forgotten_words fw WHERE fw.word = w.word AND fw.remembered = 0)`)
+	}
+	if filter.HasNotes {
+		conds = append(conds, `EXISTS (SELECT 1 FROM note_words nw JOIN notes n ON n.id = nw.note_id WHERE nw.word_id = w.id AND n.deleted_at IS NULL)`)
+	}
+
+	query := `SELECT ` + prefixColumns("w", wordColumns) + ` FROM words w WHERE ` + strings.Join(conds, " AND ") +
+		` ORDER BY ` + order + ` LIMIT ?`
+	args = append(args, orderArgs...)
+	args = append(args, filter.Limit)
+	return r.list(ctx, "search", query, args)
+}
+
+// splitAccents parses a comma separated accent column, nil when empty.
+func splitAccents(raw string) []string {
+	var ret []string
+	for _, accent := range strings.Split(raw, ",") {
+		if accent = strings.TrimSpace(accent); accent != "" {
+			ret = append(ret, accent)
+		}
+	}
+	return ret
+}
+
+// escapeLike escapes the LIKE wildcards of s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// fullTextPhrase quotes s as a boolean mode phrase; with the ngram parser a
+// phrase matches s as a substring.
+func fullTextPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, ` `) + `"`
+}
+
+// prefixColumns qualifies a comma separated column list with a table alias.
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
+}
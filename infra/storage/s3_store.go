@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options configures an S3 compatible bucket (AWS S3, MinIO, ...).
+type S3Options struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	// Prefix is prepended to every key, e.g. "word_mp3".
+	Prefix string
+	UseSSL bool
+	// PathStyle forces path style requests, needed by most self hosted servers.
+	PathStyle bool
+}
+
+// S3Store keeps blobs as objects of one bucket.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	lookup := minio.BucketLookupAuto
+	if opts.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure:       opts.UseSSL,
+		Region:       opts.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{
+		client: client,
+		bucket: opts.Bucket,
+		prefix: strings.Trim(opts.Prefix, "/"),
+	}, nil
+}
+
+func (s *S3Store) Name() string {
+	return "s3"
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) error {
+	object, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, object, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType(key),
+	})
+	return err
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadSeekCloser, *Info, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := s.client.GetObject(ctx, s.bucket, object, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s3Error(err)
+	}
+	stat, err := reader.Stat()
+	if err != nil {
+		_ = reader.Close()
+		return nil, nil, s3Error(err)
+	}
+	return reader, &Info{Size: stat.Size, ModTime: stat.LastModified}, nil
+}
+
+// OpenRange fetches the range with a single ranged GET.
+func (s *S3Store) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+	reader, _, _, err := minio.Core{Client: s.client}.GetObject(ctx, s.bucket, object, opts)
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return reader, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (*Info, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := s.client.StatObject(ctx, s.bucket, object, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	return &Info{Size: stat.Size, ModTime: stat.LastModified}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	object, err := s.object(key)
+	if err != nil {
+		return err
+	}
+	err = s.client.RemoveObject(ctx, s.bucket, object, minio.RemoveObjectOptions{})
+	if errors.Is(s3Error(err), ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *S3Store) Walk(ctx context.Context, prefix string, fn func(key string) error) error {
+	listPrefix := strings.Trim(prefix, "/")
+	if s.prefix != "" {
+		listPrefix = path.Join(s.prefix, listPrefix)
+	}
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for item := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: listPrefix, Recursive: true}) {
+		if item.Err != nil {
+			return item.Err
+		}
+		key := item.Key
+		if s.prefix != "" {
+			key = strings.TrimPrefix(key, s.prefix+"/")
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	object, err := s.object(key)
+	if err != nil {
+		return "", err
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, object, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (s *S3Store) object(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if s.prefix == "" {
+		return key, nil
+	}
+	return s.prefix + "/" + key, nil
+}
+
+func s3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	resp := minio.ToErrorResponse(err)
+	if resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
+
+func contentType(key string) string {
+	if strings.HasSuffix(key, ".mp3") {
+		return "audio/mpeg"
+	}
+	return "application/octet-stream"
+}
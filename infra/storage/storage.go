@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a key has no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the store root.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Info describes a stored blob.
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps blobs addressed by slash separated keys such as "en/ab/about.mp3".
+type Store interface {
+	// Name identifies the backend in logs.
+	Name() string
+	Put(ctx context.Context, key string, data []byte) error
+	// Open returns the blob content; the caller closes it.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, *Info, error)
+	Stat(ctx context.Context, key string) (*Info, error)
+	// Delete removes a blob. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Walk calls fn for every key under prefix.
+	Walk(ctx context.Context, prefix string, fn func(key string) error) error
+}
+
+// Presigner is implemented by stores that can hand out temporary direct URLs.
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// RangeReader is implemented by stores that can fetch part of a blob without
+// transferring the rest.
+type RangeReader interface {
+	// OpenRange returns length bytes of the blob from offset, fewer at its
+	// end; the caller closes it.
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// ReadAll returns the whole content of a blob.
+func ReadAll(ctx context.Context, store Store, key string) ([]byte, error) {
+	reader, _, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// ReadRange returns length bytes of a blob from offset, fewer at its end.
+func ReadRange(ctx context.Context, store Store, key string, offset, length int64) ([]byte, error) {
+	if ranged, ok := store.(RangeReader); ok {
+		reader, err := ranged.OpenRange(ctx, key, offset, length)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(io.LimitReader(reader, length))
+	}
+	reader, _, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(reader, length))
+}
+
+// CleanKey validates a key and strips leading slashes.
+func CleanKey(key string) (string, error) {
+	key = strings.TrimLeft(key, "/")
+	if key == "" || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
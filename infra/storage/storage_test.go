@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	cases := []struct {
+		key  string
+		want string
+		err  bool
+	}{
+		{key: "en/ab/about.mp3", want: "en/ab/about.mp3"},
+		{key: "/en/ab/about.mp3", want: "en/ab/about.mp3"},
+		{key: "//sentence/12/1201.mp3", want: "sentence/12/1201.mp3"},
+		{key: "", err: true},
+		{key: "/", err: true},
+		{key: "en//about.mp3", err: true},
+		{key: "en/ab/", err: true},
+		{key: "en/../../etc/passwd", err: true},
+		{key: "./en/about.mp3", err: true},
+		{key: `en\ab\about.mp3`, err: true},
+	}
+	for _, tc := range cases {
+		got, err := CleanKey(tc.key)
+		if tc.err {
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("CleanKey(%q) = %q, %v, want ErrInvalidKey", tc.key, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("CleanKey(%q) = %q, %v, want %q", tc.key, got, err, tc.want)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore(t.TempDir()))
+}
+
+// TestLocalStoreConcurrentPut writes one key from many goroutines: the file
+// must always hold one whole payload and no temp file may stay behind.
+func TestLocalStoreConcurrentPut(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root)
+	ctx := context.Background()
+	payloads := make([][]byte, 8)
+	for i := range payloads {
+		payloads[i] = bytes.Repeat([]byte{byte('a' + i)}, 64<<10)
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for _, data := range payloads {
+			wg.Add(1)
+			go func(data []byte) {
+				defer wg.Done()
+				if err := store.Put(ctx, "en/ab/about.mp3", data); err != nil {
+					t.Errorf("Put: %v", err)
+				}
+			}(data)
+		}
+	}
+	wg.Wait()
+
+	got, err := os.ReadFile(filepath.Join(root, "en", "ab", "about.mp3"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	whole := false
+	for _, data := range payloads {
+		whole = whole || bytes.Equal(got, data)
+	}
+	if !whole {
+		t.Fatalf("file holds %d bytes mixed from several puts", len(got))
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "en", "ab"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("dir holds %d files, want only about.mp3", len(entries))
+	}
+	if info, _ := entries[0].Info(); info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
+
+// TestLocalStoreWalkSkipsTemp leaves a temp file like a crashed Put would;
+// Walk must not report it as a key.
+func TestLocalStoreWalkSkipsTemp(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalStore(root)
+	ctx := context.Background()
+	if err := store.Put(ctx, "en/ab/about.mp3", []byte("about")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "en", "ab", ".about.mp3.123"+localTempSuffix), []byte("ab"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	if err := store.Walk(ctx, "en", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "en/ab/about.mp3" {
+		t.Fatalf("Walk = %v, want only the stored key", keys)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake, server := newFakeS3(t, "moss")
+	store, err := NewS3Store(S3Options{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "moss",
+		Region:    "us-east-1",
+		Prefix:    "/word_mp3/",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	testStore(t, store)
+
+	// keys live below the prefix
+	if err := store.Put(context.Background(), "en/ab/about.mp3", []byte("about")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	fake.mu.Lock()
+	_, ok := fake.objects["word_mp3/en/ab/about.mp3"]
+	fake.mu.Unlock()
+	if !ok {
+		t.Fatalf("object not stored below the prefix")
+	}
+
+	// a ranged read asks for the range only
+	data, err := ReadRange(context.Background(), store, "en/ab/about.mp3", 1, 3)
+	if err != nil || string(data) != "bou" {
+		t.Fatalf("ReadRange = %q, %v, want \"bou\"", data, err)
+	}
+	reads := fake.rangeReads()
+	if last := reads[len(reads)-1]; last != "bytes=1-3" {
+		t.Fatalf("last GET Range = %q, want bytes=1-3", last)
+	}
+}
+
+// testStore runs the Store contract against store.
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := store.Stat(ctx, "en/ab/abandon.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat(missing) err = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Open(ctx, "en/ab/abandon.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(missing) err = %v, want ErrNotFound", err)
+	}
+	if err := store.Put(ctx, "../escape.mp3", []byte("x")); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Put(../escape.mp3) err = %v, want ErrInvalidKey", err)
+	}
+
+	blobs := map[string]string{
+		"en/ab/abandon.mp3":    "abandon en",
+		"am/ab/abandon.mp3":    "abandon am",
+		"en/de/desert.mp3":     "desert en",
+		"sentence/12/1201.mp3": "sentence",
+	}
+	for key, content := range blobs {
+		if err := store.Put(ctx, key, []byte(content)); err != nil {
+			t.Fatalf("Put(%s): %v", key, err)
+		}
+	}
+
+	info, err := store.Stat(ctx, "en/ab/abandon.mp3")
+	if err != nil || info.Size != int64(len("abandon en")) || info.ModTime.IsZero() {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+	reader, info, err := store.Open(ctx, "/en/ab/abandon.mp3")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	_ = reader.Close()
+	if err != nil || string(data) != "abandon en" || info.Size != int64(len(data)) {
+		t.Fatalf("Open read %q (%+v), %v", data, info, err)
+	}
+	if data, err := ReadRange(ctx, store, "en/ab/abandon.mp3", 8, 10); err != nil || string(data) != "en" {
+		t.Fatalf("ReadRange past the end = %q, %v, want \"en\"", data, err)
+	}
+
+	var walked []string
+	if err := store.Walk(ctx, "en", func(key string) error {
+		walked = append(walked, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	sort.Strings(walked)
+	if strings.Join(walked, ",") != "en/ab/abandon.mp3,en/de/desert.mp3" {
+		t.Fatalf("Walk(en) = %v", walked)
+	}
+	if err := store.Walk(ctx, "fr", func(key string) error {
+		t.Errorf("Walk(fr) visited %s", key)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk(missing prefix): %v", err)
+	}
+
+	if err := store.Delete(ctx, "en/ab/abandon.mp3"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "en/ab/abandon.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Stat(deleted) err = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "en/ab/abandon.mp3"); err != nil {
+		t.Fatalf("Delete(missing): %v", err)
+	}
+}
@@ -1,41 +1,122 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/wutianfang/moss/conf"
 	"github.com/wutianfang/moss/infra/db"
+	"github.com/wutianfang/moss/infra/metrics"
 	"github.com/wutianfang/moss/util"
 )
 
 func main() {
-	cfg, err := conf.Load("conf/config.yaml")
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	fs := flag.NewFlagSet("moss", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, commandUsage) }
+	configFlags := addConfigFlags(fs)
+	_ = fs.Parse(os.Args[1:])
+	cfg, err := configFlags.load()
 	if err != nil {
 		log.Fatalf("load config failed: %v", err)
 	}
 
-	if err := util.InitLogger(cfg.Log.Dir); err != nil {
+	logFile, err := util.InitLogger(util.LogOptions{
+		Dir:        cfg.Log.Dir,
+		Level:      cfg.Log.Level,
+		Format:     cfg.Log.Format,
+		Stdout:     cfg.Log.Stdout,
+		Rotate:     cfg.Log.Rotate,
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+		MaxAgeDays: cfg.Log.MaxAgeDays,
+		Compress:   cfg.Log.Compress,
+		Levels:     cfg.Log.Levels,
+	})
+	if err != nil {
 		log.Fatalf("init logger failed: %v", err)
 	}
-	util.SetRequestLogEnabled(cfg.Log.EnableRequestLog)
+	defer logFile.Close()
+
+	if err := serve(cfg); err != nil {
+		util.Errorf("%v", err)
+		logFile.Close()
+		os.Exit(1)
+	}
+}
 
-	database, err := db.InitMySQL(&cfg.MySQL)
+// serve runs the HTTP server until SIGINT or SIGTERM, then stops taking new
+// connections and waits for in-flight requests and background jobs.
+func serve(cfg *conf.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	database, err := db.ConnectMySQL(ctx, &cfg.MySQL)
 	if err != nil {
-		util.Fatalf("init mysql failed: %v", err)
+		return fmt.Errorf("init mysql failed: %w", err)
 	}
 	defer database.Close()
+	if err := metrics.RegisterDB(database, "moss"); err != nil {
+		return fmt.Errorf("register db metrics failed: %w", err)
+	}
 
 	if err := db.AutoMigrate(database); err != nil {
-		util.Fatalf("auto migrate failed: %v", err)
+		return fmt.Errorf("auto migrate failed: %w", err)
 	}
 
+	audioStore, err := newAudioStore(cfg)
+	if err != nil {
+		return fmt.Errorf("init audio store failed: %w", err)
+	}
+	reciteService := newReciteService(cfg, database, audioStore)
+
 	e := echo.New()
 	e.Use(util.RequestLogIDMiddleware())
-	registerRoutes(e, cfg, database)
+	e.Use(metrics.Middleware())
+	if cfg.Log.AccessLog {
+		e.Use(util.AccessLogMiddleware())
+	}
+	registerRoutes(e, cfg, database, audioStore, newAuthService(database), reciteService)
 
-	util.Infof("server starting at %s", cfg.Server.Addr)
-	if err := e.Start(cfg.Server.Addr); err != nil {
-		util.Fatalf("echo start failed: %v", err)
+	startErr := make(chan error, 1)
+	go func() {
+		util.Infof("server starting at %s", cfg.Server.Addr)
+		startErr <- e.Start(cfg.Server.Addr)
+	}()
+
+	select {
+	case err := <-startErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("echo start failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	stop()
+
+	timeout := time.Duration(cfg.Server.ShutdownTimeoutSec) * time.Second
+	util.Infof("server shutting down, waiting up to %s", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		util.Errorf("drain http requests failed: %v", err)
+	}
+	if err := reciteService.Shutdown(shutdownCtx); err != nil {
+		util.Errorf("drain background jobs failed: %v", err)
 	}
+	util.Infof("server stopped")
+	return nil
 }
@@ -3,48 +3,81 @@ package main
 import (
 	"compress/gzip"
 	"database/sql"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	authhandler "github.com/wutianfang/moss/app/handler/auth"
 	"github.com/wutianfang/moss/app/handler/common"
 	recitehandler "github.com/wutianfang/moss/app/handler/recite"
 	todohandler "github.com/wutianfang/moss/app/handler/todo"
+	"github.com/wutianfang/moss/app/service/auth"
 	"github.com/wutianfang/moss/app/service/recite"
 	"github.com/wutianfang/moss/conf"
+	authrepository "github.com/wutianfang/moss/infra/auth/repository"
+	"github.com/wutianfang/moss/infra/metrics"
+	"github.com/wutianfang/moss/infra/recite/audio"
 	"github.com/wutianfang/moss/infra/recite/fetcher"
 	"github.com/wutianfang/moss/infra/recite/repository"
+	"github.com/wutianfang/moss/infra/storage"
 	"github.com/wutianfang/moss/util"
 )
 
-func registerRoutes(e *echo.Echo, cfg *conf.Config, db *sql.DB) {
+func newReciteService(cfg *conf.Config, db *sql.DB, audioStore storage.Store) *recite.Service {
 	wordRepo := repository.NewWordRepository(db)
 	unitRepo := repository.NewUnitRepository(db)
 	unitWordRepo := repository.NewUnitWordRepository(db)
 	forgottenRepo := repository.NewForgottenWordRepository(db)
 	quizRepo := repository.NewQuizRepository(db)
 	noteRepo := repository.NewNoteRepository(db)
-	wordFetcher := fetcher.NewIcibaFetcher(cfg.Storage.WordMP3Dir)
-	reciteService := recite.NewService(
+	wordFormRepo := repository.NewWordFormRepository(db)
+	audioLibrary := fetcher.NewAudioLibrary(audioStore)
+	wordFetcher := fetcher.NewIcibaFetcher(audioLibrary)
+	return recite.NewService(
 		wordRepo,
 		unitRepo,
 		unitWordRepo,
 		forgottenRepo,
 		quizRepo,
 		noteRepo,
+		wordFormRepo,
 		wordFetcher,
+		audioLibrary,
+		newAudioProvider(cfg),
 		cfg.Recite.DefaultAccent,
 		cfg.Recite.ReviewIntervalsDays,
 		cfg.Recite.NoteTypes,
+		recite.ForgottenPolicy{
+			AddOnForgotten:       cfg.Recite.ForgottenPolicy.AddOnForgotten,
+			AddOnWrong:           cfg.Recite.ForgottenPolicy.AddOnWrong,
+			RememberAfterCorrect: cfg.Recite.ForgottenPolicy.RememberAfterCorrect,
+		},
 	)
+}
+
+func newAuthService(db *sql.DB) *auth.Service {
+	return auth.NewService(authrepository.NewTokenRepository(db))
+}
+
+func registerRoutes(e *echo.Echo, cfg *conf.Config, db *sql.DB, audioStore storage.Store, authService *auth.Service, reciteService *recite.Service) {
 
 	e.Static("/static", "static")
-	e.Static("/word_mp3", cfg.Storage.WordMP3Dir)
+	e.GET("/word_mp3/*", common.WordAudio(audioStore, audioPresignExpiry(cfg)))
 
 	e.GET("/", common.IndexPage)
 	e.GET("/healthz", common.Health)
+	e.GET("/readyz", common.Ready(db, audioStore))
+	e.GET("/metrics", metrics.Handler())
 
 	api := e.Group("/api")
 	api.Use(util.GzipResponseMiddleware(gzip.BestSpeed))
+	api.Use(authhandler.TokenAuth(authService, cfg.Auth.RequireToken))
 	api.GET("/todo/placeholder", todohandler.Placeholder)
+	api.GET("/openapi.json", recitehandler.OpenAPI())
+
+	authGroup := api.Group("/auth")
+	authGroup.POST("/tokens", authhandler.CreateToken(authService))
+	authGroup.GET("/tokens", authhandler.ListTokens(authService))
+	authGroup.DELETE("/tokens/:tokenId", authhandler.RevokeToken(authService))
 
 	reciteGroup := api.Group("/recite")
 	reciteGroup.GET("/config", recitehandler.GetClientConfig(reciteService))
@@ -54,9 +87,18 @@ func registerRoutes(e *echo.Echo, cfg *conf.Config, db *sql.DB) {
 	reciteGroup.DELETE("/units/:unitId", recitehandler.DeleteUnit(reciteService))
 	reciteGroup.PUT("/units/order", recitehandler.ReorderUnits(reciteService))
 	reciteGroup.POST("/words/query", recitehandler.QueryWord(reciteService))
+	reciteGroup.POST("/words/query/batch", recitehandler.QueryWords(reciteService))
+	reciteGroup.GET("/words/search", recitehandler.SearchWords(reciteService))
+	reciteGroup.GET("/words/:wordId", recitehandler.GetWord(reciteService))
+	reciteGroup.PUT("/words/:wordId", recitehandler.UpdateWord(reciteService))
+	reciteGroup.POST("/words/:wordId/revert", recitehandler.RevertWord(reciteService))
+	reciteGroup.POST("/words/:wordId/refresh", recitehandler.RefreshWord(reciteService))
+	reciteGroup.GET("/words/:wordId/graph", recitehandler.GetWordGraph(reciteService))
 	reciteGroup.POST("/units/:unitId/words", recitehandler.AddUnitWord(reciteService))
 	reciteGroup.GET("/units/:unitId/words", recitehandler.ListUnitWords(reciteService))
 	reciteGroup.GET("/units/:unitId/dictation", recitehandler.GetDictation(reciteService))
+	reciteGroup.GET("/units/:unitId/graph", recitehandler.GetUnitGraph(reciteService))
+	reciteGroup.GET("/units/:unitId/graph.dot", recitehandler.GetUnitGraphDOT(reciteService))
 	reciteGroup.GET("/review/dates", recitehandler.ListReviewDates(reciteService))
 	reciteGroup.GET("/review/words", recitehandler.ListReviewWords(reciteService))
 	reciteGroup.GET("/review/dictation", recitehandler.GetReviewDictation(reciteService))
@@ -74,5 +116,55 @@ func registerRoutes(e *echo.Echo, cfg *conf.Config, db *sql.DB) {
 	reciteGroup.PUT("/notes/:noteId", recitehandler.UpdateNote(reciteService))
 	reciteGroup.GET("/notes", recitehandler.ListNotes(reciteService))
 	reciteGroup.GET("/notes/by-words", recitehandler.ListNotesByWords(reciteService))
+	reciteGroup.GET("/notes/search", recitehandler.SearchNotes(reciteService))
+	reciteGroup.GET("/notes/trash", recitehandler.ListTrashedNotes(reciteService))
+	reciteGroup.DELETE("/notes/trash/:noteId", recitehandler.PurgeNote(reciteService))
 	reciteGroup.GET("/notes/:noteId", recitehandler.GetNote(reciteService))
+	reciteGroup.DELETE("/notes/:noteId", recitehandler.DeleteNote(reciteService))
+	reciteGroup.POST("/notes/:noteId/restore", recitehandler.RestoreNote(reciteService))
+	reciteGroup.DELETE("/notes/:noteId/words/:wordId", recitehandler.UnlinkNoteWord(reciteService))
+	reciteGroup.GET("/admin/audio/check", recitehandler.CheckAudio(reciteService))
+	reciteGroup.POST("/admin/audio/repair", recitehandler.RepairAudio(reciteService))
+	reciteGroup.GET("/admin/audio/repair", recitehandler.AudioRepairStatus(reciteService))
+}
+
+// newAudioStore opens the blob store holding word and sentence audio.
+func newAudioStore(cfg *conf.Config) (storage.Store, error) {
+	if cfg.Storage.Backend != "s3" {
+		return storage.NewLocalStore(cfg.Storage.WordMP3Dir), nil
+	}
+	s3 := cfg.Storage.S3
+	return storage.NewS3Store(storage.S3Options{
+		Endpoint:  s3.Endpoint,
+		AccessKey: s3.AccessKey,
+		SecretKey: s3.SecretKey,
+		Bucket:    s3.Bucket,
+		Region:    s3.Region,
+		Prefix:    s3.Prefix,
+		UseSSL:    s3.UseSSL,
+		PathStyle: s3.PathStyle,
+	})
+}
+
+// audioPresignExpiry is how long presigned audio URLs stay valid, 0 when audio
+// is proxied.
+func audioPresignExpiry(cfg *conf.Config) time.Duration {
+	if cfg.Storage.Backend != "s3" || cfg.Storage.S3.URLMode != "presign" {
+		return 0
+	}
+	return time.Duration(cfg.Storage.S3.PresignExpirySec) * time.Second
+}
+
+// newAudioProvider returns the local TTS fallback, or nil when it is disabled
+// or misconfigured.
+func newAudioProvider(cfg *conf.Config) audio.Provider {
+	if !cfg.TTS.Enabled {
+		return nil
+	}
+	provider, err := audio.NewCommandTTS(cfg.TTS.Command, cfg.TTS.EncodeCommand, cfg.TTS.Voices, time.Duration(cfg.TTS.TimeoutSec)*time.Second)
+	if err != nil {
+		util.Errorf("init tts audio provider failed: %v", err)
+		return nil
+	}
+	return provider
 }
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	recitehandler "github.com/wutianfang/moss/app/handler/recite"
+	"github.com/wutianfang/moss/app/service/auth"
+	"github.com/wutianfang/moss/app/service/recite"
+	"github.com/wutianfang/moss/conf"
+	"github.com/wutianfang/moss/infra/auth/repository/memory"
+	"github.com/wutianfang/moss/util/openapi"
+)
+
+func newTestRoutes(t *testing.T, settings ...string) *echo.Echo {
+	t.Helper()
+	e, _ := newTestAuthRoutes(t, settings...)
+	return e
+}
+
+// newTestAuthRoutes also returns the auth service, backed by memory, to issue
+// tokens with.
+func newTestAuthRoutes(t *testing.T, settings ...string) (*echo.Echo, *auth.Service) {
+	t.Helper()
+	cfg, err := conf.LoadFrom(conf.Source{Path: "conf/config.yaml.template", Settings: settings})
+	if err != nil {
+		t.Fatalf("load config template: %v", err)
+	}
+	e := echo.New()
+	svc := recite.NewService(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, "", nil, nil, recite.ForgottenPolicy{})
+	authService := auth.NewService(memory.NewTokenRepository())
+	registerRoutes(e, cfg, nil, nil, authService, svc)
+	return e, authService
+}
+
+func serveWithToken(e *echo.Echo, method, path, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if secret != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+secret)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestOpenAPICoversRoutes fails when a recite route is registered without an
+// entry in the OpenAPI document, or the document lists a route that is gone.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	e := newTestRoutes(t)
+	doc := recitehandler.OpenAPIDocument()
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		// echo registers "<group>/*" for groups with middleware
+		if !strings.HasPrefix(route.Path, recitehandler.APIPrefix+"/") || strings.HasSuffix(route.Path, "/*") {
+			continue
+		}
+		path := openapi.EchoPath(route.Path)
+		registered[route.Method+" "+path] = true
+		if !doc.Has(route.Method, path) {
+			t.Errorf("route %s %s has no OpenAPI entry", route.Method, route.Path)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method := range *item {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("OpenAPI entry %s %s has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	e := newTestRoutes(t)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version || len(doc.Paths) == 0 {
+		t.Fatalf("unexpected document %+v", doc.Info)
+	}
+	for name, schema := range doc.Components.Schemas {
+		for prop, propSchema := range schema.Properties {
+			if propSchema.Ref != "" && doc.Components.Schemas[strings.TrimPrefix(propSchema.Ref, "#/components/schemas/")] == nil {
+				t.Errorf("%s.%s refers to missing schema %s", name, prop, propSchema.Ref)
+			}
+		}
+	}
+}
+
+// TestOpenAPIMatchesResponses serves routes that need no database and checks
+// their "data" has exactly the properties the document lists.
+func TestOpenAPIMatchesResponses(t *testing.T) {
+	e := newTestRoutes(t)
+	doc := recitehandler.OpenAPIDocument()
+	for _, path := range []string{"/config", "/review/dates"} {
+		rec := serveWithToken(e, http.MethodGet, recitehandler.APIPrefix+path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d", path, rec.Code)
+		}
+		var body struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET %s decode: %v", path, err)
+		}
+		op := (*doc.Paths[recitehandler.APIPrefix+path])["get"]
+		data := op.Responses["200"].Content["application/json"].Schema.Properties["data"]
+		if len(body.Data) != len(data.Properties) {
+			t.Errorf("GET %s data has %d properties, the document %d", path, len(body.Data), len(data.Properties))
+		}
+		for name := range data.Properties {
+			if _, ok := body.Data[name]; !ok {
+				t.Errorf("GET %s data lacks %q", path, name)
+			}
+		}
+	}
+}
+
+func TestTokenRequired(t *testing.T) {
+	e := newTestRoutes(t, "auth.require_token=true")
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz", "Bearer not-a-moss-token"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/recite/units", nil)
+		if header != "" {
+			req.Header.Set(echo.HeaderAuthorization, header)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status %d, want 401", header, rec.Code)
+		}
+		if rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+			t.Errorf("Authorization %q: no WWW-Authenticate header", header)
+		}
+	}
+
+	// the probes outside /api stay open
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz status %d, want 200", rec.Code)
+	}
+}
+
+// TestTokenScopes checks that without require_token only reads may go without
+// a token, and that a token never reaches past its scope.
+func TestTokenScopes(t *testing.T) {
+	e, authService := newTestAuthRoutes(t)
+	ctx := context.Background()
+	read, err := authService.CreateToken(ctx, "reader", auth.ScopeRead)
+	if err != nil {
+		t.Fatalf("create read token: %v", err)
+	}
+	admin, err := authService.CreateToken(ctx, "admin", auth.ScopeAdmin)
+	if err != nil {
+		t.Fatalf("create admin token: %v", err)
+	}
+
+	cases := []struct {
+		name, method, path, secret string
+		want                       int
+	}{
+		{"anonymous write", http.MethodPost, "/api/recite/units", "", http.StatusUnauthorized},
+		{"anonymous quiz", http.MethodPost, "/api/recite/quizzes/start", "", http.StatusUnauthorized},
+		{"anonymous token list", http.MethodGet, "/api/auth/tokens", "", http.StatusUnauthorized},
+		{"anonymous token create", http.MethodPost, "/api/auth/tokens", "", http.StatusUnauthorized},
+		{"anonymous audio repair", http.MethodPost, "/api/recite/admin/audio/repair", "", http.StatusUnauthorized},
+		{"read token write", http.MethodPost, "/api/recite/units", read.Secret, http.StatusForbidden},
+		{"read token quiz", http.MethodPost, "/api/recite/quizzes/start", read.Secret, http.StatusForbidden},
+		{"read token token create", http.MethodPost, "/api/auth/tokens", read.Secret, http.StatusForbidden},
+		{"read token audio check", http.MethodGet, "/api/recite/admin/audio/check", read.Secret, http.StatusForbidden},
+		{"admin token list", http.MethodGet, "/api/auth/tokens", admin.Secret, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if rec := serveWithToken(e, tc.method, tc.path, tc.secret); rec.Code != tc.want {
+			t.Errorf("%s: %s %s status %d, want %d", tc.name, tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+}
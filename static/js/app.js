@@ -1,14 +1,29 @@
 const { useCallback, useEffect, useMemo, useRef, useState } = React;
 
-function api(path, options = {}) {
+const API_TOKEN_KEY = "moss_api_token";
+
+function api(path, options = {}, retried = false) {
   const init = { ...options };
   init.headers = { ...(options.headers || {}) };
   if (init.body !== undefined && typeof init.body !== "string") {
     init.headers["Content-Type"] = "application/json";
     init.body = JSON.stringify(init.body);
   }
+  const token = window.localStorage.getItem(API_TOKEN_KEY);
+  if (token) {
+    init.headers.Authorization = `Bearer ${token}`;
+  }
   return fetch(path, init)
-    .then((resp) => resp.json())
+    .then((resp) => {
+      if (resp.status === 401 && !retried) {
+        const input = window.prompt("请输入 API 令牌");
+        if (input && input.trim()) {
+          window.localStorage.setItem(API_TOKEN_KEY, input.trim());
+          return api(path, options, true).then((data) => ({ errno: 0, data }));
+        }
+      }
+      return resp.json();
+    })
     .then((result) => {
       if (result.errno !== 0) {
         throw new Error(result.error || "请求失败");
@@ -54,7 +69,7 @@ function useIsMobile(breakpoint = 960) {
 }
 
 function normalizeWordText(raw) {
-  return (raw || "").trim().toLowerCase();
+  return (raw || "").trim().toLowerCase().replace(/\s+/g, " ");
 }
 
 function getEnglishAudio(row) {
@@ -387,7 +402,9 @@ function NoteViewerModal({ visible, note, loading, onClose }) {
           <div className="note-view-body">
             <div className="note-view-line"><strong>标签：</strong>{note.type || "-"}</div>
             <div className="note-view-line"><strong>关联单词：</strong>{(note.words || []).map((w) => w.word).join(" / ") || "-"}</div>
-            <pre className="note-content-pre">{note.content || "-"}</pre>
+            {note.html
+              ? <div className="note-content-html" dangerouslySetInnerHTML={{ __html: note.html }} />
+              : <pre className="note-content-pre">{note.content || "-"}</pre>}
           </div>
         )}
       </div>
@@ -407,6 +424,7 @@ function NoteEditorModal({
   const [searchInput, setSearchInput] = useState("");
   const [searchResult, setSearchResult] = useState(null);
   const [words, setWords] = useState([]);
+  const [refWordIDs, setRefWordIDs] = useState([]);
   const [noteType, setNoteType] = useState("");
   const [content, setContent] = useState("");
   const [saving, setSaving] = useState(false);
@@ -430,6 +448,7 @@ function NoteEditorModal({
           setNoteType(note.type || options[0]);
           setContent(note.content || "");
           setWords(note.words || []);
+          setRefWordIDs((note.ref_word_ids || []).map(Number));
         })
         .catch((err) => setError(err.message))
         .finally(() => setLoading(false));
@@ -438,6 +457,7 @@ function NoteEditorModal({
     setLoading(false);
     setNoteType(options[0] || "");
     setContent("");
+    setRefWordIDs([]);
     if (defaultWord && defaultWord.word_id) {
       setWords([defaultWord]);
     } else {
@@ -491,7 +511,8 @@ function NoteEditorModal({
       setError("请选择类型");
       return;
     }
-    if (words.length === 0) {
+    // [[word]] references in the content link their words on save
+    if (words.length === 0 && !(content || "").includes("[[")) {
       setError("请至少关联一个单词");
       return;
     }
@@ -504,7 +525,9 @@ function NoteEditorModal({
     const body = {
       note_type: noteType,
       content,
-      word_ids: words.map((item) => Number(item.word_id)).filter((id) => id > 0),
+      word_ids: words
+        .map((item) => Number(item.word_id))
+        .filter((id) => id > 0 && !refWordIDs.includes(id)),
     };
     const req = noteId
       ? api(`/api/recite/notes/${noteId}`, { method: "PUT", body })
@@ -625,7 +648,7 @@ function NoteEditorModal({
                 className="input note-textarea"
                 value={content}
                 onChange={(e) => setContent(e.target.value)}
-                placeholder="请输入笔记内容"
+                placeholder="请输入笔记内容，支持 Markdown，用 [[单词]] 引用并自动关联单词"
               />
             </div>
 
@@ -970,8 +993,8 @@ function DictationPanel({
         result: localResultToServer(nextResult),
       },
     })
-      .then(() => {
-        markWordCompleted(row, nextResult, submittedInput);
+      .then((data) => {
+        markWordCompleted(row, serverResultToLocal(data.result) || nextResult, submittedInput);
         return true;
       })
       .catch((err) => {
@@ -2325,14 +2348,16 @@ function MobileQuizPanel({
         result: ok ? "正确" : "错误",
       },
     })
-      .then(() => {
+      .then((data) => {
         const key = rowKey(current);
-        setStatusMap((prev) => ({ ...prev, [key]: nextResult }));
+        const serverResult = data.result || (ok ? "正确" : "错误");
+        const finalResult = serverResult === "正确" ? "correct" : nextResult;
+        setStatusMap((prev) => ({ ...prev, [key]: finalResult }));
         setWordStatusMap((prev) => ({ ...prev, [key]: "已测试" }));
         setWords((prev) => prev.map((row) => (
-          rowKey(row) === key ? { ...row, word_status: "已测试", input_answer: submittedInput, quiz_result: ok ? "正确" : "错误" } : row
+          rowKey(row) === key ? { ...row, word_status: "已测试", input_answer: submittedInput, quiz_result: serverResult } : row
         )));
-        setResult(nextResult);
+        setResult(finalResult);
         setRevealed(true);
         if (type === "spelling") {
           playAudio(getDefaultAudio(current, defaultAccent));
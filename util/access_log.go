@@ -0,0 +1,46 @@
+package util
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AccessLogMiddleware logs one "access" record per request with the method,
+// route, status, latency and log id. It must run inside
+// RequestLogIDMiddleware to see the log id.
+func AccessLogMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let echo write the error response now so the status is known
+				c.Error(err)
+			}
+
+			req := c.Request()
+			status := c.Response().Status
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("route", c.Path()),
+				slog.Int("status", status),
+				slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+				slog.Int64("bytes", c.Response().Size),
+				slog.String("remote_ip", c.RealIP()),
+				slog.String("log_id", LogIDFromContext(req.Context())),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logf(req.Context(), 2, level, "access", attrs...)
+			return nil
+		}
+	}
+}
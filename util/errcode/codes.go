@@ -0,0 +1,131 @@
+package errcode
+
+import "net/http"
+
+// Errno classes; clients that predate the keys compare these. Errors that
+// existed before the keys keep the errno they had then, even where it differs
+// from their class (see WordUnknown and QuizFinished).
+const (
+	errnoInternal = 1
+	errnoInvalid  = 1001
+	errnoNotFound = 1002
+	errnoUpstream = 1003
+	errnoConflict = 1004
+	errnoAuth     = 1005
+	errnoScope    = 1006
+)
+
+func invalid(key, zh, en string) *Code {
+	return &Code{Errno: errnoInvalid, Key: key, Status: http.StatusBadRequest, Zh: zh, En: en}
+}
+
+func notFound(key, zh, en string) *Code {
+	return &Code{Errno: errnoNotFound, Key: key, Status: http.StatusNotFound, Zh: zh, En: en}
+}
+
+func conflict(key, zh, en string) *Code {
+	return &Code{Errno: errnoConflict, Key: key, Status: http.StatusConflict, Zh: zh, En: en}
+}
+
+func internal(key, zh, en string) *Code {
+	return &Code{Errno: errnoInternal, Key: key, Status: http.StatusInternalServerError, Zh: zh, En: en}
+}
+
+// Generic errors.
+var (
+	Internal       = internal("internal", "服务内部错误", "internal error")
+	InvalidRequest = invalid("invalid_request", "请求参数错误", "invalid request parameters")
+	// InvalidParam takes the parameter name, e.g. "unit_id".
+	InvalidParam = invalid("invalid_param", "%s 非法", "invalid %s")
+	InvalidDate  = invalid("invalid_date", "日期格式错误，需为 yyyy-mm-dd", "invalid date, want yyyy-mm-dd")
+)
+
+// Units.
+var (
+	UnitNotFound        = notFound("unit_not_found", "单元不存在", "unit not found")
+	UnitNameEmpty       = invalid("unit_name_empty", "单元名称不能为空", "unit name must not be empty")
+	InvalidReciteDate   = invalid("invalid_recite_date", "背诵时间格式错误，需为 yyyy-mm-dd", "invalid recite date, want yyyy-mm-dd")
+	UnitOrderEmpty      = invalid("unit_order_empty", "排序列表不能为空", "unit order must not be empty")
+	UnitOrderDuplicate  = invalid("unit_order_duplicate", "排序列表包含重复单元", "unit order lists a unit twice")
+	UnitOrderIncomplete = invalid("unit_order_incomplete", "排序列表必须包含全部单元", "unit order must list every unit")
+)
+
+// Words.
+var (
+	WordNotFound = notFound("word_not_found", "单词不存在", "word not found")
+	WordEmpty    = invalid("word_empty", "单词不能为空", "word must not be empty")
+	WordInvalid  = invalid("word_invalid", "单词格式非法，仅支持英文字母/单引号/短横线/空格",
+		"invalid word, only letters, apostrophes, hyphens and spaces are allowed")
+	// WordFetchFailed wraps the dictionary error.
+	WordFetchFailed = &Code{Errno: errnoUpstream, Key: "word_fetch_failed", Status: http.StatusBadGateway,
+		Zh: "查词失败", En: "dictionary lookup failed"}
+	// WordUnknown keeps the errno of WordFetchFailed, which it was split from.
+	WordUnknown = &Code{Errno: errnoUpstream, Key: "word_unknown", Status: http.StatusNotFound,
+		Zh: "词典中没有这个单词", En: "the dictionary has no such word"}
+	// MnemonicTooLong takes the max length in characters.
+	MnemonicTooLong         = invalid("mnemonic_too_long", "助记内容不能超过 %d 字", "mnemonic must not exceed %d characters")
+	CustomModeInvalid       = invalid("custom_mode_invalid", "自定义释义模式非法", "invalid custom meaning mode")
+	SentenceEmpty           = invalid("sentence_empty", "例句英文不能为空", "example sentence text must not be empty")
+	SentenceAudioURLInvalid = invalid("sentence_audio_url_invalid", "例句音频只能使用词典提供的地址",
+		"example sentence audio must use a url the dictionary provided")
+	WordNotEdited  = conflict("word_not_edited", "单词未被编辑，无需还原", "word has no edits to revert")
+	WordBatchEmpty = invalid("word_batch_empty", "单词列表不能为空", "word list must not be empty")
+	// WordBatchTooLarge takes the max number of words.
+	WordBatchTooLarge     = invalid("word_batch_too_large", "单词列表不能超过 %d 个", "word list must not exceed %d words")
+	WordSearchQueryEmpty  = invalid("word_search_query_empty", "搜索内容不能为空", "search query must not be empty")
+	WordSearchModeInvalid = invalid("word_search_mode_invalid", "搜索方式非法", "invalid search mode")
+	AudioRepairRunning    = conflict("audio_repair_running", "音频修复正在进行中", "an audio repair is already running")
+)
+
+// Quizzes.
+var (
+	QuizNotFound     = notFound("quiz_not_found", "测验不存在", "quiz not found")
+	QuizWordNotFound = notFound("quiz_word_not_found", "测验单词不存在", "quiz word not found")
+	QuizNoWords      = notFound("quiz_no_words", "暂无可测试单词", "no words to quiz")
+	// QuizFinished predates the conflict class and keeps its invalid errno.
+	QuizFinished = &Code{Errno: errnoInvalid, Key: "quiz_finished", Status: http.StatusConflict,
+		Zh: "测验已完结", En: "quiz is already finished"}
+	QuizTypeInvalid   = invalid("quiz_type_invalid", "测验类型非法", "invalid quiz type")
+	QuizSourceInvalid = invalid("quiz_source_invalid", "测验来源非法", "invalid quiz source")
+	QuizResultInvalid = invalid("quiz_result_invalid", "测验结果非法", "invalid quiz result")
+	QuizBadWordID     = internal("quiz_bad_word_id", "单词ID异常", "quiz source has an invalid word id")
+	QuizNoConfusables = invalid("quiz_no_confusables", "没有关联两个以上单词的笔记", "no note links two or more words")
+)
+
+// Notes.
+var (
+	NoteNotFound     = notFound("note_not_found", "笔记不存在", "note not found")
+	NoteContentEmpty = invalid("note_content_empty", "笔记内容不能为空", "note content must not be empty")
+	NoteTypeInvalid  = invalid("note_type_invalid", "笔记类型非法", "invalid note type")
+	NoteWordsEmpty   = invalid("note_words_empty", "关联单词不能为空", "a note needs at least one linked word")
+	NoteWordsUnknown = notFound("note_words_unknown", "存在无效关联单词", "some linked words do not exist")
+	// NoteWordRefUnknown takes the referenced words missing from the dictionary.
+	NoteWordRefUnknown = notFound("note_word_ref_unknown", "笔记引用的单词尚未查询过：%s", "referenced words have not been looked up yet: %s")
+	// NoteSearchTooManyTerms takes the max number of terms.
+	NoteSearchTooManyTerms = invalid("note_search_too_many_terms", "搜索词不能超过 %d 个", "a search must not have more than %d terms")
+	NoteSearchSortInvalid  = invalid("note_search_sort_invalid", "排序方式非法", "invalid sort order")
+	NoteNotInTrash         = conflict("note_not_in_trash", "笔记不在回收站中", "note is not in the trash")
+	NoteWordNotLinked      = notFound("note_word_not_linked", "单词未关联到该笔记", "word is not linked to the note")
+	NoteLastWord           = conflict("note_last_word", "不能移除笔记的最后一个单词", "cannot unlink the last word of a note")
+)
+
+// API tokens.
+var (
+	TokenMissing = &Code{Errno: errnoAuth, Key: "token_missing", Status: http.StatusUnauthorized,
+		Zh: "缺少 API 令牌", En: "an API token is required"}
+	TokenInvalid = &Code{Errno: errnoAuth, Key: "token_invalid", Status: http.StatusUnauthorized,
+		Zh: "API 令牌无效或已吊销", En: "the API token is invalid or revoked"}
+	// TokenScopeDenied takes the scope the request needs.
+	TokenScopeDenied = &Code{Errno: errnoScope, Key: "token_scope_denied", Status: http.StatusForbidden,
+		Zh: "API 令牌权限不足，需要 %s", En: "the API token lacks the %s scope"}
+	TokenNotFound     = notFound("token_not_found", "API 令牌不存在", "API token not found")
+	TokenNameEmpty    = invalid("token_name_empty", "令牌名称不能为空", "token name must not be empty")
+	TokenScopeInvalid = invalid("token_scope_invalid", "令牌权限非法", "invalid token scope")
+)
+
+// Components missing from a partially wired service.
+var (
+	QuizRepoMissing   = internal("quiz_repo_missing", "测验仓储未初始化", "quiz repository is not initialized")
+	NoteRepoMissing   = internal("note_repo_missing", "笔记仓储未初始化", "note repository is not initialized")
+	AudioCheckMissing = internal("audio_check_missing", "音频检查未初始化", "audio check is not initialized")
+)
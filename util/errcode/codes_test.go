@@ -0,0 +1,46 @@
+package errcode
+
+import "testing"
+
+// TestLegacyErrnos pins the errno of errors that clients compared before the
+// keys existed.
+func TestLegacyErrnos(t *testing.T) {
+	cases := []struct {
+		code  *Code
+		errno int
+	}{
+		{InvalidRequest, 1001},
+		{InvalidParam, 1001},
+		{InvalidDate, 1001},
+		{InvalidReciteDate, 1001},
+		{UnitNameEmpty, 1001},
+		{UnitNotFound, 1002},
+		{UnitOrderEmpty, 1001},
+		{UnitOrderDuplicate, 1001},
+		{UnitOrderIncomplete, 1001},
+		{WordEmpty, 1001},
+		{WordInvalid, 1001},
+		{WordFetchFailed, 1003},
+		{WordUnknown, 1003},
+		{QuizNotFound, 1002},
+		{QuizWordNotFound, 1002},
+		{QuizNoWords, 1002},
+		{QuizFinished, 1001},
+		{QuizTypeInvalid, 1001},
+		{QuizSourceInvalid, 1001},
+		{QuizResultInvalid, 1001},
+		{QuizBadWordID, 1},
+		{QuizRepoMissing, 1},
+		{NoteRepoMissing, 1},
+		{NoteNotFound, 1002},
+		{NoteContentEmpty, 1001},
+		{NoteTypeInvalid, 1001},
+		{NoteWordsEmpty, 1001},
+		{NoteWordsUnknown, 1002},
+	}
+	for _, tc := range cases {
+		if tc.code.Errno != tc.errno {
+			t.Errorf("%s errno = %d, want %d", tc.code.Key, tc.code.Errno, tc.errno)
+		}
+	}
+}
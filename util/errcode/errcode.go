@@ -0,0 +1,123 @@
+// Package errcode is the catalogue of errors the API reports to clients.
+// Every Code has a stable errno, a machine-readable key, an HTTP status and
+// its message in Chinese and English.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Languages of the messages.
+const (
+	LangZh = "zh"
+	LangEn = "en"
+)
+
+// Code is one entry of the catalogue. Zh and En are fmt formats filled with
+// the args of the Error.
+type Code struct {
+	Errno  int
+	Key    string
+	Status int
+	Zh     string
+	En     string
+}
+
+// Error is an API error: a catalogue Code with its message args and, for
+// internal failures, the underlying cause.
+type Error struct {
+	Code *Code
+	Args []any
+	Err  error
+}
+
+// New returns an error of code with the message args.
+func New(code *Code, args ...any) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+// Wrap returns an error of code caused by err; the cause is appended to the
+// message.
+func Wrap(code *Code, err error, args ...any) *Error {
+	return &Error{Code: code, Args: args, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message(LangZh)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same code, so errors.Is(err, errcode.New(NotFound))
+// works through wrapping.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Message renders the message in lang, falling back to Chinese.
+func (e *Error) Message(lang string) string {
+	format := e.Code.Zh
+	if lang == LangEn && e.Code.En != "" {
+		format = e.Code.En
+	}
+	msg := format
+	if len(e.Args) > 0 {
+		msg = fmt.Sprintf(format, e.Args...)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Parse classifies err. An *Error anywhere in the wrap chain is returned as
+// is; anything else is an Internal error caused by err.
+func Parse(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return Wrap(Internal, err)
+}
+
+// Lang picks the message language from an Accept-Language header: the
+// preferred one of zh and en, Chinese when neither is accepted.
+func Lang(acceptLanguage string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(name), "-")
+		if (primary == LangZh || primary == LangEn) && q > 0 {
+			tags = append(tags, tag{lang: primary, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	if len(tags) == 0 {
+		return LangZh
+	}
+	return tags[0].lang
+}
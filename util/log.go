@@ -1,36 +1,298 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func InitLogger(logDir string) error {
-	if err := os.MkdirAll(logDir, 0o755); err != nil {
-		return fmt.Errorf("mkdir log dir failed: %w", err)
+// LogOptions configures InitLogger.
+//
+// Levels overrides Level for single packages, keyed by import path relative
+// to the module ("app/service/recite", "infra/recite/repository"). A key also
+// covers the packages below it; the longest matching key wins.
+type LogOptions struct {
+	Dir        string
+	Level      string
+	Format     string
+	Stdout     bool
+	Rotate     string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+	Levels     map[string]string
+}
+
+var logger = slog.New(newPackageLevelHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}), slog.LevelInfo, nil))
+
+// InitLogger sends logs to a rotating file in opts.Dir and, when opts.Stdout
+// is set, to stdout. The returned closer flushes the log file.
+func InitLogger(opts LogOptions) (io.Closer, error) {
+	level, err := ParseLogLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	overrides := make([]packageLevel, 0, len(opts.Levels))
+	for pkg, raw := range opts.Levels {
+		pkgLevel, err := ParseLogLevel(raw)
+		if err != nil {
+			return nil, fmt.Errorf("log level of %s: %w", pkg, err)
+		}
+		pkg = strings.Trim(strings.TrimSpace(pkg), "/")
+		if pkg == "" {
+			return nil, fmt.Errorf("log level override without package")
+		}
+		overrides = append(overrides, packageLevel{pkg: pkg, level: pkgLevel})
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir log dir failed: %w", err)
 	}
-	logFileName := filepath.Join(logDir, time.Now().Format("20060102")+".log")
-	file, err := os.OpenFile(logFileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	file, err := newRotatingFile(filepath.Join(opts.Dir, "moss.log"), opts)
 	if err != nil {
-		return fmt.Errorf("open log file failed: %w", err)
+		return nil, err
 	}
-	log.SetOutput(io.MultiWriter(os.Stdout, file))
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-	return nil
+	var out io.Writer = file
+	if opts.Stdout {
+		out = io.MultiWriter(os.Stdout, file)
+	}
+
+	handlerOpts := &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug}
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", "json":
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		_ = file.Close()
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	logger = slog.New(newPackageLevelHandler(handler, level, overrides))
+	slog.SetDefault(logger)
+	return file, nil
+}
+
+// ParseLogLevel parses debug, info, warn or error; empty means info.
+func ParseLogLevel(raw string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", raw)
+	}
+}
+
+// Logger returns the process logger for callers that log attributes directly.
+func Logger() *slog.Logger {
+	return logger
+}
+
+func Debugf(format string, args ...any) {
+	logf(context.Background(), 3, slog.LevelDebug, fmt.Sprintf(format, args...))
 }
 
 func Infof(format string, args ...any) {
-	log.Printf("[INFO] "+format, args...)
+	logf(context.Background(), 3, slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func Warnf(format string, args ...any) {
+	logf(context.Background(), 3, slog.LevelWarn, fmt.Sprintf(format, args...))
 }
 
 func Errorf(format string, args ...any) {
-	log.Printf("[ERROR] "+format, args...)
+	logf(context.Background(), 3, slog.LevelError, fmt.Sprintf(format, args...))
 }
 
 func Fatalf(format string, args ...any) {
-	log.Fatalf("[FATAL] "+format, args...)
+	logf(context.Background(), 3, slog.LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// logf writes one record attributed to the function skip frames up the stack
+// (3 for the caller of an exported helper), so the source and the package
+// level overrides point at the real call site.
+func logf(ctx context.Context, skip int, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(skip, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.AddAttrs(attrs...)
+	_ = logger.Handler().Handle(ctx, record)
+}
+
+type packageLevel struct {
+	pkg   string
+	level slog.Level
+}
+
+// packageLevelHandler filters records by the level configured for the package
+// of the calling function.
+type packageLevelHandler struct {
+	next      slog.Handler
+	level     slog.Level
+	minLevel  slog.Level
+	overrides []packageLevel
+	cache     *sync.Map
+}
+
+func newPackageLevelHandler(next slog.Handler, level slog.Level, overrides []packageLevel) *packageLevelHandler {
+	sort.Slice(overrides, func(i, j int) bool {
+		return len(overrides[i].pkg) > len(overrides[j].pkg)
+	})
+	minLevel := level
+	for _, item := range overrides {
+		if item.level < minLevel {
+			minLevel = item.level
+		}
+	}
+	return &packageLevelHandler{next: next, level: level, minLevel: minLevel, overrides: overrides, cache: &sync.Map{}}
+}
+
+func (h *packageLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.minLevel
+}
+
+func (h *packageLevelHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < h.levelOf(record.PC) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *packageLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	return &clone
+}
+
+func (h *packageLevelHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
+
+func (h *packageLevelHandler) levelOf(pc uintptr) slog.Level {
+	if len(h.overrides) == 0 || pc == 0 {
+		return h.level
+	}
+	if cached, ok := h.cache.Load(pc); ok {
+		return cached.(slog.Level)
+	}
+	level := h.level
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		path := "/" + funcPackage(fn.Name()) + "/"
+		for _, item := range h.overrides {
+			if strings.Contains(path, "/"+item.pkg+"/") {
+				level = item.level
+				break
+			}
+		}
+	}
+	h.cache.Store(pc, level)
+	return level
+}
+
+// funcPackage returns the import path of a function name as reported by
+// runtime, e.g. "github.com/x/moss/util.Infof" -> "github.com/x/moss/util".
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[slash+1:], "."); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// rotatingFile is a size rotated log file that also starts a new file every
+// day or hour.
+type rotatingFile struct {
+	mu     sync.Mutex
+	file   *lumberjack.Logger
+	period string
+	next   time.Time
+}
+
+// rotateClock tells rotatingFile the time; tests move it across a period
+// boundary.
+var rotateClock = time.Now
+
+func newRotatingFile(path string, opts LogOptions) (*rotatingFile, error) {
+	period := strings.ToLower(strings.TrimSpace(opts.Rotate))
+	switch period {
+	case "daily", "hourly":
+	case "", "none":
+		period = ""
+	default:
+		return nil, fmt.Errorf("unknown log rotate period %q", opts.Rotate)
+	}
+	w := &rotatingFile{
+		file: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			LocalTime:  true,
+			Compress:   opts.Compress,
+		},
+		period: period,
+	}
+	if period != "" {
+		now := rotateClock()
+		w.next = periodStart(now, period, 1)
+		// a file left over from an earlier period is rotated away first
+		if info, err := os.Stat(path); err == nil && info.ModTime().Before(periodStart(now, period, 0)) {
+			if err := w.file.Rotate(); err != nil {
+				return nil, fmt.Errorf("rotate log file failed: %w", err)
+			}
+		}
+	}
+	return w, nil
+}
+
+func (w *rotatingFile) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.period != "" {
+		if now := rotateClock(); !now.Before(w.next) {
+			if err := w.file.Rotate(); err != nil {
+				return 0, err
+			}
+			w.next = periodStart(now, w.period, 1)
+		}
+	}
+	return w.file.Write(p)
+}
+
+func (w *rotatingFile) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// periodStart returns the start of the day or hour containing t, shifted by
+// offset periods.
+func periodStart(t time.Time, period string, offset int) time.Time {
+	if period == "hourly" {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+offset, 0, 0, 0, t.Location())
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, t.Location())
 }
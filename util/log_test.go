@@ -0,0 +1,237 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLog points the process logger at a JSON buffer with the given
+// default level and package overrides until the test ends.
+func captureLog(t *testing.T, level slog.Level, overrides ...packageLevel) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug})
+	saved := logger
+	logger = slog.New(newPackageLevelHandler(handler, level, overrides))
+	t.Cleanup(func() { logger = saved })
+	return &buf
+}
+
+type logLine struct {
+	Msg    string `json:"msg"`
+	Source struct {
+		Function string `json:"function"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+	} `json:"source"`
+}
+
+func readLogLines(t *testing.T, buf *bytes.Buffer) []logLine {
+	t.Helper()
+	var lines []logLine
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line logLine
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("decode log line %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func funcPC(fn any) uintptr {
+	return reflect.ValueOf(fn).Pointer()
+}
+
+func TestPackageLevelOverride(t *testing.T) {
+	buf := captureLog(t, slog.LevelInfo, packageLevel{pkg: "util", level: slog.LevelDebug})
+	Debugf("util debug")
+
+	// records attributed to a package without an override keep the info level
+	ctx := context.Background()
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo} {
+		record := slog.NewRecord(time.Now(), level, "strings "+level.String(), funcPC(strings.ToLower))
+		if err := logger.Handler().Handle(ctx, record); err != nil {
+			t.Fatalf("Handle: %v", err)
+		}
+	}
+
+	var msgs []string
+	for _, line := range readLogLines(t, buf) {
+		msgs = append(msgs, line.Msg)
+	}
+	if got := strings.Join(msgs, ","); got != "util debug,strings INFO" {
+		t.Fatalf("logged %q, want the util debug record and the strings info record", got)
+	}
+}
+
+func TestPackageLevelDefault(t *testing.T) {
+	buf := captureLog(t, slog.LevelInfo, packageLevel{pkg: "app/service/recite", level: slog.LevelDebug})
+	Debugf("util debug")
+	Infof("util info")
+	lines := readLogLines(t, buf)
+	if len(lines) != 1 || lines[0].Msg != "util info" {
+		t.Fatalf("logged %+v, want only the info record", lines)
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	handler := newPackageLevelHandler(slog.NewTextHandler(&bytes.Buffer{}, nil), slog.LevelInfo, []packageLevel{
+		{pkg: "encoding", level: slog.LevelDebug},
+		{pkg: "encoding/json", level: slog.LevelWarn},
+		{pkg: "util", level: slog.LevelError},
+	})
+	cases := []struct {
+		name string
+		pc   uintptr
+		want slog.Level
+	}{
+		{"longest key wins", funcPC(json.Marshal), slog.LevelWarn},
+		{"key covers sub packages", funcPC(base64.NewEncoding), slog.LevelDebug},
+		{"module relative key", funcPC(Infof), slog.LevelError},
+		{"no matching key", funcPC(strings.ToLower), slog.LevelInfo},
+		{"unknown caller", 0, slog.LevelInfo},
+	}
+	for _, tc := range cases {
+		// the second lookup is served from the cache
+		for i := 0; i < 2; i++ {
+			if got := handler.levelOf(tc.pc); got != tc.want {
+				t.Errorf("%s: levelOf = %v, want %v", tc.name, got, tc.want)
+			}
+		}
+	}
+	if !handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("Enabled(debug) = false although one package logs at debug")
+	}
+}
+
+func TestLogSource(t *testing.T) {
+	buf := captureLog(t, slog.LevelDebug)
+	_, file, line, _ := runtime.Caller(0)
+	Infof("plain")
+	InfofWithRequest(context.Background(), "test.source", "with request")
+	nested := func() { Warnf("nested") }
+	nested()
+
+	lines := readLogLines(t, buf)
+	if len(lines) != 3 {
+		t.Fatalf("logged %d records, want 3", len(lines))
+	}
+	wantLines := []int{line + 1, line + 2, line + 3}
+	for i, got := range lines {
+		if got.Source.File != file || got.Source.Line != wantLines[i] {
+			t.Errorf("%s: source = %s:%d, want %s:%d", got.Msg, got.Source.File, got.Source.Line, file, wantLines[i])
+		}
+		if !strings.HasPrefix(got.Source.Function, "github.com/wutianfang/moss/util.TestLogSource") {
+			t.Errorf("%s: source function = %s, want TestLogSource", got.Msg, got.Source.Function)
+		}
+	}
+}
+
+func TestRotatingFileDaily(t *testing.T) {
+	clock := time.Date(2026, 3, 9, 23, 59, 58, 0, time.Local)
+	saved := rotateClock
+	rotateClock = func() time.Time { return clock }
+	t.Cleanup(func() { rotateClock = saved })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "moss.log")
+	w, err := newRotatingFile(path, LogOptions{Rotate: "daily"})
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	defer w.Close()
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	write("a\n")
+	clock = clock.Add(time.Second + 999*time.Millisecond)
+	write("b\n")
+	if backups := logBackups(t, dir); len(backups) != 0 {
+		t.Fatalf("rotated before midnight: %v", backups)
+	}
+
+	clock = time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	write("c\n")
+	clock = clock.Add(time.Hour)
+	write("d\n")
+
+	backups := logBackups(t, dir)
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v, want one after midnight", backups)
+	}
+	if got := readFile(t, backups[0]); got != "a\nb\n" {
+		t.Errorf("backup = %q, want the records of the first day", got)
+	}
+	if got := readFile(t, path); got != "c\nd\n" {
+		t.Errorf("moss.log = %q, want the records of the second day", got)
+	}
+}
+
+func TestRotatingFileLeftover(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "moss.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if err := os.Chtimes(path, yesterday, yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newRotatingFile(path, LogOptions{Rotate: "daily"})
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("new\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if backups := logBackups(t, dir); len(backups) != 1 || readFile(t, backups[0]) != "old\n" {
+		t.Fatalf("backups = %v, want the file of yesterday", backups)
+	}
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("moss.log = %q, want only the new record", got)
+	}
+}
+
+func TestNewRotatingFilePeriod(t *testing.T) {
+	if _, err := newRotatingFile(filepath.Join(t.TempDir(), "moss.log"), LogOptions{Rotate: "weekly"}); err == nil {
+		t.Fatalf("newRotatingFile(weekly) succeeded, want an error")
+	}
+}
+
+func logBackups(t *testing.T, dir string) []string {
+	t.Helper()
+	backups, err := filepath.Glob(filepath.Join(dir, "moss-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backups
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,135 @@
+// Package markdown renders note content written in Markdown with inline word
+// references: "[[abandon]]", or "[[abandon|abandoned]]" to show a different
+// text for the word.
+//
+// Rendering is safe for untrusted input: raw HTML is dropped and links with
+// dangerous schemes such as javascript: lose their target.
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// maxRefRunes bounds the text between the brackets of a word reference.
+const maxRefRunes = 128
+
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, wordRefExtension{}),
+	// notes were plain text before, so keep their line breaks
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// Render returns the HTML of content. Word references become
+// <span class="word-ref" data-word="...">.
+func Render(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// WordRefs returns the words referenced in content in order of first
+// appearance, without duplicates. References inside code are not words.
+func WordRefs(content string) []string {
+	source := []byte(content)
+	doc := md.Parser().Parse(text.NewReader(source))
+	seen := make(map[string]struct{})
+	ret := make([]string, 0)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if ref, ok := n.(*wordRef); ok && entering {
+			if _, ok := seen[ref.Word]; !ok {
+				seen[ref.Word] = struct{}{}
+				ret = append(ret, ref.Word)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return ret
+}
+
+var kindWordRef = ast.NewNodeKind("WordRef")
+
+// wordRef is a [[word|label]] reference; Label is empty without a "|".
+type wordRef struct {
+	ast.BaseInline
+	Word  string
+	Label string
+}
+
+func (n *wordRef) Kind() ast.NodeKind {
+	return kindWordRef
+}
+
+func (n *wordRef) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Word": n.Word, "Label": n.Label}, nil)
+}
+
+type wordRefParser struct{}
+
+func (wordRefParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wordRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := string(line[2 : 2+end])
+	if inner == "" || len([]rune(inner)) > maxRefRunes || strings.ContainsAny(inner, "[]\n") {
+		return nil
+	}
+	word, label, _ := strings.Cut(inner, "|")
+	word = strings.TrimSpace(word)
+	if word == "" {
+		return nil
+	}
+	block.Advance(2 + end + 2)
+	return &wordRef{Word: word, Label: strings.TrimSpace(label)}
+}
+
+type wordRefRenderer struct{}
+
+func (wordRefRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindWordRef, renderWordRef)
+}
+
+func renderWordRef(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	ref := n.(*wordRef)
+	label := ref.Label
+	if label == "" {
+		label = ref.Word
+	}
+	_, _ = w.WriteString(`<span class="word-ref" data-word="`)
+	_, _ = w.Write(util.EscapeHTML([]byte(ref.Word)))
+	_, _ = w.WriteString(`">`)
+	_, _ = w.Write(util.EscapeHTML([]byte(label)))
+	_, _ = w.WriteString(`</span>`)
+	return ast.WalkSkipChildren, nil
+}
+
+type wordRefExtension struct{}
+
+func (wordRefExtension) Extend(m goldmark.Markdown) {
+	// ahead of the link parser, which also starts at "["
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(wordRefParser{}, 199)))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(wordRefRenderer{}, 500)))
+}
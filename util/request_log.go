@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 	"time"
@@ -13,8 +14,6 @@ import (
 
 const ResponseHeaderLogID = "X-Log-ID"
 
-var requestLogEnabled bool
-
 type requestMeta struct {
 	LogID string
 	Start time.Time
@@ -40,16 +39,22 @@ func RequestLogIDMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// DebugfWithRequest logs request tracing detail; it is dropped unless debug is
+// enabled for the calling package.
+func DebugfWithRequest(ctx context.Context, location string, format string, args ...any) {
+	logfWithRequest(ctx, slog.LevelDebug, location, format, args...)
+}
+
 func InfofWithRequest(ctx context.Context, location string, format string, args ...any) {
-	logfWithRequest(Infof, ctx, location, format, args...)
+	logfWithRequest(ctx, slog.LevelInfo, location, format, args...)
 }
 
 func ErrorfWithRequest(ctx context.Context, location string, format string, args ...any) {
-	logfWithRequest(Errorf, ctx, location, format, args...)
+	logfWithRequest(ctx, slog.LevelError, location, format, args...)
 }
 
-func logfWithRequest(logFn func(string, ...any), ctx context.Context, location string, format string, args ...any) {
-	if !requestLogEnabled {
+func logfWithRequest(ctx context.Context, level slog.Level, location string, format string, args ...any) {
+	if !logger.Enabled(ctx, level) {
 		return
 	}
 	meta := getRequestMeta(ctx)
@@ -65,11 +70,16 @@ func logfWithRequest(logFn func(string, ...any), ctx context.Context, location s
 	if location == "" {
 		location = "-"
 	}
-	logFn("[logid=%s] [loc=%s] [cost_ms=%d] "+format, append([]any{logID, location, costMS}, args...)...)
+	logf(ctx, 4, level, fmt.Sprintf(format, args...),
+		slog.String("log_id", logID),
+		slog.String("loc", location),
+		slog.Int64("cost_ms", costMS),
+	)
 }
 
-func SetRequestLogEnabled(enabled bool) {
-	requestLogEnabled = enabled
+// LogIDFromContext returns the log id RequestLogIDMiddleware gave the request.
+func LogIDFromContext(ctx context.Context) string {
+	return getRequestMeta(ctx).LogID
 }
 
 func withRequestMeta(ctx context.Context, meta requestMeta) context.Context {
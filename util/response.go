@@ -1,9 +1,13 @@
 package util
 
-import "github.com/labstack/echo/v4"
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/wutianfang/moss/util/errcode"
+)
 
 type APIResponse struct {
 	Errno int    `json:"errno"`
+	Key   string `json:"key,omitempty"`
 	Error string `json:"error,omitempty"`
 	Data  any    `json:"data,omitempty"`
 }
@@ -12,6 +16,16 @@ func JSONSuccess(c echo.Context, data any) error {
 	return c.JSON(200, APIResponse{Data: data})
 }
 
-func JSONError(c echo.Context, errno int, msg string) error {
-	return c.JSON(200, APIResponse{Errno: errno, Error: msg})
+// JSONError answers with the HTTP status of err's code and its message in
+// the language asked for by Accept-Language. Internal errors are logged.
+func JSONError(c echo.Context, err *errcode.Error) error {
+	if err.Code.Status >= 500 {
+		ErrorfWithRequest(c.Request().Context(), "api.error", "key=%s err=%v", err.Code.Key, err)
+	}
+	lang := errcode.Lang(c.Request().Header.Get("Accept-Language"))
+	return c.JSON(err.Code.Status, APIResponse{
+		Errno: err.Code.Errno,
+		Key:   err.Code.Key,
+		Error: err.Message(lang),
+	})
 }